@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+)
+
+// BlockCacheKey identifies a decoded block.Block by the id of its persistent sorted segment and its index in the segment.
+type BlockCacheKey struct {
+	SegmentId  uint64
+	BlockIndex int
+}
+
+// BlockCache caches the decoded blocks (block.Block) of the persistent sorted segments, which allows the reads of the hot
+// blocks to skip the object store.
+type BlockCache struct {
+	comparableKeyCache[BlockCacheKey, block.Block]
+}
+
+func NewBlockCache(options ComparableKeyCacheOptions[BlockCacheKey, block.Block]) (BlockCache, error) {
+	cache, err := newComparableKeyCache[BlockCacheKey, block.Block](options)
+	if err != nil {
+		return BlockCache{}, err
+	}
+	return BlockCache{
+		cache,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBlockCacheSetAndGetASingleKeyAndBlock(t *testing.T) {
+	blockBuilder := block.NewBlockBuilder(1024)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	decodedBlock, err := block.DecodeToBlock(blockBuilder.Build().Encode())
+	assert.NoError(t, err)
+
+	cacheOptions := NewComparableKeyCacheOptions[BlockCacheKey, block.Block](
+		4096,
+		5*time.Minute,
+		func(key BlockCacheKey, value block.Block) uint32 {
+			return uint32(value.SizeInBytes())
+		},
+	)
+	cache, err := NewBlockCache(cacheOptions)
+	assert.NoError(t, err)
+
+	assert.True(t, cache.Set(BlockCacheKey{SegmentId: 10, BlockIndex: 0}, decodedBlock))
+
+	cachedBlock, ok := cache.Get(BlockCacheKey{SegmentId: 10, BlockIndex: 0})
+	assert.True(t, ok)
+
+	iterator := cachedBlock.SeekToFirst()
+	defer iterator.Close()
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "consensus", iterator.Key().RawString())
+
+	_, ok = cache.Get(BlockCacheKey{SegmentId: 10, BlockIndex: 1})
+	assert.False(t, ok)
+}
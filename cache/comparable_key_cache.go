@@ -9,12 +9,26 @@ type comparableKeyCache[K comparable, V any] struct {
 }
 
 func newComparableKeyCache[K comparable, V any](options ComparableKeyCacheOptions[K, V]) (comparableKeyCache[K, V], error) {
-	cache, err := otter.MustBuilder[K, V](int(options.sizeInBytes)).
+	return newComparableKeyCacheWithDeletionListener[K, V](options, nil)
+}
+
+// newComparableKeyCacheWithDeletionListener creates comparableKeyCache which invokes the given (optional) deletionListener
+// (in the background) for every value which is evicted, expired or replaced.
+func newComparableKeyCacheWithDeletionListener[K comparable, V any](
+	options ComparableKeyCacheOptions[K, V],
+	deletionListener func(key K, value V),
+) (comparableKeyCache[K, V], error) {
+	builder := otter.MustBuilder[K, V](int(options.sizeInBytes)).
 		Cost(func(key K, value V) uint32 {
 			return options.costFn(key, value)
 		}).
-		WithTTL(options.entryTTL).
-		Build()
+		WithTTL(options.entryTTL)
+	if deletionListener != nil {
+		builder = builder.DeletionListener(func(key K, value V, cause otter.DeletionCause) {
+			deletionListener(key, value)
+		})
+	}
+	cache, err := builder.Build()
 
 	if err != nil {
 		return comparableKeyCache[K, V]{}, err
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+)
+
+// CompressionDictionaryCache caches the block.CompressionDictionary of the persistent sorted segments, a
+// block.CompressionDictionary is closed (please check block.CompressionDictionary.Close) when it is evicted, expired
+// or replaced.
+type CompressionDictionaryCache struct {
+	comparableKeyCache[uint64, *block.CompressionDictionary]
+}
+
+func NewCompressionDictionaryCache(options ComparableKeyCacheOptions[uint64, *block.CompressionDictionary]) (CompressionDictionaryCache, error) {
+	cache, err := newComparableKeyCacheWithDeletionListener[uint64, *block.CompressionDictionary](
+		options,
+		func(id uint64, compressionDictionary *block.CompressionDictionary) {
+			compressionDictionary.Close()
+		},
+	)
+	if err != nil {
+		return CompressionDictionaryCache{}, err
+	}
+	return CompressionDictionaryCache{
+		cache,
+	}, nil
+}
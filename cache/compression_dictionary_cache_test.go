@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCompressionDictionaryCacheSetAndGetASingleKeyAndCompressionDictionary(t *testing.T) {
+	var samples [][]byte
+	for count := 0; count < 100; count++ {
+		samples = append(samples, []byte(fmt.Sprintf(`{"id":%d,"database":"zero-store","architecture":"zero disk"}`, count)))
+	}
+	compressionDictionary, err := block.TrainCompressionDictionary(samples, 1024)
+	assert.NoError(t, err)
+
+	cacheOptions := NewComparableKeyCacheOptions[uint64, *block.CompressionDictionary](
+		4<<20,
+		5*time.Minute,
+		func(key uint64, value *block.CompressionDictionary) uint32 {
+			return uint32(value.SizeInBytes())
+		},
+	)
+	cache, err := NewCompressionDictionaryCache(cacheOptions)
+	assert.NoError(t, err)
+
+	assert.True(t, cache.Set(10, compressionDictionary))
+
+	cachedCompressionDictionary, ok := cache.Get(10)
+	assert.True(t, ok)
+	assert.Equal(t, compressionDictionary.Encode(), cachedCompressionDictionary.Encode())
+
+	_, ok = cache.Get(20)
+	assert.False(t, ok)
+}
@@ -58,6 +58,19 @@ func (cache *KeyCache) Get(key kv.Key) (kv.Value, bool) {
 	return value, true
 }
 
+// Delete removes all the cached versions (timestamps) of the raw key of the given key.
+func (cache *KeyCache) Delete(key kv.Key) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cachedKeyId, ok := cache.rawKeyCache.getKeyId(key)
+	if !ok {
+		return
+	}
+	cache.rawKeyCache.delete(key)
+	cache.keyIdCache.removeAllOccurrencesOf(cachedKeyId)
+}
+
 func (cache *KeyCache) Stop() {
 	close(cache.stopChannel)
 }
@@ -106,6 +119,10 @@ func (cache *rawKeyCache) add(key kv.Key) (keyId, error) {
 	return cachedKeyId, nil
 }
 
+func (cache *rawKeyCache) delete(key kv.Key) {
+	cache.cache.Del(key.RawBytes())
+}
+
 func (cache *rawKeyCache) getKeyId(key kv.Key) (keyId, bool) {
 	var id keyId
 	err := cache.cache.GetFn(key.RawBytes(), func(keyIdBuffer []byte) error {
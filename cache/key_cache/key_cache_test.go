@@ -190,6 +190,26 @@ func TestKeyCacheSetKeyAndGetValueByKeySuchThatTimestampIsLessThanTheTimestampOf
 	assert.Equal(t, "foundationDb", value.String())
 }
 
+func TestKeyCacheDeleteAllTheTimestampsOfAKey(t *testing.T) {
+	keyCache := NewKeyCache(NewKeyCacheOptions(512*1024, time.Second*10))
+	defer keyCache.Stop()
+
+	keyCache.Set(kv.NewStringKeyWithTimestamp("consensus", 2), kv.NewStringValue("raft"))
+	keyCache.Set(kv.NewStringKeyWithTimestamp("consensus", 3), kv.NewStringValue("paxos"))
+	keyCache.Set(kv.NewStringKeyWithTimestamp("distributed", 3), kv.NewStringValue("etcd"))
+
+	keyCache.Delete(kv.NewStringKeyWithTimestamp("consensus", 4))
+
+	_, ok := keyCache.Get(kv.NewStringKeyWithTimestamp("consensus", 2))
+	assert.False(t, ok)
+	_, ok = keyCache.Get(kv.NewStringKeyWithTimestamp("consensus", 3))
+	assert.False(t, ok)
+
+	value, ok := keyCache.Get(kv.NewStringKeyWithTimestamp("distributed", 3))
+	assert.True(t, ok)
+	assert.Equal(t, "etcd", value.String())
+}
+
 func TestKeyCacheSimulateEviction(t *testing.T) {
 	keyCache := NewKeyCache(NewKeyCacheOptions(512*1024, time.Second*10))
 	defer keyCache.Stop()
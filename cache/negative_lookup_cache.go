@@ -0,0 +1,50 @@
+package cache
+
+// NegativeLookup records that a raw key has no version at or below ReadTimestamp in the persistent sorted segments, as of
+// the version (SegmentSetVersion) of the set of the persistent sorted segments.
+type NegativeLookup struct {
+	SegmentSetVersion uint64
+	ReadTimestamp     uint64
+}
+
+// NegativeLookupCache caches the raw keys which are absent in the persistent sorted segments, which allows the reads of
+// the absent keys to skip the bloom filter false positives (and their block reads).
+// A NegativeLookup is valid only for the segment set version it was recorded at, it is invalidated by any change (addition
+// or replacement of a persistent sorted segment) in the set of the persistent sorted segments. Within a segment set version,
+// a key absent at a read timestamp is absent at all the older read timestamps.
+type NegativeLookupCache struct {
+	cache comparableKeyCache[string, NegativeLookup]
+}
+
+func NewNegativeLookupCache(options ComparableKeyCacheOptions[string, NegativeLookup]) (NegativeLookupCache, error) {
+	cache, err := newComparableKeyCache[string, NegativeLookup](options)
+	if err != nil {
+		return NegativeLookupCache{}, err
+	}
+	return NegativeLookupCache{
+		cache: cache,
+	}, nil
+}
+
+// IsAbsent returns true if the raw key is known to have no version at or below the readTimestamp, in the persistent
+// sorted segments of the given segmentSetVersion.
+func (cache NegativeLookupCache) IsAbsent(rawKey []byte, readTimestamp uint64, segmentSetVersion uint64) bool {
+	negativeLookup, ok := cache.cache.Get(string(rawKey))
+	if !ok {
+		return false
+	}
+	return negativeLookup.SegmentSetVersion == segmentSetVersion && readTimestamp <= negativeLookup.ReadTimestamp
+}
+
+// RecordAbsent records that the raw key has no version at or below the readTimestamp, in the persistent sorted segments
+// of the given segmentSetVersion.
+// The read timestamp of an existing NegativeLookup of the same segmentSetVersion is only moved forward.
+func (cache NegativeLookupCache) RecordAbsent(rawKey []byte, readTimestamp uint64, segmentSetVersion uint64) {
+	key := string(rawKey)
+	if existing, ok := cache.cache.Get(key); ok &&
+		existing.SegmentSetVersion == segmentSetVersion &&
+		existing.ReadTimestamp >= readTimestamp {
+		return
+	}
+	cache.cache.Set(key, NegativeLookup{SegmentSetVersion: segmentSetVersion, ReadTimestamp: readTimestamp})
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func testNegativeLookupCache(t *testing.T) NegativeLookupCache {
+	cache, err := NewNegativeLookupCache(NewComparableKeyCacheOptions[string, NegativeLookup](
+		4096,
+		5*time.Minute,
+		func(key string, value NegativeLookup) uint32 {
+			return uint32(len(key)) + uint32(unsafe.Sizeof(value))
+		},
+	))
+	assert.NoError(t, err)
+	return cache
+}
+
+func TestNegativeLookupCacheRecordAbsentAndCheckAtTheSameReadTimestamp(t *testing.T) {
+	cache := testNegativeLookupCache(t)
+	cache.RecordAbsent([]byte("consensus"), 10, 1)
+
+	assert.True(t, cache.IsAbsent([]byte("consensus"), 10, 1))
+	assert.False(t, cache.IsAbsent([]byte("raft"), 10, 1))
+}
+
+func TestNegativeLookupCacheRecordAbsentAndCheckAtAnOlderReadTimestamp(t *testing.T) {
+	cache := testNegativeLookupCache(t)
+	cache.RecordAbsent([]byte("consensus"), 10, 1)
+
+	assert.True(t, cache.IsAbsent([]byte("consensus"), 5, 1))
+}
+
+func TestNegativeLookupCacheRecordAbsentAndCheckAtANewerReadTimestamp(t *testing.T) {
+	cache := testNegativeLookupCache(t)
+	cache.RecordAbsent([]byte("consensus"), 10, 1)
+
+	assert.False(t, cache.IsAbsent([]byte("consensus"), 11, 1))
+}
+
+func TestNegativeLookupCacheRecordAbsentAndCheckAtAnotherSegmentSetVersion(t *testing.T) {
+	cache := testNegativeLookupCache(t)
+	cache.RecordAbsent([]byte("consensus"), 10, 1)
+
+	assert.False(t, cache.IsAbsent([]byte("consensus"), 10, 2))
+}
+
+func TestNegativeLookupCacheRecordAbsentDoesNotMoveTheReadTimestampBackward(t *testing.T) {
+	cache := testNegativeLookupCache(t)
+	cache.RecordAbsent([]byte("consensus"), 10, 1)
+	cache.RecordAbsent([]byte("consensus"), 5, 1)
+
+	assert.True(t, cache.IsAbsent([]byte("consensus"), 10, 1))
+}
+
+func TestNegativeLookupCacheRecordAbsentAtANewSegmentSetVersion(t *testing.T) {
+	cache := testNegativeLookupCache(t)
+	cache.RecordAbsent([]byte("consensus"), 10, 1)
+	cache.RecordAbsent([]byte("consensus"), 5, 2)
+
+	assert.True(t, cache.IsAbsent([]byte("consensus"), 5, 2))
+	assert.False(t, cache.IsAbsent([]byte("consensus"), 10, 2))
+	assert.False(t, cache.IsAbsent([]byte("consensus"), 5, 1))
+}
@@ -8,6 +8,7 @@ require (
 	github.com/huandu/skiplist v1.2.1
 	github.com/klauspost/compress v1.17.11
 	github.com/maypok86/otter v1.2.4
+	github.com/pierrec/lz4/v4 v4.1.21
 	github.com/thanos-io/objstore v0.0.0-20241128114755-8d266b990716
 )
 
@@ -33,7 +34,7 @@ require (
 	github.com/prometheus/common v0.44.0 // indirect
 	github.com/prometheus/procfs v0.11.1 // indirect
 	github.com/stretchr/testify v1.10.0
-	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sync v0.8.0
 	golang.org/x/sys v0.26.0 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
@@ -3,6 +3,7 @@ package kv
 import (
 	"bytes"
 	"errors"
+	"fmt"
 )
 
 type KeyValuePairKind int
@@ -33,7 +34,22 @@ func (pair RawKeyValuePair) Kind() KeyValuePairKind {
 	return pair.kind
 }
 
-var DuplicateKeyInBatchErr = errors.New("batch already contains the key")
+const (
+	// MaxKeySizeInBytes is the maximum size of a raw key.
+	// A key (along with at-least one byte of its value) must also fit in a block of the persistent sorted segment, which
+	// depends on the block size, it is validated when the batch is written to the storage state.
+	MaxKeySizeInBytes = 1 << 20
+	// MaxValueSizeInBytes is the maximum size of a raw value.
+	// The size of the encoded value is stored as uint32 in the blocks of the persistent sorted segment.
+	MaxValueSizeInBytes = 1 << 30
+)
+
+var (
+	DuplicateKeyInBatchErr = errors.New("batch already contains the key")
+	ErrEmptyKey            = errors.New("key must not be empty")
+	ErrKeyTooLarge         = errors.New("key is too large")
+	ErrValueTooLarge       = errors.New("value is too large")
+)
 
 // Batch is a collection of RawKeyValuePair.
 type Batch struct {
@@ -46,8 +62,18 @@ func NewBatch() *Batch {
 }
 
 // Set puts the key/value pair in Batch.
-// Returns DuplicateKeyInBatchErr if the key is already present in the Batch.
+// Returns ErrEmptyKey if the key is empty, ErrKeyTooLarge if the key is larger than MaxKeySizeInBytes, ErrValueTooLarge
+// if the value is larger than MaxValueSizeInBytes, and DuplicateKeyInBatchErr if the key is already present in the Batch.
 func (batch *Batch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	if len(key) > MaxKeySizeInBytes {
+		return fmt.Errorf("%w: key size %v bytes, maximum %v bytes", ErrKeyTooLarge, len(key), MaxKeySizeInBytes)
+	}
+	if len(value) > MaxValueSizeInBytes {
+		return fmt.Errorf("%w: value size %v bytes, maximum %v bytes", ErrValueTooLarge, len(value), MaxValueSizeInBytes)
+	}
 	if batch.Contains(key) {
 		return DuplicateKeyInBatchErr
 	}
@@ -25,6 +25,38 @@ func TestAddsDuplicateKeyInBatch(t *testing.T) {
 	assert.Equal(t, DuplicateKeyInBatchErr, err)
 }
 
+func TestAttemptToAddAnEmptyKeyInBatch(t *testing.T) {
+	batch := NewBatch()
+	err := batch.Set([]byte{}, []byte("Hard disk"))
+
+	assert.ErrorIs(t, err, ErrEmptyKey)
+	assert.True(t, batch.IsEmpty())
+}
+
+func TestAttemptToAddAKeyLargerThanTheMaximumKeySizeInBatch(t *testing.T) {
+	batch := NewBatch()
+	err := batch.Set(make([]byte, MaxKeySizeInBytes+1), []byte("Hard disk"))
+
+	assert.ErrorIs(t, err, ErrKeyTooLarge)
+	assert.True(t, batch.IsEmpty())
+}
+
+func TestAddAKeyOfTheMaximumKeySizeInBatch(t *testing.T) {
+	batch := NewBatch()
+	err := batch.Set(make([]byte, MaxKeySizeInBytes), []byte("Hard disk"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, batch.Length())
+}
+
+func TestAttemptToAddAValueLargerThanTheMaximumValueSizeInBatch(t *testing.T) {
+	batch := NewBatch()
+	err := batch.Set([]byte("HDD"), make([]byte, MaxValueSizeInBytes+1))
+
+	assert.ErrorIs(t, err, ErrValueTooLarge)
+	assert.True(t, batch.IsEmpty())
+}
+
 func TestGetTheValueOfAKeyFromBatch(t *testing.T) {
 	batch := NewBatch()
 	_ = batch.Set([]byte("HDD"), []byte("Hard disk"))
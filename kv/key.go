@@ -3,6 +3,7 @@ package kv
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"unsafe"
 )
 
@@ -10,6 +11,8 @@ const TimestampSize = int(unsafe.Sizeof(uint64(0)))
 
 var EmptyKey = Key{key: nil}
 
+var ErrInvalidKeyEncoding = errors.New("invalid key encoding")
+
 type Key struct {
 	key       []byte
 	timestamp uint64
@@ -24,16 +27,17 @@ func NewKey(key []byte, timestamp uint64) Key {
 }
 
 // DecodeKeyFrom decodes the key from the given byte slice.
-func DecodeKeyFrom(buffer []byte) Key {
+// It returns ErrInvalidKeyEncoding if the byte slice is too small to contain the timestamp.
+func DecodeKeyFrom(buffer []byte) (Key, error) {
 	if len(buffer) < TimestampSize {
-		panic("buffer too small to decode the key from")
+		return EmptyKey, ErrInvalidKeyEncoding
 	}
 
 	length := len(buffer)
 	return Key{
 		key:       buffer[:length-TimestampSize],
 		timestamp: binary.LittleEndian.Uint64(buffer[length-TimestampSize:]),
-	}
+	}, nil
 }
 
 // EncodedBytes returns the encoded format of the Key.
@@ -135,7 +135,8 @@ func TestRawSizeInBytes(t *testing.T) {
 
 func TestEncodedBytes(t *testing.T) {
 	key := NewStringKeyWithTimestamp("store-type", 10)
-	decodedKey := DecodeKeyFrom(key.EncodedBytes())
+	decodedKey, err := DecodeKeyFrom(key.EncodedBytes())
+	assert.NoError(t, err)
 
 	assert.Equal(t, "store-type", decodedKey.RawString())
 	assert.Equal(t, uint64(10), decodedKey.timestamp)
@@ -145,3 +146,22 @@ func TestEncodedSizeInBytesForAnEmptyKey(t *testing.T) {
 	key := NewStringKeyWithTimestamp("", 0)
 	assert.Equal(t, 0, key.EncodedSizeInBytes())
 }
+
+func TestDecodeAKeyFromAShortBuffer(t *testing.T) {
+	_, err := DecodeKeyFrom([]byte("raft"))
+	assert.ErrorIs(t, err, ErrInvalidKeyEncoding)
+}
+
+func FuzzDecodeKeyFrom(f *testing.F) {
+	f.Add(NewStringKeyWithTimestamp("consensus", 10).EncodedBytes())
+	f.Add([]byte("raft"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		key, err := DecodeKeyFrom(buffer)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, len(buffer)-TimestampSize, key.RawSizeInBytes())
+	})
+}
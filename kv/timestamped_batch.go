@@ -44,6 +44,10 @@ func (batch TimestampedBatch) SizeInBytes() int {
 	return sizeInBytes
 }
 
+func (batch TimestampedBatch) Length() int {
+	return len(batch.keys)
+}
+
 type TimestampedBatchIterator struct {
 	index int
 	batch TimestampedBatch
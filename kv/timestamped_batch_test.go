@@ -53,3 +53,13 @@ func TestSizeInBytesOfTimestampedBatch(t *testing.T) {
 
 	assert.Equal(t, 22, timestampedBatch.SizeInBytes())
 }
+
+func TestLengthOfTimestampedBatch(t *testing.T) {
+	batch := NewBatch()
+	assert.NoError(t, batch.Set([]byte("raft"), []byte("consensus")))
+	batch.Delete([]byte("storage"))
+
+	timestampedBatch, err := NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, timestampedBatch.Length())
+}
@@ -1,17 +1,25 @@
 package kv
 
-import "unsafe"
+import (
+	"errors"
+	"unsafe"
+)
 
 var EmptyValue = Value{value: nil}
 
+var ErrInvalidValueEncoding = errors.New("invalid value encoding")
+
 const (
-	deletedMarker    byte = 0x01
-	nonDeletedMarker byte = 0x00
+	deletedMarker      byte = 0x01
+	nonDeletedMarker   byte = 0x00
+	valuePointerMarker byte = 0x02
 )
 
 const deletedByteSize = int(unsafe.Sizeof(uint8(0)))
 
 // Value is a tiny wrapper over raw []byte slice.
+// deleted is a byte of markers: deletedMarker marks the value as deleted, and valuePointerMarker marks the value as a pointer
+// to the actual value stored in a value log.
 type Value struct {
 	value   []byte
 	deleted byte
@@ -19,12 +27,16 @@ type Value struct {
 
 // DecodeValueFrom sets the provided byte slice as its value.
 // It is mainly called from external.SkipList.
-func DecodeValueFrom(buffer []byte) Value {
+// It returns ErrInvalidValueEncoding if the byte slice does not contain the marker byte.
+func DecodeValueFrom(buffer []byte) (Value, error) {
 	length := len(buffer)
+	if length < deletedByteSize {
+		return EmptyValue, ErrInvalidValueEncoding
+	}
 	return Value{
 		value:   buffer[:length-1],
 		deleted: buffer[length-1],
-	}
+	}, nil
 }
 
 // NewValue creates a new instance of Value.
@@ -43,6 +55,14 @@ func NewDeletedValue() Value {
 	}
 }
 
+// NewValuePointer creates a new instance of Value which contains a pointer to the actual value stored in a value log.
+func NewValuePointer(pointer []byte) Value {
+	return Value{
+		value:   pointer,
+		deleted: valuePointerMarker,
+	}
+}
+
 // EncodeTo writes the raw byte slice to the provided buffer.
 // It is mainly called from external.SkipList.
 func (value Value) EncodeTo(buffer []byte) {
@@ -71,6 +91,11 @@ func (value Value) IsDeleted() bool {
 	return value.deleted&deletedMarker == deletedMarker
 }
 
+// IsValuePointer returns true if the value contains a pointer to the actual value stored in a value log.
+func (value Value) IsValuePointer() bool {
+	return value.deleted&valuePointerMarker == valuePointerMarker
+}
+
 // SizeInBytes returns the length of the raw byte slice.
 func (value Value) SizeInBytes() int {
 	return len(value.Bytes()) + deletedByteSize
@@ -37,14 +37,16 @@ func TestEncodeValue1(t *testing.T) {
 	buffer := make([]byte, value.SizeAsUint32())
 
 	value.EncodeTo(buffer)
-	decodedValue := DecodeValueFrom(buffer)
+	decodedValue, err := DecodeValueFrom(buffer)
+	assert.NoError(t, err)
 
 	assert.Equal(t, "zero disk architecture", decodedValue.String())
 }
 
 func TestEncodeValue2(t *testing.T) {
 	value := NewStringValue("zero disk architecture")
-	decodedValue := DecodeValueFrom(value.EncodedBytes())
+	decodedValue, err := DecodeValueFrom(value.EncodedBytes())
+	assert.NoError(t, err)
 
 	assert.Equal(t, "zero disk architecture", decodedValue.String())
 }
@@ -54,7 +56,8 @@ func TestEncodeADeletedValue1(t *testing.T) {
 	buffer := make([]byte, value.SizeAsUint32())
 
 	value.EncodeTo(buffer)
-	decodedValue := DecodeValueFrom(buffer)
+	decodedValue, err := DecodeValueFrom(buffer)
+	assert.NoError(t, err)
 
 	assert.Equal(t, "", decodedValue.String())
 	assert.True(t, value.IsDeleted())
@@ -62,8 +65,43 @@ func TestEncodeADeletedValue1(t *testing.T) {
 
 func TestEncodeADeletedValue2(t *testing.T) {
 	value := NewDeletedValue()
-	decodedValue := DecodeValueFrom(value.EncodedBytes())
+	decodedValue, err := DecodeValueFrom(value.EncodedBytes())
+	assert.NoError(t, err)
 
 	assert.Equal(t, "", decodedValue.String())
 	assert.True(t, value.IsDeleted())
 }
+
+func TestEncodeAValuePointer(t *testing.T) {
+	value := NewValuePointer([]byte("pointer"))
+	decodedValue, err := DecodeValueFrom(value.EncodedBytes())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "pointer", decodedValue.String())
+	assert.True(t, decodedValue.IsValuePointer())
+	assert.False(t, decodedValue.IsDeleted())
+}
+
+func TestANonPointerValue(t *testing.T) {
+	value := NewStringValue("raft")
+	assert.False(t, value.IsValuePointer())
+}
+
+func TestDecodeAValueFromAnEmptyBuffer(t *testing.T) {
+	_, err := DecodeValueFrom(nil)
+	assert.ErrorIs(t, err, ErrInvalidValueEncoding)
+}
+
+func FuzzDecodeValueFrom(f *testing.F) {
+	f.Add(NewStringValue("raft").EncodedBytes())
+	f.Add(NewDeletedValue().EncodedBytes())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		value, err := DecodeValueFrom(buffer)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, buffer, value.EncodedBytes())
+	})
+}
@@ -14,6 +14,10 @@ const (
 	// node.getValueOffset uses atomic.LoadUint64, which expects its input
 	// pointer to be 64-bit aligned.
 	nodeAlign = int(unsafe.Sizeof(uint64(0))) - 1
+
+	// MaxNodeAllocationSize is the maximum number of bytes that the arena allocates for a node,
+	// it includes the padding for alignment.
+	MaxNodeAllocationSize = MaxNodeSize + nodeAlign
 )
 
 // Arena should be lock-free.
@@ -87,14 +91,24 @@ func (arena *Arena) getNode(offset uint32) *node {
 }
 
 // getKey returns byte slice at offset.
-func (arena *Arena) getKey(offset uint32, size uint16) kv.Key {
-	return kv.DecodeKeyFrom(arena.buf[offset : offset+uint32(size)])
+// The arena only contains the keys encoded by the skiplist, so a decoding error is a bug.
+func (arena *Arena) getKey(offset uint32, size uint32) kv.Key {
+	key, err := kv.DecodeKeyFrom(arena.buf[offset : offset+size])
+	if err != nil {
+		panic(err)
+	}
+	return key
 }
 
 // getValue returns byte slice at offset. The given size should be just the value
 // size and should NOT include the meta bytes.
+// The arena only contains the values encoded by the skiplist, so a decoding error is a bug.
 func (arena *Arena) getValue(offset uint32, size uint32) kv.Value {
-	return kv.DecodeValueFrom(arena.buf[offset : offset+size])
+	value, err := kv.DecodeValueFrom(arena.buf[offset : offset+size])
+	if err != nil {
+		panic(err)
+	}
+	return value
 }
 
 // getNodeOffset returns the offset of node in the arena. If the node pointer is
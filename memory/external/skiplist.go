@@ -36,12 +36,12 @@ type node struct {
 	// Multiple parts of the value are encoded as a single uint64 so that it
 	// can be atomically loaded and stored:
 	//   value offset: uint32 (bits 0-31)
-	//   value size  : uint16 (bits 32-63)
+	//   value size  : uint32 (bits 32-63)
 	value atomic.Uint64
 
 	// A byte slice is 24 bytes. We are trying to save space here.
 	keyOffset uint32 // Immutable. No need to lock to access key.
-	keySize   uint16 // Immutable. No need to lock to access key.
+	keySize   uint32 // Immutable. No need to lock to access key.
 
 	// Height of the tower.
 	height uint16
@@ -199,7 +199,7 @@ func newNode(arena *Arena, key kv.Key, v kv.Value, height int) *node {
 	offset := arena.putNode(height)
 	node := arena.getNode(offset)
 	node.keyOffset = arena.putKey(key)
-	node.keySize = uint16(key.EncodedSizeInBytes())
+	node.keySize = uint32(key.EncodedSizeInBytes())
 	node.height = uint16(height)
 	node.value.Store(encodeValue(arena.putVal(v), v.SizeAsUint32()))
 	return node
@@ -3,6 +3,7 @@ package external
 import (
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -24,6 +25,16 @@ func TestPutAndGetTheKeyWithTimestampHigherThanThatOfTheKeyPresentInSkipList(t *
 	assert.Equal(t, kv.NewStringValue("raft"), value)
 }
 
+func TestPutAndGetAKeyLargerThan64KiB(t *testing.T) {
+	skipList := NewSkipList(1 << 20)
+	rawKey := strings.Repeat("k", 70*1024)
+	skipList.Put(kv.NewStringKeyWithTimestamp(rawKey, 10), kv.NewStringValue("raft"))
+
+	value, ok := skipList.Get(kv.NewStringKeyWithTimestamp(rawKey, 10))
+	assert.True(t, ok)
+	assert.Equal(t, kv.NewStringValue("raft"), value)
+}
+
 func TestGetANonExistingKey(t *testing.T) {
 	skipList := NewSkipList(1 << 10)
 
@@ -22,6 +22,9 @@ type SortedSegment struct {
 
 var EmptySortedSegment = SortedSegment{}
 
+// emptySortedSegmentSizeInBytes is the size of an empty SortedSegment, the arena of an empty Skiplist contains the head node.
+var emptySortedSegmentSizeInBytes = external.NewSkipList(int64(2 * external.MaxNodeAllocationSize)).MemSize()
+
 // NewSortedSegment creates a new instance of SortedSegment
 func NewSortedSegment(id uint64, allowedSizeInBytes int64) SortedSegment {
 	return SortedSegment{
@@ -32,6 +35,35 @@ func NewSortedSegment(id uint64, allowedSizeInBytes int64) SortedSegment {
 	}
 }
 
+// NewSortedSegmentToFit creates a new instance of SortedSegment which has the size enough for the requiredSizeInBytes.
+// It is used for the batches which do not fit in a SortedSegment of the configured size.
+func NewSortedSegmentToFit(id uint64, requiredSizeInBytes int64) SortedSegment {
+	return NewSortedSegment(id, SortedSegmentSizeInBytesToFit(requiredSizeInBytes))
+}
+
+// SortedSegmentSizeInBytesToFit returns the size (in bytes) of the SortedSegment created by NewSortedSegmentToFit, it
+// includes the size of an empty SortedSegment.
+func SortedSegmentSizeInBytesToFit(requiredSizeInBytes int64) int64 {
+	return emptySortedSegmentSizeInBytes + requiredSizeInBytes + int64(external.MaxNodeAllocationSize) + 1
+}
+
+// CanFitInAnEmptySortedSegment returns true if an empty SortedSegment with the allowedSizeInBytes has the size enough for the
+// requiredSizeInBytes.
+func CanFitInAnEmptySortedSegment(requiredSizeInBytes int64, allowedSizeInBytes int64) bool {
+	return emptySortedSegmentSizeInBytes+requiredSizeInBytes+int64(external.MaxNodeAllocationSize) < allowedSizeInBytes
+}
+
+// SizeInBytesToFit returns the size (in bytes) which is required to fit all the key/value pairs of the kv.TimestampedBatch.
+// Each key/value pair takes a skiplist node along with the encoded key and the encoded value.
+// CanFit reserves the size for one skiplist node, so SizeInBytesToFit includes the skiplist nodes for the remaining key/value pairs.
+// An empty batch takes no skiplist node.
+func SizeInBytesToFit(batch kv.TimestampedBatch) int64 {
+	if batch.Length() == 0 {
+		return int64(batch.SizeInBytes())
+	}
+	return int64(batch.SizeInBytes()) + int64(batch.Length()-1)*int64(external.MaxNodeAllocationSize)
+}
+
 // Get returns the value for the key if found.
 func (segment SortedSegment) Get(key kv.Key) (kv.Value, bool) {
 	value, ok := segment.entries.Get(key)
@@ -58,7 +90,7 @@ func (segment SortedSegment) IsEmpty() bool {
 
 // CanFit returns true if the SortedSegment has the size enough for the requiredSizeInBytes.
 func (segment SortedSegment) CanFit(requiredSizeInBytes int64) bool {
-	return segment.sizeInBytes()+requiredSizeInBytes+int64(external.MaxNodeSize) < segment.allowedSizeInBytes
+	return segment.sizeInBytes()+requiredSizeInBytes+int64(external.MaxNodeAllocationSize) < segment.allowedSizeInBytes
 }
 
 // Id returns the id of SortedSegment.
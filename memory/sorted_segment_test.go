@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -95,6 +96,41 @@ func TestSortedSegmentDoesNotHaveEnoughSpaceToFitTheRequiredSize(t *testing.T) {
 	assert.False(t, sortedSegment.CanFit(20))
 }
 
+func TestSizeInBytesToFitAnEmptyBatch(t *testing.T) {
+	assert.Equal(t, int64(0), SizeInBytesToFit(kv.TimestampedBatch{}))
+}
+
+func TestSizeInBytesToFitABatchWithASingleKeyValuePair(t *testing.T) {
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	timestampedBatch, _ := kv.NewTimestampedBatch(batch, 5)
+
+	assert.Equal(t, int64(timestampedBatch.SizeInBytes()), SizeInBytesToFit(timestampedBatch))
+}
+
+func TestSortedSegmentToFitTheRequiredSize(t *testing.T) {
+	batch := kv.NewBatch()
+	for count := 1; count <= 10; count++ {
+		_ = batch.Set([]byte(fmt.Sprintf("consensus-%d", count)), []byte("raft"))
+	}
+	timestampedBatch, _ := kv.NewTimestampedBatch(batch, 5)
+
+	requiredSizeInBytes := SizeInBytesToFit(timestampedBatch)
+	assert.False(t, CanFitInAnEmptySortedSegment(requiredSizeInBytes, 200))
+
+	sortedSegment := NewSortedSegmentToFit(1, requiredSizeInBytes)
+	assert.True(t, sortedSegment.CanFit(requiredSizeInBytes))
+
+	iterator := timestampedBatch.Iterator()
+	for iterator.IsValid() {
+		sortedSegment.Set(iterator.Key(), iterator.Value())
+		_ = iterator.Next()
+	}
+	value, ok := sortedSegment.Get(kv.NewStringKeyWithTimestamp("consensus-10", 5))
+	assert.True(t, ok)
+	assert.Equal(t, "raft", value.String())
+}
+
 func TestSortedSegmentAllEntriesIterator(t *testing.T) {
 	sortedSegment := NewSortedSegment(1, testSortedSegmentSizeInBytes)
 	sortedSegment.Set(kv.NewStringKeyWithTimestamp("consensus", 1), kv.NewStringValue("raft"))
@@ -1,27 +1,52 @@
 package block
 
 import (
-	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 )
 
+var ErrInvalidBlock = errors.New("invalid block")
+
 // Block represents the in-memory representation of Block.
 //
-// Each block contains encoded key/value pairs, and keyValueBeginOffsets. The reason for storing keyValueBeginOffsets is to allow
-// binary search for a key within each block.
+// Each block contains encoded key/value pairs, and restartPoints. The keys are delta encoded against their previous key, and
+// each restart point is the begin-offset of a key/value pair which stores its entire key. The reason for storing
+// restartPoints is to allow binary search for a key within each block.
+// The blocks written before the keys were delta encoded store the entire key of each key/value pair, and each key/value
+// pair is a restart point.
+//
+// A block may contain a hashIndex (after its restartPoints), which maps the raw keys to their restart points.
+//
+// A block containing a key/value pair which is larger than the block size, is followed by overflow bytes. Overflow bytes
+// contain the part of the value which does not fit in the block.
 type Block struct {
-	data                 []byte
-	keyValueBeginOffsets []uint16
-	lastDataIndex        int
+	data          []byte
+	restartPoints []uint32
+	lastDataIndex int
+	overflow      []byte
+	hashIndex     hashIndex
+	layout        layout
+}
+
+// keyValuePair is the encoded key/value pair at an offset in the Block.
+// valueInBlock is the part of the encoded value that is stored in the block, valueSize is the size of the entire encoded value.
+// endOffset is the offset of the next key/value pair.
+type keyValuePair struct {
+	encodedKey   []byte
+	valueInBlock []byte
+	valueSize    uint32
+	endOffset    int
 }
 
 // newBlock creates a new instance of Block.
 // data is the encoded key/value pairs generated by block.Builder.
-func newBlock(data []byte, lastDataIndex int, keyValueBeginOffsets []uint16) Block {
+func newBlock(data []byte, lastDataIndex int, restartPoints []uint32, layout layout) Block {
 	return Block{
-		data:                 data,
-		keyValueBeginOffsets: keyValueBeginOffsets,
-		lastDataIndex:        lastDataIndex,
+		data:          data,
+		restartPoints: restartPoints,
+		lastDataIndex: lastDataIndex,
+		layout:        layout,
 	}
 }
 
@@ -29,65 +54,221 @@ func newBlock(data []byte, lastDataIndex int, keyValueBeginOffsets []uint16) Blo
 /*
 // blocking encoding looks like the following:
   ---------------------------------------------------------------------------------------------------------------------------------------------------
- | encoded key/value  | encoded key/value  |....| encoded key/value  | 0 | 48 | 120 | ...... |3088|        2 bytes       |        2 bytes 	         |
+ | encoded key/value  | encoded key/value  |....| encoded key/value  | 0 | 480 | 960 | ...... |3088|      offset size     |      offset size 	         |
   ---------------------------------------------------------------------------------------------------------------------------------------------------
-  <--------------------------Encoded data---------------------------<-- Begin offsets of keys ---><-- Start of offsets --><-Number of begin offsets->
+  <--------------------------Encoded data---------------------------<------- Restart points ------><-- Start of offsets --><-Number of restart points->
 */
+// The offset size is 2 bytes for the blocks of FormatVersion1 (and FormatVersionLegacy), and 4 bytes for the blocks of
+// FormatVersion2.
 func (block Block) Encode() []byte {
 	data := block.data
-	copy(data[block.lastDataIndex:], block.encodeKeyValueBeginOffsets())
+	copy(data[block.lastDataIndex:], block.encodeRestartPoints())
 
-	binary.LittleEndian.PutUint16(data[len(data)-Uint16Size:], uint16(len(block.keyValueBeginOffsets)))
-	binary.LittleEndian.PutUint16(data[len(data)-Uint16Size-Uint16Size:], uint16(block.lastDataIndex))
+	offsetSize := block.layout.offsetSize()
+	numberOfRestartPoints := uint32(len(block.restartPoints))
+	if len(block.hashIndex) > 0 {
+		block.hashIndex.encodeTo(data, len(data)-offsetSize-offsetSize, block.layout)
+		numberOfRestartPoints |= block.layout.hashIndexFlag()
+	}
+	block.layout.putOffset(data[len(data)-offsetSize:], numberOfRestartPoints)
+	block.layout.putOffset(data[len(data)-offsetSize-offsetSize:], uint32(block.lastDataIndex))
 
 	return data
 }
 
-// DecodeToBlock decodes the given byte slice to the Block.
+// DecodeToBlock decodes the given byte slice (of CurrentFormatVersion) to the Block.
 //
-// The last 2 bytes denote the number of keyValueBeginOffsets.
-// The 2 bytes prior to the last 2 bytes denote the start offset of keyValueBeginOffsets.
-func DecodeToBlock(data []byte) Block {
-	numberOfOffsets := binary.LittleEndian.Uint16(data[len(data)-Uint16Size:])
-	startOfOffsets := binary.LittleEndian.Uint16(data[len(data)-Uint16Size-Uint16Size:])
-	offsetsBuffer := data[startOfOffsets : startOfOffsets+numberOfOffsets*uint16(Uint16Size)]
+// The last offset denotes the number of restartPoints, the highest bit is set if the block contains a hashIndex.
+// The offset prior to the last offset denotes the start offset of restartPoints.
+// It returns ErrInvalidBlock if the byte slice is not a valid encoding of the Block.
+func DecodeToBlock(data []byte) (Block, error) {
+	return decodeToBlock(data, nil, layoutOf(CurrentFormatVersion))
+}
 
-	keyValueBeginOffsets := make([]uint16, 0, numberOfOffsets)
-	for index := 0; index < len(offsetsBuffer); index += Uint16Size {
-		keyValueBeginOffsets = append(keyValueBeginOffsets, binary.LittleEndian.Uint16(offsetsBuffer[index:]))
+// DecodeToBlockWithOverflow decodes the given byte slice (of CurrentFormatVersion) to the Block.
+// The first blockSize bytes of the given byte slice contain the block, and the remaining bytes (if any) are the overflow bytes
+// of the last key/value pair in the block.
+// It returns ErrInvalidBlock if the byte slice is not a valid encoding of the Block.
+func DecodeToBlockWithOverflow(data []byte, blockSize uint) (Block, error) {
+	return DecodeToBlockWithOverflowOfFormatVersion(data, blockSize, CurrentFormatVersion)
+}
+
+// DecodeToBlockWithOverflowOfFormatVersion decodes the given byte slice to the Block, the block belongs to a persistent
+// sorted segment of the given format version.
+// It returns ErrInvalidBlock if the byte slice is not a valid encoding of the Block.
+func DecodeToBlockWithOverflowOfFormatVersion(data []byte, blockSize uint, formatVersion uint16) (Block, error) {
+	if uint(len(data)) <= blockSize {
+		return decodeToBlock(data, nil, layoutOf(formatVersion))
 	}
-	return Block{
-		data:                 data[:startOfOffsets],
-		keyValueBeginOffsets: keyValueBeginOffsets,
+	return decodeToBlock(data[:blockSize], data[blockSize:], layoutOf(formatVersion))
+}
+
+// decodeToBlock decodes the given byte slice of the given layout to the Block with the given overflow bytes.
+// All the key/value pairs are validated, so that block.Iterator never reads beyond the block (and its overflow bytes).
+func decodeToBlock(data []byte, overflow []byte, layout layout) (Block, error) {
+	offsetSize := layout.offsetSize()
+	if len(data) < offsetSize+offsetSize {
+		return Block{}, fmt.Errorf("%w: block size %v bytes", ErrInvalidBlock, len(data))
+	}
+	encodedNumberOfRestartPoints := layout.offsetAt(data[len(data)-offsetSize:])
+	numberOfRestartPoints := int(encodedNumberOfRestartPoints &^ layout.hashIndexFlag())
+	startOfRestartPoints := int(layout.offsetAt(data[len(data)-offsetSize-offsetSize:]))
+
+	var index hashIndex
+	endOfRestartPoints := len(data) - offsetSize - offsetSize
+	if encodedNumberOfRestartPoints&layout.hashIndexFlag() != 0 {
+		var err error
+		if index, endOfRestartPoints, err = decodeToHashIndex(data, endOfRestartPoints, numberOfRestartPoints, layout); err != nil {
+			return Block{}, err
+		}
+	}
+	if startOfRestartPoints+numberOfRestartPoints*offsetSize > endOfRestartPoints {
+		return Block{}, fmt.Errorf("%w: offsets beyond the block", ErrInvalidBlock)
+	}
+	restartPointsBuffer := data[startOfRestartPoints : startOfRestartPoints+numberOfRestartPoints*offsetSize]
+
+	restartPoints := make([]uint32, 0, numberOfRestartPoints)
+	for index := 0; index < len(restartPointsBuffer); index += offsetSize {
+		restartPoints = append(restartPoints, layout.offsetAt(restartPointsBuffer[index:]))
+	}
+	block := Block{
+		data:          data[:startOfRestartPoints],
+		restartPoints: restartPoints,
+		lastDataIndex: startOfRestartPoints,
+		overflow:      overflow,
+		hashIndex:     index,
+		layout:        layout,
+	}
+	if err := block.validateKeyValuePairs(); err != nil {
+		return Block{}, err
 	}
+	return block, nil
 }
 
-// SeekToFirst creates an iterator (/block iterator) that is positioned at the first offset in the block.
+// SizeInBytes returns the size of the decoded Block, which includes the encoded key/value pairs, the restart points, the
+// hashIndex and the overflow bytes.
+func (block Block) SizeInBytes() int {
+	return len(block.data) + len(block.restartPoints)*Uint32Size + len(block.hashIndex) + len(block.overflow)
+}
+
+// SeekToFirst creates an iterator (/block iterator) that is positioned at the first key/value pair in the block.
 func (block Block) SeekToFirst() *Iterator {
 	iterator := &Iterator{
-		block:       block,
-		offsetIndex: 0,
+		block: block,
 	}
-	iterator.seekToOffsetIndex(iterator.offsetIndex)
+	iterator.seekToRestartPoint(0)
 	return iterator
 }
 
 // SeekToKey creates an iterator (/block iterator) that is positioned at a key which is greater or equal to the given key.
+// The hashIndex (if the block contains it) is used for seeking to the raw keys present in the block (exact-match lookups),
+// binary search over the restart points is used otherwise.
 func (block Block) SeekToKey(key kv.Key) *Iterator {
 	iterator := &Iterator{
 		block: block,
 	}
+	if iterator.seekToRawKeyUsingHashIndex(key) {
+		return iterator
+	}
 	iterator.seekToGreaterOrEqual(key)
 	return iterator
 }
 
-// encodeKeyValueBeginOffsets encodes all the keyValueBeginOffsets to byte slice using LittleEndian encoding.
-func (block Block) encodeKeyValueBeginOffsets() []byte {
-	offsetBuffer := make([]byte, Uint16Size*len(block.keyValueBeginOffsets))
+// validateKeyValuePairs validates all the encoded key/value pairs by scanning the block from the first key/value pair.
+// The first key/value pair must be a restart point, each restart point must be the begin-offset of a key/value pair, and
+// only the last key/value pair may continue in the overflow bytes.
+func (block Block) validateKeyValuePairs() error {
+	var previousEncodedKey []byte
+	restartPointIndex := 0
+	for offset := 0; offset < block.lastDataIndex; {
+		if offset == 0 && (len(block.restartPoints) == 0 || block.restartPoints[0] != 0) {
+			return fmt.Errorf("%w: first key/value pair is not a restart point", ErrInvalidBlock)
+		}
+		isRestartPoint := restartPointIndex < len(block.restartPoints) && int(block.restartPoints[restartPointIndex]) == offset
+		if !isRestartPoint && restartPointIndex < len(block.restartPoints) && int(block.restartPoints[restartPointIndex]) < offset {
+			return fmt.Errorf("%w: restart point %v is not a key/value offset", ErrInvalidBlock, block.restartPoints[restartPointIndex])
+		}
+		pair, err := block.keyValuePairAt(offset, isRestartPoint, previousEncodedKey)
+		if err != nil {
+			return err
+		}
+		if isRestartPoint {
+			restartPointIndex++
+		}
+		previousEncodedKey = pair.encodedKey
+		offset = pair.endOffset
+	}
+	if restartPointIndex != len(block.restartPoints) {
+		return fmt.Errorf("%w: restart point %v beyond the key/value pairs", ErrInvalidBlock, block.restartPoints[restartPointIndex])
+	}
+	return nil
+}
+
+// keyValuePairAt decodes the encoded key/value pair at the given offset.
+// The key of a restart point is stored entirely, the key of any other key/value pair shares its prefix with previousEncodedKey.
+// The key must contain a non-empty raw key and a timestamp, and the value must contain its marker byte, and end within
+// the block or its overflow bytes.
+func (block Block) keyValuePairAt(offset int, isRestartPoint bool, previousEncodedKey []byte) (keyValuePair, error) {
+	data := block.data[:block.lastDataIndex]
+
+	index, sharedKeySize := offset, 0
+	if !isRestartPoint {
+		size, length, ok := block.layout.keySizeAt(data[index:])
+		if !ok {
+			return keyValuePair{}, fmt.Errorf("%w: key/value offset %v beyond the block", ErrInvalidBlock, offset)
+		}
+		if size > len(previousEncodedKey) {
+			return keyValuePair{}, fmt.Errorf("%w: invalid shared key size %v at offset %v", ErrInvalidBlock, size, offset)
+		}
+		sharedKeySize = size
+		index += length
+	}
+	unsharedKeySize, length, ok := block.layout.keySizeAt(data[index:])
+	if !ok {
+		return keyValuePair{}, fmt.Errorf("%w: key/value offset %v beyond the block", ErrInvalidBlock, offset)
+	}
+	index += length
+	if sharedKeySize+unsharedKeySize <= kv.TimestampSize || index+unsharedKeySize > len(data) {
+		return keyValuePair{}, fmt.Errorf("%w: invalid key size %v at offset %v", ErrInvalidBlock, sharedKeySize+unsharedKeySize, offset)
+	}
+	encodedKey := make([]byte, sharedKeySize+unsharedKeySize)
+	copy(encodedKey, previousEncodedKey[:sharedKeySize])
+	copy(encodedKey[sharedKeySize:], data[index:index+unsharedKeySize])
+	index += unsharedKeySize
+
+	valueSize, length, ok := block.layout.valueSizeAt(data[index:])
+	if !ok {
+		return keyValuePair{}, fmt.Errorf("%w: value size at offset %v beyond the block", ErrInvalidBlock, offset)
+	}
+	index += length
+	if valueSize < 1 || index+int(valueSize) > len(data)+len(block.overflow) {
+		return keyValuePair{}, fmt.Errorf("%w: invalid value size %v at offset %v", ErrInvalidBlock, valueSize, offset)
+	}
+	endOffset := index + int(valueSize)
+	return keyValuePair{
+		encodedKey:   encodedKey,
+		valueInBlock: data[index:min(endOffset, len(data))],
+		valueSize:    valueSize,
+		endOffset:    endOffset,
+	}, nil
+}
+
+// valueWithOverflow returns the encoded value which begins in the block and continues in the overflow bytes.
+// valueInBlock is the part of the encoded value that is stored in the block, valueSize is the size of the entire encoded value.
+func (block Block) valueWithOverflow(valueInBlock []byte, valueSize uint32) []byte {
+	value := make([]byte, 0, valueSize)
+	value = append(value, valueInBlock...)
+	return append(value, block.overflow[:int(valueSize)-len(valueInBlock)]...)
+}
+
+// encodeRestartPoints encodes all the restartPoints to byte slice using LittleEndian encoding.
+func (block Block) encodeRestartPoints() []byte {
+	offsetSize := block.layout.offsetSize()
+	offsetBuffer := make([]byte, offsetSize*len(block.restartPoints))
 	offsetIndex := 0
-	for _, offset := range block.keyValueBeginOffsets {
-		binary.LittleEndian.PutUint16(offsetBuffer[offsetIndex:], offset)
-		offsetIndex += Uint16Size
+	for _, offset := range block.restartPoints {
+		block.layout.putOffset(offsetBuffer[offsetIndex:], offset)
+		offsetIndex += offsetSize
 	}
 	return offsetBuffer
 }
@@ -1,9 +1,11 @@
 package block
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -21,7 +23,8 @@ func TestEncodeAndDecodeBlockWithASingleKeyValueAndSeekToTheFirstKey(t *testing.
 	block := blockBuilder.Build()
 	buffer := block.Encode()
 
-	decodedBlock := DecodeToBlock(buffer)
+	decodedBlock, err := DecodeToBlock(buffer)
+	assert.NoError(t, err)
 	iterator := decodedBlock.SeekToFirst()
 	defer iterator.Close()
 
@@ -44,7 +47,8 @@ func TestEncodeAndDecodeBlockWithASingleKeyValueAndSeekToTheKeySuchThatTheTimest
 	block := blockBuilder.Build()
 	buffer := block.Encode()
 
-	decodedBlock := DecodeToBlock(buffer)
+	decodedBlock, err := DecodeToBlock(buffer)
+	assert.NoError(t, err)
 	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("consensus", 14))
 	defer iterator.Close()
 
@@ -62,7 +66,8 @@ func TestEncodeAndDecodeBlockWithASingleKeyValueAndAttemptSeekToTheKeySuchThatTh
 	block := blockBuilder.Build()
 	buffer := block.Encode()
 
-	decodedBlock := DecodeToBlock(buffer)
+	decodedBlock, err := DecodeToBlock(buffer)
+	assert.NoError(t, err)
 	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("consensus", 2))
 	defer iterator.Close()
 
@@ -77,7 +82,8 @@ func TestEncodeAndDecodeBlockWithTwoKeyValues(t *testing.T) {
 	block := blockBuilder.Build()
 	buffer := block.Encode()
 
-	decodedBlock := DecodeToBlock(buffer)
+	decodedBlock, err := DecodeToBlock(buffer)
+	assert.NoError(t, err)
 	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("consensus", 5))
 	defer iterator.Close()
 
@@ -104,7 +110,8 @@ func TestEncodeAndDecodeBlockWithFewKeyValues(t *testing.T) {
 	block := blockBuilder.Build()
 	buffer := block.Encode()
 
-	decodedBlock := DecodeToBlock(buffer)
+	decodedBlock, err := DecodeToBlock(buffer)
+	assert.NoError(t, err)
 	for count := 1; count <= numberOfKeyValues; count++ {
 		iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%d", count), uint64(count)))
 		assert.True(t, iterator.IsValid())
@@ -112,3 +119,381 @@ func TestEncodeAndDecodeBlockWithFewKeyValues(t *testing.T) {
 		iterator.Close()
 	}
 }
+
+func TestEncodeAndDecodeBlockWithAnOverflowingKeyValue(t *testing.T) {
+	blockBuilder := NewBlockBuilder(40)
+	largeValue := strings.Repeat("raft", 30)
+
+	assert.False(t, blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(largeValue)))
+	overflow, ok := blockBuilder.AddOverflowing(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(largeValue))
+	assert.True(t, ok)
+	assert.True(t, len(overflow) > 0)
+
+	buffer := append(blockBuilder.Build().Encode(), overflow...)
+
+	decodedBlock, err := DecodeToBlockWithOverflow(buffer, 40)
+	assert.NoError(t, err)
+	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("consensus", 10))
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "consensus", iterator.Key().RawString())
+	assert.Equal(t, largeValue, iterator.Value().String())
+
+	_ = iterator.Next()
+	assert.False(t, iterator.IsValid())
+}
+
+func TestAttemptToAddAnOverflowingKeyValueToANonEmptyBlockBuilder(t *testing.T) {
+	blockBuilder := NewBlockBuilder(40)
+	assert.True(t, blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft")))
+
+	_, ok := blockBuilder.AddOverflowing(kv.NewStringKeyWithTimestamp("distributed", 10), kv.NewStringValue(strings.Repeat("etcd", 30)))
+	assert.False(t, ok)
+}
+
+func TestAttemptToAddAnOverflowingKeyValueWithTheKeyLargerThanTheBlock(t *testing.T) {
+	blockBuilder := NewBlockBuilder(40)
+
+	_, ok := blockBuilder.AddOverflowing(kv.NewStringKeyWithTimestamp(strings.Repeat("consensus", 10), 10), kv.NewStringValue("raft"))
+	assert.False(t, ok)
+}
+
+func TestAttemptToDecodeABlockFromAShortBuffer(t *testing.T) {
+	_, err := DecodeToBlock([]byte{1, 0})
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
+
+func TestAttemptToDecodeABlockWithOffsetsBeyondTheBlock(t *testing.T) {
+	blockBuilder := NewBlockBuilder(64)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	buffer := blockBuilder.Build().Encode()
+	buffer[len(buffer)-1] = 0xFF
+
+	_, err := DecodeToBlock(buffer)
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
+
+func TestAttemptToDecodeABlockWithAnInvalidValueSize(t *testing.T) {
+	blockBuilder := NewBlockBuilder(64)
+	key := kv.NewStringKeyWithTimestamp("consensus", 10)
+	blockBuilder.Add(key, kv.NewStringValue("raft"))
+
+	buffer := blockBuilder.Build().Encode()
+	buffer[layoutOf(CurrentFormatVersion).keySizeLength(key.EncodedSizeInBytes())+key.EncodedSizeInBytes()] = 0xFF
+
+	_, err := DecodeToBlock(buffer)
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
+
+func TestEncodeAndDecodeBlockWithKeysSharingPrefixes(t *testing.T) {
+	blockBuilder := NewBlockBuilder(4096)
+	numberOfKeyValues := 40
+
+	for count := 0; count < numberOfKeyValues; count++ {
+		key := kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/zero-store/user-%03d", count), 10)
+		assert.True(t, blockBuilder.Add(key, kv.NewStringValue(fmt.Sprintf("raft%d", count))))
+	}
+	assert.Equal(t, 3, len(blockBuilder.restartPoints))
+
+	decodedBlock, err := DecodeToBlock(blockBuilder.Build().Encode())
+	assert.NoError(t, err)
+
+	iterator := decodedBlock.SeekToFirst()
+	defer iterator.Close()
+
+	for count := 0; count < numberOfKeyValues; count++ {
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, fmt.Sprintf("tenant/zero-store/user-%03d", count), iterator.Key().RawString())
+		assert.Equal(t, fmt.Sprintf("raft%d", count), iterator.Value().String())
+		_ = iterator.Next()
+	}
+	assert.False(t, iterator.IsValid())
+}
+
+func TestBlockWithKeysSharingPrefixesIsSmallerThanTheBlockWithEntireKeys(t *testing.T) {
+	blockBuilder := NewBlockBuilder(4096)
+	blockBuilderWithEntireKeys := newBlockBuilderWithRestartInterval(4096, 1)
+
+	for count := 0; count < 40; count++ {
+		key := kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/zero-store/user-%03d", count), 10)
+		blockBuilder.Add(key, kv.NewStringValue("raft"))
+		blockBuilderWithEntireKeys.Add(key, kv.NewStringValue("raft"))
+	}
+	assert.True(t, blockBuilder.size() < blockBuilderWithEntireKeys.size())
+}
+
+func TestDecodeABlockOfFormatVersion1WithEachKeyValueAsARestartPoint(t *testing.T) {
+	blockBuilder := newBlockBuilderOfFormatVersion(128, 1, FormatVersion1)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consistent", 11), kv.NewStringValue("etcd"))
+	assert.Equal(t, 2, len(blockBuilder.restartPoints))
+
+	key := kv.NewStringKeyWithTimestamp("consensus", 10)
+	var expectedData []byte
+	expectedData = binary.LittleEndian.AppendUint16(expectedData, uint16(key.EncodedSizeInBytes()))
+	expectedData = append(expectedData, key.EncodedBytes()...)
+	expectedData = binary.LittleEndian.AppendUint32(expectedData, kv.NewStringValue("raft").SizeAsUint32())
+	expectedData = append(expectedData, kv.NewStringValue("raft").EncodedBytes()...)
+
+	buffer := blockBuilder.Build().Encode()
+	assert.Equal(t, expectedData, buffer[:len(expectedData)])
+
+	decodedBlock, err := DecodeToBlockWithOverflowOfFormatVersion(buffer, 128, FormatVersion1)
+	assert.NoError(t, err)
+
+	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("consistent", 11))
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "etcd", iterator.Value().String())
+}
+
+func TestAttemptToDecodeABlockWithARestartPointWhichIsNotAKeyValueOffset(t *testing.T) {
+	blockBuilder := NewBlockBuilder(64)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	block := blockBuilder.Build()
+	block.restartPoints = append(block.restartPoints, 3)
+
+	_, err := DecodeToBlock(block.Encode())
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
+
+func TestAttemptToDecodeABlockWithAnInvalidSharedKeySize(t *testing.T) {
+	blockBuilder := NewBlockBuilder(64)
+	firstKey := kv.NewStringKeyWithTimestamp("consensus", 10)
+	blockBuilder.Add(firstKey, kv.NewStringValue("raft"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consistent", 11), kv.NewStringValue("etcd"))
+
+	buffer := blockBuilder.Build().Encode()
+	currentLayout := layoutOf(CurrentFormatVersion)
+	secondKeyValueOffset := currentLayout.keySizeLength(firstKey.EncodedSizeInBytes()) + firstKey.EncodedSizeInBytes() +
+		currentLayout.valueSizeLength(kv.NewStringValue("raft").SizeAsUint32()) + kv.NewStringValue("raft").SizeInBytes()
+	binary.LittleEndian.PutUint16(buffer[secondKeyValueOffset:], 0xFF)
+
+	_, err := DecodeToBlock(buffer)
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
+
+func TestEncodeAndDecodeBlockWithHashIndex(t *testing.T) {
+	blockBuilder := NewBlockBuilderWithHashIndex(4096)
+	for count := 0; count < 40; count++ {
+		for _, timestamp := range []uint64{15, 10, 5} {
+			key := kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%02d", count), timestamp)
+			assert.True(t, blockBuilder.Add(key, kv.NewStringValue(fmt.Sprintf("value-%d-%d", count, timestamp))))
+		}
+	}
+
+	decodedBlock, err := DecodeToBlock(blockBuilder.Build().Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, numberOfHashIndexBuckets(40), len(decodedBlock.hashIndex))
+
+	for count := 0; count < 40; count++ {
+		for _, timestamp := range []uint64{16, 15, 12, 10, 5} {
+			iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%02d", count), timestamp))
+			expectedTimestamp := timestamp
+			if timestamp == 16 {
+				expectedTimestamp = 15
+			}
+			if timestamp == 12 {
+				expectedTimestamp = 10
+			}
+			assert.True(t, iterator.IsValid())
+			assert.Equal(t, fmt.Sprintf("tenant/key-%02d", count), iterator.Key().RawString())
+			assert.Equal(t, fmt.Sprintf("value-%d-%d", count, expectedTimestamp), iterator.Value().String())
+			iterator.Close()
+		}
+	}
+}
+
+func TestBlockWithHashIndexSeekToKeysNotPresentInTheBlock(t *testing.T) {
+	blockBuilder := NewBlockBuilderWithHashIndex(4096)
+	for count := 0; count < 40; count += 2 {
+		blockBuilder.Add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%02d", count), 10), kv.NewStringValue("raft"))
+	}
+
+	decodedBlock, err := DecodeToBlock(blockBuilder.Build().Encode())
+	assert.NoError(t, err)
+
+	for count := 1; count < 40; count += 2 {
+		iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%02d", count), 10))
+		if count == 39 {
+			assert.False(t, iterator.IsValid())
+			continue
+		}
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, fmt.Sprintf("tenant/key-%02d", count+1), iterator.Key().RawString())
+		iterator.Close()
+	}
+
+	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("tenant/key-10", 5))
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "tenant/key-12", iterator.Key().RawString())
+}
+
+func TestBlockWithHashIndexFitsInTheBlock(t *testing.T) {
+	blockBuilder := NewBlockBuilderWithHashIndex(256)
+	count := 0
+	for blockBuilder.Add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%03d", count), 10), kv.NewStringValue("raft")) {
+		count++
+	}
+	assert.True(t, blockBuilder.size()+hashIndexSizeInBytes(count, blockBuilder.layout) <= 256)
+
+	decodedBlock, err := DecodeToBlock(blockBuilder.Build().Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, numberOfHashIndexBuckets(count), len(decodedBlock.hashIndex))
+
+	iterator := decodedBlock.SeekToFirst()
+	defer iterator.Close()
+
+	for index := 0; index < count; index++ {
+		assert.Equal(t, fmt.Sprintf("tenant/key-%03d", index), iterator.Key().RawString())
+		_ = iterator.Next()
+	}
+	assert.False(t, iterator.IsValid())
+}
+
+func TestBlockWithoutHashIndexGivenTooManyRestartPoints(t *testing.T) {
+	blockBuilder := newBlockBuilderWithRestartInterval(MaximumBlockSize, 1)
+	blockBuilder.hashIndexBuilder = &hashIndexBuilder{}
+	for count := 0; count <= maxRestartPointsForHashIndex; count++ {
+		assert.True(t, blockBuilder.Add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%03d", count), 10), kv.NewStringValue("raft")))
+	}
+
+	decodedBlock, err := DecodeToBlock(blockBuilder.Build().Encode())
+	assert.NoError(t, err)
+	assert.Nil(t, decodedBlock.hashIndex)
+
+	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("tenant/key-100", 10))
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "tenant/key-100", iterator.Key().RawString())
+}
+
+func TestAttemptToDecodeABlockWithAnInvalidNumberOfHashIndexBuckets(t *testing.T) {
+	blockBuilder := NewBlockBuilderWithHashIndex(64)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	buffer := blockBuilder.Build().Encode()
+	binary.LittleEndian.PutUint32(buffer[len(buffer)-3*Uint32Size:], 0xFF)
+
+	_, err := DecodeToBlock(buffer)
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
+
+func TestEncodeAndDecodeABlockOfFormatVersion1(t *testing.T) {
+	blockBuilder := newBlockBuilderOfFormatVersion(4096, DefaultRestartInterval, FormatVersion1)
+	for count := 0; count < 40; count++ {
+		key := kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/zero-store/user-%03d", count), 10)
+		assert.True(t, blockBuilder.Add(key, kv.NewStringValue(fmt.Sprintf("raft%d", count))))
+	}
+	buffer := blockBuilder.Build().Encode()
+	assert.Equal(t, uint16(3), binary.LittleEndian.Uint16(buffer[len(buffer)-Uint16Size:]))
+
+	decodedBlock, err := DecodeToBlockWithOverflowOfFormatVersion(buffer, 4096, FormatVersion1)
+	assert.NoError(t, err)
+
+	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp("tenant/zero-store/user-021", 10))
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "raft21", iterator.Value().String())
+}
+
+func TestEncodeAndDecodeABlockLargerThan64KiBWithAKeyLargerThan64KiB(t *testing.T) {
+	blockSize := 256 * kb
+	largeRawKey := strings.Repeat("k", 70*1024)
+
+	blockBuilder := NewBlockBuilderWithHashIndex(blockSize)
+	assert.True(t, blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(strings.Repeat("raft", 20*1024))))
+	assert.True(t, blockBuilder.Add(kv.NewStringKeyWithTimestamp(largeRawKey, 10), kv.NewStringValue("etcd")))
+	assert.True(t, blockBuilder.Add(kv.NewStringKeyWithTimestamp(largeRawKey+"-suffix", 10), kv.NewStringValue("paxos")))
+
+	decodedBlock, err := DecodeToBlockWithOverflow(blockBuilder.Build().Encode(), blockSize)
+	assert.NoError(t, err)
+
+	iterator := decodedBlock.SeekToKey(kv.NewStringKeyWithTimestamp(largeRawKey, 10))
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, largeRawKey, iterator.Key().RawString())
+	assert.Equal(t, "etcd", iterator.Value().String())
+
+	_ = iterator.Next()
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, largeRawKey+"-suffix", iterator.Key().RawString())
+	assert.Equal(t, "paxos", iterator.Value().String())
+}
+
+func TestAttemptToDecodeABlockOfFormatVersion1AsABlockOfFormatVersion2(t *testing.T) {
+	blockBuilder := newBlockBuilderOfFormatVersion(128, DefaultRestartInterval, FormatVersion1)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	_, err := DecodeToBlockWithOverflowOfFormatVersion(blockBuilder.Build().Encode(), 128, FormatVersion2)
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
+
+func FuzzDecodeToBlockWithOverflow(f *testing.F) {
+	blockBuilder := NewBlockBuilder(64)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("distributed", 11), kv.NewStringValue("etcd"))
+	f.Add(blockBuilder.Build().Encode(), uint(64))
+
+	blockBuilder = NewBlockBuilder(40)
+	overflow, _ := blockBuilder.AddOverflowing(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(strings.Repeat("raft", 20)))
+	f.Add(append(blockBuilder.Build().Encode(), overflow...), uint(40))
+	blockBuilder = newBlockBuilderWithRestartInterval(128, 2)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/consensus", 10), kv.NewStringValue("raft"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/consistent", 11), kv.NewStringValue("etcd"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/distributed", 12), kv.NewStringValue("kv"))
+	f.Add(blockBuilder.Build().Encode(), uint(128))
+
+	blockBuilder = NewBlockBuilderWithHashIndex(128)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/consensus", 10), kv.NewStringValue("raft"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/distributed", 12), kv.NewStringValue("kv"))
+	f.Add(blockBuilder.Build().Encode(), uint(128))
+	f.Add([]byte{}, uint(0))
+
+	f.Fuzz(func(t *testing.T, data []byte, blockSize uint) {
+		block, err := DecodeToBlockWithOverflow(data, blockSize)
+		if err != nil {
+			return
+		}
+		iterator := block.SeekToFirst()
+		for iterator.IsValid() {
+			_ = block.SeekToKey(iterator.Key())
+			_ = iterator.Next()
+		}
+	})
+}
+
+func TestSizeInBytesOfADecodedBlock(t *testing.T) {
+	blockBuilder := NewBlockBuilder(1024)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("distributed", 11), kv.NewStringValue("etcd"))
+
+	buffer := blockBuilder.Build().Encode()
+	decodedBlock, err := DecodeToBlock(buffer)
+	assert.NoError(t, err)
+
+	assert.True(t, decodedBlock.SizeInBytes() > 0)
+	assert.True(t, decodedBlock.SizeInBytes() <= len(buffer))
+}
+
+func TestCanFitKeyInAnEmptyBlockAgreesWithAddOverflowing(t *testing.T) {
+	key := kv.NewStringKeyWithTimestamp(strings.Repeat("consensus", 10), 10)
+	value := kv.NewStringValue(strings.Repeat("raft", 300))
+
+	for blockSize := uint(key.EncodedSizeInBytes()); blockSize < uint(key.EncodedSizeInBytes()+64); blockSize++ {
+		_, ok := NewBlockBuilder(blockSize).AddOverflowing(key, value)
+		assert.Equal(t, ok, CanFitKeyInAnEmptyBlock(blockSize, false, key, value.SizeAsUint32()))
+
+		_, ok = NewBlockBuilderWithHashIndex(blockSize).AddOverflowing(key, value)
+		assert.Equal(t, ok, CanFitKeyInAnEmptyBlock(blockSize, true, key, value.SizeAsUint32()))
+	}
+}
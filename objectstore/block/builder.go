@@ -1,7 +1,7 @@
 package block
 
 import (
-	"encoding/binary"
+	"bytes"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"unsafe"
 )
@@ -10,25 +10,51 @@ var ReservedKeySize = int(unsafe.Sizeof(uint16(0)))
 var ReservedValueSize = int(unsafe.Sizeof(uint32(0)))
 var KeyValueOffsetSize = int(unsafe.Sizeof(uint16(0)))
 
+// SharedKeySize is the size of the length of the key prefix, which a key/value pair (other than a restart point) shares
+// with the previous key.
+var SharedKeySize = int(unsafe.Sizeof(uint16(0)))
+
 var Uint16Size = int(unsafe.Sizeof(uint16(0)))
 var Uint32Size = int(unsafe.Sizeof(uint32(0)))
+var Uint64Size = int(unsafe.Sizeof(uint64(0)))
 
 const kb uint = 1024
 const DefaultBlockSize = 4 * kb
 
+// MinimumBlockSize is the minimum block size, a block (including the footer block) must be able to hold its trailer and offsets.
+const MinimumBlockSize uint = 64
+
+// MaximumBlockSize is the maximum block size.
+// The offsets within a block of FormatVersion2 are encoded as uint32, the blocks of the earlier format versions (which
+// encode the offsets as uint16) are never larger than 64 KiB.
+const MaximumBlockSize = 4 * kb * kb
+
+// DefaultRestartInterval is the number of key/value pairs between two restart points of a block.
+const DefaultRestartInterval = 16
+
 // Builder represents a block builder.
-// keyValueBeginOffsets contain the begin-offsets of each of the keys that a part of the block.
-// firstKey is the first key of the block.
+// restartPoints contain the begin-offsets of the key/value pairs which store their entire key.
 // data contains the encoded key/value pairs.
 //
-// Each block contains encoded key/value pairs, and keyValueBeginOffsets. The reason for storing keyValueBeginOffsets is to allow
-// binary search for a key within a block. The keyValueBeginOffsets are always in increasing order, hence binary search can be used.
+// Sorted keys within a block share long prefixes (like tenant/...), so each key is delta encoded against the previous key:
+// the key/value pair stores the length of the shared prefix and the rest of the key. Every restartInterval-th key/value
+// pair is a restart point, which stores its entire key (and does not store the shared length).
+// The reason for storing restartPoints is to allow binary search for a key within a block. The restartPoints are always
+// in increasing order, hence binary search can be used to find the restart point, followed by a linear scan.
 // Please check Block.SeekToKey().
+//
+// A Builder created with hash index (please check NewBlockBuilderWithHashIndex) appends a hashIndex to the block, which maps
+// each raw key to its restart point.
 type Builder struct {
-	keyValueBeginOffsets []uint16
-	blockSize            uint
-	data                 []byte
-	index                int
+	restartPoints             []uint32
+	restartInterval           int
+	keysSinceLastRestartPoint int
+	previousEncodedKey        []byte
+	hashIndexBuilder          *hashIndexBuilder
+	blockSize                 uint
+	data                      []byte
+	index                     int
+	layout                    layout
 }
 
 // NewBlockBuilderWithDefaultBlockSize creates a new instance of block builder with blocksize as DefaultBlockSize.
@@ -36,59 +62,204 @@ func NewBlockBuilderWithDefaultBlockSize() *Builder {
 	return NewBlockBuilder(DefaultBlockSize)
 }
 
-// NewBlockBuilder creates a new instance of block builder.
+// NewBlockBuilder creates a new instance of block builder with DefaultRestartInterval.
 func NewBlockBuilder(blockSize uint) *Builder {
+	return newBlockBuilderWithRestartInterval(blockSize, DefaultRestartInterval)
+}
+
+// NewBlockBuilderWithHashIndex creates a new instance of block builder with DefaultRestartInterval, which appends a hash
+// index to the block. The hash index allows point lookups to skip the binary search over the restart points.
+func NewBlockBuilderWithHashIndex(blockSize uint) *Builder {
+	builder := NewBlockBuilder(blockSize)
+	builder.hashIndexBuilder = &hashIndexBuilder{}
+	return builder
+}
+
+// newBlockBuilderWithRestartInterval creates a new instance of block builder of CurrentFormatVersion.
+func newBlockBuilderWithRestartInterval(blockSize uint, restartInterval int) *Builder {
+	return newBlockBuilderOfFormatVersion(blockSize, restartInterval, CurrentFormatVersion)
+}
+
+// newBlockBuilderOfFormatVersion creates a new instance of block builder which encodes the block of the given format version.
+// A restartInterval of 1 (with FormatVersion1) stores the entire key of each key/value pair, which is the encoding of the
+// blocks written before the keys were delta encoded.
+func newBlockBuilderOfFormatVersion(blockSize uint, restartInterval int, formatVersion uint16) *Builder {
 	return &Builder{
-		blockSize: blockSize,
-		data:      make([]byte, blockSize),
-		index:     0,
+		restartInterval: restartInterval,
+		blockSize:       blockSize,
+		data:            make([]byte, blockSize),
+		index:           0,
+		layout:          layoutOf(formatVersion),
 	}
 }
 
-// Add adds the key/value pair, along with the begin-offset of the pair in the builder.
+// Add adds the key/value pair, along with the begin-offset of the pair (if it is a restart point) in the builder.
 // This involves:
-// 1) Keeping a track of the first key in the block builder.
-// 2) Storing the begin-offset of the key/value pair in keyValueBeginOffsets.
-// 3) Storing the key/value pair.
+// 1) Storing the begin-offset of the key/value pair in restartPoints, if the pair is a restart point.
+// 2) Storing the key/value pair, with the key delta encoded against the previous key.
 func (builder *Builder) Add(key kv.Key, value kv.Value) bool {
-	//TODO: what if key and value size is greater than block size?
-	if uint(builder.size()+key.EncodedSizeInBytes()+value.SizeInBytes()+ReservedKeySize+ReservedValueSize+KeyValueOffsetSize) > builder.blockSize {
+	encodedKey := key.EncodedBytes()
+	if uint(builder.size()+builder.encodedSizeOf(encodedKey, value.SizeInBytes())+builder.hashIndexSizeAfterAdding(encodedKey)) > builder.blockSize {
 		return false
 	}
+	builder.add(encodedKey, value.EncodedBytes(), value.SizeAsUint32())
+	return true
+}
 
-	builder.keyValueBeginOffsets = append(builder.keyValueBeginOffsets, uint16(builder.index))
-	keyValueBuffer := make([]byte, ReservedKeySize+ReservedValueSize+key.EncodedSizeInBytes()+value.SizeInBytes())
-
-	binary.LittleEndian.PutUint16(keyValueBuffer[:], uint16(key.EncodedSizeInBytes()))
-	copy(keyValueBuffer[ReservedKeySize:], key.EncodedBytes())
-
-	binary.LittleEndian.PutUint32(keyValueBuffer[ReservedKeySize+key.EncodedSizeInBytes():], value.SizeAsUint32())
-	copy(keyValueBuffer[ReservedKeySize+key.EncodedSizeInBytes()+ReservedValueSize:], value.EncodedBytes())
-
-	n := copy(builder.data[builder.index:], keyValueBuffer)
-	builder.index += n
+// AddOverflowing adds the key/value pair which does not fit in an empty block.
+// The key, and the part of the value which fits in the block are stored in the block. The rest of the value is returned
+// as overflow bytes, which are stored right after the block (please check segment.SortedSegmentBuilder).
+// The encoded key/value pair keeps the size of the entire value, this allows block.Iterator to read the value back from the
+// block and its overflow bytes.
+// It returns false if the builder is not empty, or the key (along with at-least one byte of the value) does not fit in the block.
+func (builder *Builder) AddOverflowing(key kv.Key, value kv.Value) ([]byte, bool) {
+	if !builder.IsEmpty() {
+		return nil, false
+	}
+	encodedKey, encodedValue := key.EncodedBytes(), value.EncodedBytes()
+	availableSizeForValue := builder.layout.availableSizeForValueInAnEmptyBlock(
+		builder.blockSize,
+		builder.hashIndexSizeAfterAdding(encodedKey),
+		len(encodedKey),
+		value.SizeAsUint32(),
+	)
+	if availableSizeForValue <= 0 {
+		return nil, false
+	}
+	if availableSizeForValue >= len(encodedValue) {
+		builder.add(encodedKey, encodedValue, value.SizeAsUint32())
+		return nil, true
+	}
+	builder.add(encodedKey, encodedValue[:availableSizeForValue], value.SizeAsUint32())
+	return encodedValue[availableSizeForValue:], true
+}
 
-	return true
+// CanFitKeyInAnEmptyBlock returns true if the key (along with at-least one byte of a value of the given size) fits in an
+// empty block of the given size, that is, if a Builder of the block size (with or without the hash index) can add the
+// key/value pair using AddOverflowing.
+func CanFitKeyInAnEmptyBlock(blockSize uint, withHashIndex bool, key kv.Key, valueSize uint32) bool {
+	layout := layoutOf(CurrentFormatVersion)
+	hashIndexSize := 0
+	if withHashIndex {
+		hashIndexSize = hashIndexSizeInBytes(1, layout)
+	}
+	return layout.availableSizeForValueInAnEmptyBlock(blockSize, hashIndexSize, key.EncodedSizeInBytes(), valueSize) > 0
 }
 
-// isEmpty returns true if the builder has not stored any key/value pair.
-func (builder *Builder) isEmpty() bool {
-	return len(builder.keyValueBeginOffsets) == 0
+// IsEmpty returns true if the builder has not stored any key/value pair.
+func (builder *Builder) IsEmpty() bool {
+	return len(builder.restartPoints) == 0
 }
 
 // Build creates a new instance of Block.
+// The Block does not contain the hash index if the builder was created without the hash index, or the block has more
+// restart points than the hash index can refer to.
 func (builder *Builder) Build() Block {
-	if builder.isEmpty() {
+	if builder.IsEmpty() {
 		panic("cannot build an empty Block")
 	}
-	return newBlock(builder.data, builder.index, builder.keyValueBeginOffsets)
+	block := newBlock(builder.data, builder.index, builder.restartPoints, builder.layout)
+	if builder.hashIndexBuilder != nil {
+		if index, ok := builder.hashIndexBuilder.build(len(builder.restartPoints)); ok {
+			block.hashIndex = index
+		}
+	}
+	return block
+}
+
+// add stores the begin-offset of the key/value pair in restartPoints (if the pair is a restart point), and the encoded key/value pair.
+// valueSize is the size of the entire encoded value, encodedValue may only be a part of it (please check Builder.AddOverflowing).
+/*
+// key/value pair encoding looks like the following:
+  -------------------------------------------------------------------------------------------------
+ | shared key size (2 bytes) | unshared key size (2 bytes) | unshared key | value size (4 bytes) | value |
+  -------------------------------------------------------------------------------------------------
+  The shared key size is not stored for a restart point, its unshared key is the entire key.
+  The blocks of FormatVersion2 encode the shared key size, the unshared key size and the value size as varints.
+*/
+func (builder *Builder) add(encodedKey []byte, encodedValue []byte, valueSize uint32) {
+	isNewRawKey := builder.isNewRawKey(encodedKey)
+	sharedKeySize := 0
+	if builder.isNextRestartPoint() {
+		builder.restartPoints = append(builder.restartPoints, uint32(builder.index))
+		builder.keysSinceLastRestartPoint = 0
+	} else {
+		sharedKeySize = sharedPrefixLength(builder.previousEncodedKey, encodedKey)
+		builder.index += builder.layout.putKeySize(builder.data[builder.index:], sharedKeySize)
+	}
+	if builder.hashIndexBuilder != nil && isNewRawKey {
+		builder.hashIndexBuilder.add(encodedKey[:len(encodedKey)-kv.TimestampSize], len(builder.restartPoints)-1)
+	}
+	unsharedKey := encodedKey[sharedKeySize:]
+
+	builder.index += builder.layout.putKeySize(builder.data[builder.index:], len(unsharedKey))
+	builder.index += copy(builder.data[builder.index:], unsharedKey)
+
+	builder.index += builder.layout.putValueSize(builder.data[builder.index:], valueSize)
+	builder.index += copy(builder.data[builder.index:], encodedValue)
+
+	builder.previousEncodedKey = encodedKey
+	builder.keysSinceLastRestartPoint++
+}
+
+// hashIndexSizeAfterAdding returns the size of the hash index after adding the given key, it returns 0 if the builder was
+// created without the hash index.
+func (builder *Builder) hashIndexSizeAfterAdding(encodedKey []byte) int {
+	if builder.hashIndexBuilder == nil {
+		return 0
+	}
+	numberOfRawKeys := builder.hashIndexBuilder.numberOfRawKeys()
+	if builder.isNewRawKey(encodedKey) {
+		numberOfRawKeys++
+	}
+	return hashIndexSizeInBytes(numberOfRawKeys, builder.layout)
+}
+
+// isNewRawKey returns true if the raw key of the given encoded key is not the raw key of the previous key.
+// The different versions (timestamps) of a raw key are added one after the other.
+func (builder *Builder) isNewRawKey(encodedKey []byte) bool {
+	if builder.IsEmpty() {
+		return true
+	}
+	previousRawKey := builder.previousEncodedKey[:len(builder.previousEncodedKey)-kv.TimestampSize]
+	return !bytes.Equal(previousRawKey, encodedKey[:len(encodedKey)-kv.TimestampSize])
+}
+
+// encodedSizeOf returns the size that the key/value pair would take in the builder, including its restart point offset.
+func (builder *Builder) encodedSizeOf(encodedKey []byte, valueSize int) int {
+	valueSizeLength := builder.layout.valueSizeLength(uint32(valueSize))
+	if builder.isNextRestartPoint() {
+		return builder.layout.keySizeLength(len(encodedKey)) + len(encodedKey) + valueSizeLength + valueSize + builder.layout.offsetSize()
+	}
+	sharedKeySize := sharedPrefixLength(builder.previousEncodedKey, encodedKey)
+	unsharedKeySize := len(encodedKey) - sharedKeySize
+	return builder.layout.keySizeLength(sharedKeySize) + builder.layout.keySizeLength(unsharedKeySize) + unsharedKeySize +
+		valueSizeLength + valueSize
+}
+
+// isNextRestartPoint returns true if the next key/value pair is a restart point.
+func (builder *Builder) isNextRestartPoint() bool {
+	return builder.IsEmpty() || builder.keysSinceLastRestartPoint >= builder.restartInterval
 }
 
 // size returns the size of the builder.
-// The size includes: the size of encoded key/values (builder.data) + size of N keyValueBeginOffsets + Reserved bytes.
+// The size includes: the size of encoded key/values (builder.data) + size of N restartPoints + Reserved bytes.
+// The size of the hash index is not included, please check Builder.hashIndexSizeAfterAdding.
 func (builder *Builder) size() int {
+	offsetSize := builder.layout.offsetSize()
 	return len(builder.data[:builder.index]) +
-		len(builder.keyValueBeginOffsets)*Uint16Size +
-		Uint16Size + //block uses last 2 bytes for the number of begin offsets
-		Uint16Size //block uses 2 bytes before the last 2 bytes for the start offset of begin offsets
+		len(builder.restartPoints)*offsetSize +
+		offsetSize + //block uses the last offset for the number of restart points
+		offsetSize //block uses the offset before the last offset for the start offset of restart points
+}
+
+// sharedPrefixLength returns the length of the common prefix of the two encoded keys.
+func sharedPrefixLength(encodedKey, otherEncodedKey []byte) int {
+	length := min(len(encodedKey), len(otherEncodedKey))
+	for index := 0; index < length; index++ {
+		if encodedKey[index] != otherEncodedKey[index] {
+			return index
+		}
+	}
+	return length
 }
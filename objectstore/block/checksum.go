@@ -0,0 +1,96 @@
+package block
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// ChecksumSize is the size of a CRC32C checksum.
+var ChecksumSize = Uint32Size
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Checksum returns the CRC32C checksum of the given byte slice.
+func Checksum(data []byte) uint32 {
+	return crc32.Checksum(data, castagnoliTable)
+}
+
+// AppendChecksum appends the CRC32C checksum of the given byte slice to it.
+func AppendChecksum(data []byte) []byte {
+	return binary.LittleEndian.AppendUint32(data, Checksum(data))
+}
+
+// VerifyAndStripChecksum verifies the CRC32C checksum which is the last ChecksumSize bytes of the given byte slice.
+// It returns the byte slice without the checksum, and false if the checksum does not match.
+func VerifyAndStripChecksum(data []byte) ([]byte, bool) {
+	if len(data) < ChecksumSize {
+		return nil, false
+	}
+	content := data[:len(data)-ChecksumSize]
+	if Checksum(content) != binary.LittleEndian.Uint32(data[len(data)-ChecksumSize:]) {
+		return nil, false
+	}
+	return content, true
+}
+
+// Checksums is the checksums section of the persistent sorted segment.
+// It contains the CRC32C checksums of the block meta list, the bloom filter and each data block (including its overflow bytes).
+// The footer block contains its own checksum, please check segment.SortedSegmentBuilder.
+type Checksums struct {
+	BlockMetaList uint32
+	BloomFilter   uint32
+	Blocks        []uint32
+}
+
+// VerifyBlock verifies the checksum of the data block at the given index.
+func (checksums *Checksums) VerifyBlock(blockIndex int, data []byte) bool {
+	if blockIndex >= len(checksums.Blocks) {
+		return false
+	}
+	return checksums.Blocks[blockIndex] == Checksum(data)
+}
+
+// Encode encodes the Checksums as byte slice.
+// Encoding includes:
+/*
+  ------------------------------------------------------------------------------------------------------------------------------------
+ | 4 bytes for the number of blocks | 4 bytes block meta list checksum | 4 bytes bloom filter checksum | 4 bytes block checksum | 4 bytes checksum |
+  ------------------------------------------------------------------------------------------------------------------------------------
+                                                                                                     <------for each block------>
+*/
+// The last 4 bytes are the checksum of the Checksums section itself.
+func (checksums *Checksums) Encode() []byte {
+	buffer := make([]byte, 0, Uint32Size*(3+len(checksums.Blocks))+ChecksumSize)
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(len(checksums.Blocks)))
+	buffer = binary.LittleEndian.AppendUint32(buffer, checksums.BlockMetaList)
+	buffer = binary.LittleEndian.AppendUint32(buffer, checksums.BloomFilter)
+	for _, blockChecksum := range checksums.Blocks {
+		buffer = binary.LittleEndian.AppendUint32(buffer, blockChecksum)
+	}
+	return AppendChecksum(buffer)
+}
+
+// DecodeToChecksums decodes the byte slice and returns an instance of Checksums.
+// It returns ErrChecksumMismatch if the checksum of the Checksums section does not match.
+func DecodeToChecksums(buffer []byte) (*Checksums, error) {
+	content, ok := VerifyAndStripChecksum(buffer)
+	if !ok || len(content) < 3*Uint32Size {
+		return nil, ErrChecksumMismatch
+	}
+	numberOfBlocks := int(binary.LittleEndian.Uint32(content[:]))
+	if len(content) != (3+numberOfBlocks)*Uint32Size {
+		return nil, ErrChecksumMismatch
+	}
+	checksums := &Checksums{
+		BlockMetaList: binary.LittleEndian.Uint32(content[Uint32Size:]),
+		BloomFilter:   binary.LittleEndian.Uint32(content[2*Uint32Size:]),
+		Blocks:        make([]uint32, 0, numberOfBlocks),
+	}
+	for index := 3 * Uint32Size; index < len(content); index += Uint32Size {
+		checksums.Blocks = append(checksums.Blocks, binary.LittleEndian.Uint32(content[index:]))
+	}
+	return checksums, nil
+}
@@ -0,0 +1,72 @@
+package block
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVerifyAndStripAnAppendedChecksum(t *testing.T) {
+	data, ok := VerifyAndStripChecksum(AppendChecksum([]byte("raft")))
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(data))
+}
+
+func TestVerifyAChecksumOfACorruptedByteSlice(t *testing.T) {
+	data := AppendChecksum([]byte("raft"))
+	data[0] = 'c'
+
+	_, ok := VerifyAndStripChecksum(data)
+	assert.False(t, ok)
+}
+
+func TestVerifyAChecksumOfAShortByteSlice(t *testing.T) {
+	_, ok := VerifyAndStripChecksum([]byte("ra"))
+	assert.False(t, ok)
+}
+
+func TestEncodeAndDecodeChecksums(t *testing.T) {
+	checksums := &Checksums{
+		BlockMetaList: Checksum([]byte("meta")),
+		BloomFilter:   Checksum([]byte("filter")),
+		Blocks:        []uint32{Checksum([]byte("raft")), Checksum([]byte("paxos"))},
+	}
+
+	decodedChecksums, err := DecodeToChecksums(checksums.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, checksums, decodedChecksums)
+	assert.True(t, decodedChecksums.VerifyBlock(0, []byte("raft")))
+	assert.True(t, decodedChecksums.VerifyBlock(1, []byte("paxos")))
+	assert.False(t, decodedChecksums.VerifyBlock(1, []byte("raft")))
+	assert.False(t, decodedChecksums.VerifyBlock(2, []byte("raft")))
+}
+
+func TestDecodeCorruptedChecksums(t *testing.T) {
+	checksums := &Checksums{
+		BlockMetaList: Checksum([]byte("meta")),
+		BloomFilter:   Checksum([]byte("filter")),
+		Blocks:        []uint32{Checksum([]byte("raft"))},
+	}
+	encoded := checksums.Encode()
+	encoded[5] ^= 0xFF
+
+	_, err := DecodeToChecksums(encoded)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func FuzzDecodeToChecksums(f *testing.F) {
+	checksums := &Checksums{
+		BlockMetaList: Checksum([]byte("meta")),
+		BloomFilter:   Checksum([]byte("filter")),
+		Blocks:        []uint32{Checksum([]byte("raft"))},
+	}
+	f.Add(checksums.Encode())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		decodedChecksums, err := DecodeToChecksums(buffer)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, buffer, decodedChecksums.Encode())
+	})
+}
@@ -0,0 +1,218 @@
+package block
+
+import (
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"sync"
+)
+
+// CompressionCodec identifies the codec used to compress a data block, it is recorded in the block Meta of each block.
+// The identifiers are a part of the persistent format, they must never be changed.
+type CompressionCodec uint8
+
+const (
+	CompressionCodecNone   CompressionCodec = 0
+	CompressionCodecS2     CompressionCodec = 1
+	CompressionCodecSnappy CompressionCodec = 2
+	CompressionCodecZstd   CompressionCodec = 3
+	CompressionCodecLz4    CompressionCodec = 4
+	// CompressionCodecZstdDictionary denotes the zstd compression using the CompressionDictionary of the persistent sorted
+	// segment. The codec is not usable without the CompressionDictionary, please check CompressWithDictionary and
+	// DecompressWithDictionary.
+	CompressionCodecZstdDictionary CompressionCodec = 5
+)
+
+var (
+	ErrUnknownCompressionCodec = errors.New("unknown compression codec")
+	ErrDecompression           = errors.New("failed to decompress the block")
+	errDecodedLengthMismatch   = errors.New("decoded length does not match the uncompressed size")
+)
+
+// Codec compresses and decompresses the data blocks.
+// Compress returns false if the data is not compressible, such a data block is stored without compression
+// (CompressionCodecNone).
+// Decompress receives the size of the data before compression, which is recorded in the block Meta.
+// Implementations must be safe for concurrent use.
+type Codec interface {
+	Compress(data []byte) ([]byte, bool)
+	Decompress(data []byte, uncompressedSize int) ([]byte, error)
+}
+
+var (
+	codecsLock sync.RWMutex
+	codecs     = map[CompressionCodec]Codec{
+		CompressionCodecNone:   noneCodec{},
+		CompressionCodecS2:     s2Codec{},
+		CompressionCodecSnappy: snappyCodec{},
+		CompressionCodecZstd:   newZstdCodec(),
+		CompressionCodecLz4:    lz4Codec{},
+		// the identifier is reserved, the CompressionDictionary is the codec.
+		CompressionCodecZstdDictionary: dictionaryRequiredCodec{},
+	}
+)
+
+// RegisterCodec registers the given Codec with the given CompressionCodec identifier.
+// It allows plugging in the codecs which are not available by default. It panics if a codec is already registered with the
+// given identifier, because the identifiers are recorded in the persistent sorted segments.
+func RegisterCodec(compressionCodec CompressionCodec, codec Codec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+
+	if _, ok := codecs[compressionCodec]; ok {
+		panic(fmt.Sprintf("codec %v is already registered", compressionCodec))
+	}
+	codecs[compressionCodec] = codec
+}
+
+// CodecFor returns the Codec registered with the given CompressionCodec identifier.
+func CodecFor(compressionCodec CompressionCodec) (Codec, error) {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+
+	codec, ok := codecs[compressionCodec]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownCompressionCodec, compressionCodec)
+	}
+	return codec, nil
+}
+
+// Compress compresses the given data block (including its overflow bytes) using the given CompressionCodec.
+// It returns the data block as is along with CompressionCodecNone, if the data block is not compressible, or its compressed
+// size is not smaller than its size.
+func Compress(compressionCodec CompressionCodec, data []byte) ([]byte, CompressionCodec, error) {
+	codec, err := CodecFor(compressionCodec)
+	if err != nil {
+		return nil, CompressionCodecNone, err
+	}
+	compressed, ok := codec.Compress(data)
+	if !ok || len(compressed) >= len(data) {
+		return data, CompressionCodecNone, nil
+	}
+	return compressed, compressionCodec, nil
+}
+
+// Decompress decompresses the given data block using the given CompressionCodec.
+// It returns ErrDecompression if the decompressed size does not match uncompressedSize.
+func Decompress(compressionCodec CompressionCodec, data []byte, uncompressedSize int) ([]byte, error) {
+	codec, err := CodecFor(compressionCodec)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(codec, data, uncompressedSize)
+}
+
+// decompress decompresses the given data block using the given Codec, and validates the decompressed size.
+func decompress(codec Codec, data []byte, uncompressedSize int) ([]byte, error) {
+	decompressed, err := codec.Decompress(data, uncompressedSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecompression, err)
+	}
+	if len(decompressed) != uncompressedSize {
+		return nil, fmt.Errorf("%w: decompressed size %v, expected %v", ErrDecompression, len(decompressed), uncompressedSize)
+	}
+	return decompressed, nil
+}
+
+type noneCodec struct{}
+
+func (noneCodec) Compress(data []byte) ([]byte, bool) {
+	return data, false
+}
+
+func (noneCodec) Decompress(data []byte, _ int) ([]byte, error) {
+	return data, nil
+}
+
+type dictionaryRequiredCodec struct{}
+
+func (dictionaryRequiredCodec) Compress(data []byte) ([]byte, bool) {
+	return data, false
+}
+
+func (dictionaryRequiredCodec) Decompress([]byte, int) ([]byte, error) {
+	return nil, ErrCompressionDictionaryRequired
+}
+
+type s2Codec struct{}
+
+func (s2Codec) Compress(data []byte) ([]byte, bool) {
+	return s2.Encode(nil, data), true
+}
+
+func (s2Codec) Decompress(data []byte, uncompressedSize int) ([]byte, error) {
+	decodedLength, err := s2.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if decodedLength != uncompressedSize {
+		return nil, errDecodedLengthMismatch
+	}
+	return s2.Decode(make([]byte, uncompressedSize), data)
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Compress(data []byte) ([]byte, bool) {
+	return snappy.Encode(nil, data), true
+}
+
+func (snappyCodec) Decompress(data []byte, uncompressedSize int) ([]byte, error) {
+	decodedLength, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if decodedLength != uncompressedSize {
+		return nil, errDecodedLengthMismatch
+	}
+	return snappy.Decode(make([]byte, uncompressedSize), data)
+}
+
+// zstdCodec uses a single encoder and decoder, EncodeAll and DecodeAll are safe for concurrent use.
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCodec() zstdCodec {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		panic(err)
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	if err != nil {
+		panic(err)
+	}
+	return zstdCodec{encoder: encoder, decoder: decoder}
+}
+
+func (codec zstdCodec) Compress(data []byte) ([]byte, bool) {
+	return codec.encoder.EncodeAll(data, nil), true
+}
+
+func (codec zstdCodec) Decompress(data []byte, uncompressedSize int) ([]byte, error) {
+	return codec.decoder.DecodeAll(data, make([]byte, 0, uncompressedSize))
+}
+
+type lz4Codec struct{}
+
+func (lz4Codec) Compress(data []byte) ([]byte, bool) {
+	compressed := make([]byte, lz4.CompressBlockBound(len(data)))
+	size, err := lz4.CompressBlock(data, compressed, nil)
+	if err != nil || size == 0 {
+		return nil, false
+	}
+	return compressed[:size], true
+}
+
+func (lz4Codec) Decompress(data []byte, uncompressedSize int) ([]byte, error) {
+	decompressed := make([]byte, uncompressedSize)
+	size, err := lz4.UncompressBlock(data, decompressed)
+	if err != nil {
+		return nil, err
+	}
+	return decompressed[:size], nil
+}
@@ -0,0 +1,181 @@
+package block
+
+import (
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+	"sync"
+)
+
+// compressionDictionaryId is the zstd dictionary id of all the CompressionDictionary instances.
+// Each persistent sorted segment contains at most one CompressionDictionary, so the id does not need to be unique.
+const compressionDictionaryId uint32 = 32768
+
+// minimumCompressionDictionarySize is the minimum size of the CompressionDictionary, as required by zstd.
+const minimumCompressionDictionarySize = 8
+
+// compressionDictionaryDecoderSizeInBytes is the (estimated) size of the decoder of a CompressionDictionary, which
+// includes the entropy tables of the dictionary and the buffers of a zstd block (at most 128 KiB), the decoder decodes
+// one block at a time.
+const compressionDictionaryDecoderSizeInBytes = 128 * int(kb)
+
+var (
+	ErrCompressionDictionaryRequired = errors.New("compression dictionary required")
+	ErrInvalidCompressionDictionary  = errors.New("invalid compression dictionary")
+)
+
+// CompressionDictionary is a zstd dictionary trained from the sampled values of a persistent sorted segment.
+// Small values (like small JSON documents) compress poorly one block at a time, the dictionary allows zstd to find
+// the repetitions across the blocks.
+// CompressionDictionary is a Codec, and it is recorded as CompressionCodecZstdDictionary in the block Meta.
+// The encoder is only needed while a persistent sorted segment is built, so it is created on the first Compress. The
+// decoder (with concurrency 1) is created when the CompressionDictionary is decoded. Close releases both, and they are
+// created again if the CompressionDictionary is used after Close (for example, by a read which got the
+// CompressionDictionary from a cache just before it was evicted).
+type CompressionDictionary struct {
+	content []byte
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	lock    sync.RWMutex
+}
+
+// TrainCompressionDictionary trains a CompressionDictionary of at most maxSizeInBytes from the given samples.
+// It returns an error if the samples are not sufficient to train a dictionary.
+func TrainCompressionDictionary(samples [][]byte, maxSizeInBytes uint) (*CompressionDictionary, error) {
+	content, err := dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: int(maxSizeInBytes),
+		HashBytes:   6,
+		ZstdDictID:  compressionDictionaryId,
+		ZstdLevel:   zstd.SpeedDefault,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCompressionDictionary, err)
+	}
+	return DecodeToCompressionDictionary(content)
+}
+
+// DecodeToCompressionDictionary decodes the byte slice (encoded using CompressionDictionary.Encode) to CompressionDictionary.
+// It returns ErrInvalidCompressionDictionary if the byte slice is not a valid zstd dictionary.
+func DecodeToCompressionDictionary(buffer []byte) (*CompressionDictionary, error) {
+	if len(buffer) < minimumCompressionDictionarySize {
+		return nil, fmt.Errorf("%w: size %v bytes", ErrInvalidCompressionDictionary, len(buffer))
+	}
+	dictionary := &CompressionDictionary{content: buffer}
+	if err := dictionary.createDecoder(); err != nil {
+		return nil, err
+	}
+	return dictionary, nil
+}
+
+// Encode encodes the CompressionDictionary as byte slice, which is the zstd dictionary.
+func (dictionary *CompressionDictionary) Encode() []byte {
+	return dictionary.content
+}
+
+// SizeInBytes returns the (estimated) size of the CompressionDictionary in memory, which is the size of the zstd
+// dictionary along with the size of its decoder.
+func (dictionary *CompressionDictionary) SizeInBytes() int {
+	return len(dictionary.content) + compressionDictionaryDecoderSizeInBytes
+}
+
+// Compress compresses the given data using the dictionary.
+// It panics if the encoder can not be created, the dictionary is validated when the CompressionDictionary is decoded.
+func (dictionary *CompressionDictionary) Compress(data []byte) ([]byte, bool) {
+	for {
+		dictionary.lock.RLock()
+		if encoder := dictionary.encoder; encoder != nil {
+			defer dictionary.lock.RUnlock()
+			return encoder.EncodeAll(data, nil), true
+		}
+		dictionary.lock.RUnlock()
+		if err := dictionary.createEncoder(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Decompress decompresses the given data using the dictionary.
+func (dictionary *CompressionDictionary) Decompress(data []byte, uncompressedSize int) ([]byte, error) {
+	for {
+		dictionary.lock.RLock()
+		if decoder := dictionary.decoder; decoder != nil {
+			defer dictionary.lock.RUnlock()
+			return decoder.DecodeAll(data, make([]byte, 0, uncompressedSize))
+		}
+		dictionary.lock.RUnlock()
+		if err := dictionary.createDecoder(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close releases the encoder and the decoder of the CompressionDictionary.
+func (dictionary *CompressionDictionary) Close() {
+	dictionary.lock.Lock()
+	defer dictionary.lock.Unlock()
+
+	if dictionary.encoder != nil {
+		_ = dictionary.encoder.Close()
+		dictionary.encoder = nil
+	}
+	if dictionary.decoder != nil {
+		dictionary.decoder.Close()
+		dictionary.decoder = nil
+	}
+}
+
+// createEncoder creates the encoder of the CompressionDictionary, if it does not exist.
+func (dictionary *CompressionDictionary) createEncoder() error {
+	dictionary.lock.Lock()
+	defer dictionary.lock.Unlock()
+
+	if dictionary.encoder != nil {
+		return nil
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dictionary.content), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCompressionDictionary, err)
+	}
+	dictionary.encoder = encoder
+	return nil
+}
+
+// createDecoder creates the decoder of the CompressionDictionary, if it does not exist.
+func (dictionary *CompressionDictionary) createDecoder() error {
+	dictionary.lock.Lock()
+	defer dictionary.lock.Unlock()
+
+	if dictionary.decoder != nil {
+		return nil
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dictionary.content), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCompressionDictionary, err)
+	}
+	dictionary.decoder = decoder
+	return nil
+}
+
+// CompressWithDictionary compresses the given data block (including its overflow bytes) using the given CompressionDictionary.
+// It returns the data block as is along with CompressionCodecNone, if its compressed size is not smaller than its size.
+func CompressWithDictionary(dictionary *CompressionDictionary, data []byte) ([]byte, CompressionCodec) {
+	compressed, _ := dictionary.Compress(data)
+	if len(compressed) >= len(data) {
+		return data, CompressionCodecNone
+	}
+	return compressed, CompressionCodecZstdDictionary
+}
+
+// DecompressWithDictionary decompresses the given data block using the given CompressionCodec.
+// The CompressionDictionary is used if the CompressionCodec is CompressionCodecZstdDictionary, it returns
+// ErrCompressionDictionaryRequired if the dictionary is nil.
+func DecompressWithDictionary(compressionCodec CompressionCodec, data []byte, uncompressedSize int, dictionary *CompressionDictionary) ([]byte, error) {
+	if compressionCodec != CompressionCodecZstdDictionary {
+		return Decompress(compressionCodec, data, uncompressedSize)
+	}
+	if dictionary == nil {
+		return nil, ErrCompressionDictionaryRequired
+	}
+	return decompress(dictionary, data, uncompressedSize)
+}
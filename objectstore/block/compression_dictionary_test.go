@@ -0,0 +1,111 @@
+package block
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testJsonDocuments(from, to int) [][]byte {
+	var documents [][]byte
+	for id := from; id < to; id++ {
+		documents = append(documents, []byte(fmt.Sprintf(
+			`{"id":%d,"name":"user-%d","email":"user%d@zero-store.io","active":true,"roles":["reader","writer"]}`, id, id*7, id),
+		))
+	}
+	return documents
+}
+
+func TestTrainCompressionDictionaryAndCompressWithIt(t *testing.T) {
+	compressionDictionary, err := TrainCompressionDictionary(testJsonDocuments(0, 200), 2048)
+	assert.NoError(t, err)
+	assert.True(t, len(compressionDictionary.Encode()) <= 2048)
+
+	var data []byte
+	for _, document := range testJsonDocuments(1000, 1030) {
+		data = append(data, document...)
+	}
+	compressed, compressionCodec := CompressWithDictionary(compressionDictionary, data)
+	assert.Equal(t, CompressionCodecZstdDictionary, compressionCodec)
+
+	compressedWithoutDictionary, _, err := Compress(CompressionCodecZstd, data)
+	assert.NoError(t, err)
+	assert.True(t, len(compressed) < len(compressedWithoutDictionary))
+
+	decompressed, err := DecompressWithDictionary(compressionCodec, compressed, len(data), compressionDictionary)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestEncodeAndDecodeCompressionDictionary(t *testing.T) {
+	compressionDictionary, err := TrainCompressionDictionary(testJsonDocuments(0, 200), 2048)
+	assert.NoError(t, err)
+
+	decodedCompressionDictionary, err := DecodeToCompressionDictionary(compressionDictionary.Encode())
+	assert.NoError(t, err)
+
+	data := testJsonDocuments(1000, 1001)[0]
+	compressed, compressionCodec := CompressWithDictionary(compressionDictionary, data)
+	assert.Equal(t, CompressionCodecZstdDictionary, compressionCodec)
+
+	decompressed, err := DecompressWithDictionary(compressionCodec, compressed, len(data), decodedCompressionDictionary)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestDecodeAnInvalidCompressionDictionary(t *testing.T) {
+	_, err := DecodeToCompressionDictionary([]byte("raft"))
+	assert.ErrorIs(t, err, ErrInvalidCompressionDictionary)
+
+	_, err = DecodeToCompressionDictionary([]byte("raft is a consensus algorithm"))
+	assert.ErrorIs(t, err, ErrInvalidCompressionDictionary)
+}
+
+func TestDecompressWithDictionaryWithoutTheDictionary(t *testing.T) {
+	_, err := DecompressWithDictionary(CompressionCodecZstdDictionary, []byte("raft"), 10, nil)
+	assert.ErrorIs(t, err, ErrCompressionDictionaryRequired)
+
+	_, err = Decompress(CompressionCodecZstdDictionary, []byte("raft"), 10)
+	assert.ErrorIs(t, err, ErrCompressionDictionaryRequired)
+}
+
+func TestDecompressWithDictionaryForOtherCodecs(t *testing.T) {
+	data := []byte("raft raft raft raft raft raft raft raft raft raft raft raft")
+	compressed, compressionCodec, err := Compress(CompressionCodecS2, data)
+	assert.NoError(t, err)
+
+	decompressed, err := DecompressWithDictionary(compressionCodec, compressed, len(data), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestDecodeToCompressionDictionaryDoesNotCreateTheEncoder(t *testing.T) {
+	compressionDictionary, err := TrainCompressionDictionary(testJsonDocuments(0, 200), 2048)
+	assert.NoError(t, err)
+
+	decodedCompressionDictionary, err := DecodeToCompressionDictionary(compressionDictionary.Encode())
+	assert.NoError(t, err)
+	assert.Nil(t, decodedCompressionDictionary.encoder)
+	assert.NotNil(t, decodedCompressionDictionary.decoder)
+	assert.Equal(t, len(compressionDictionary.Encode())+compressionDictionaryDecoderSizeInBytes, decodedCompressionDictionary.SizeInBytes())
+}
+
+func TestCompressionDictionaryAfterClose(t *testing.T) {
+	compressionDictionary, err := TrainCompressionDictionary(testJsonDocuments(0, 200), 2048)
+	assert.NoError(t, err)
+
+	var data []byte
+	for _, document := range testJsonDocuments(1000, 1030) {
+		data = append(data, document...)
+	}
+	compressed, compressionCodec := CompressWithDictionary(compressionDictionary, data)
+	assert.Equal(t, CompressionCodecZstdDictionary, compressionCodec)
+
+	compressionDictionary.Close()
+	assert.Nil(t, compressionDictionary.encoder)
+	assert.Nil(t, compressionDictionary.decoder)
+
+	decompressed, err := DecompressWithDictionary(compressionCodec, compressed, len(data), compressionDictionary)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
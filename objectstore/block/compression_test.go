@@ -0,0 +1,99 @@
+package block
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompressAndDecompressWithEachCodec(t *testing.T) {
+	data := bytes.Repeat([]byte("zero disk architecture "), 100)
+	for _, compressionCodec := range []CompressionCodec{
+		CompressionCodecS2,
+		CompressionCodecSnappy,
+		CompressionCodecZstd,
+		CompressionCodecLz4,
+	} {
+		compressed, usedCompressionCodec, err := Compress(compressionCodec, data)
+		assert.NoError(t, err)
+		assert.Equal(t, compressionCodec, usedCompressionCodec)
+		assert.True(t, len(compressed) < len(data))
+
+		decompressed, err := Decompress(usedCompressionCodec, compressed, len(data))
+		assert.NoError(t, err)
+		assert.Equal(t, data, decompressed)
+	}
+}
+
+func TestCompressWithNoneCodec(t *testing.T) {
+	data := bytes.Repeat([]byte("zero disk architecture "), 100)
+	compressed, usedCompressionCodec, err := Compress(CompressionCodecNone, data)
+	assert.NoError(t, err)
+	assert.Equal(t, CompressionCodecNone, usedCompressionCodec)
+	assert.Equal(t, data, compressed)
+}
+
+func TestCompressDataWhichIsNotCompressible(t *testing.T) {
+	data := []byte("raft")
+	compressed, usedCompressionCodec, err := Compress(CompressionCodecZstd, data)
+	assert.NoError(t, err)
+	assert.Equal(t, CompressionCodecNone, usedCompressionCodec)
+	assert.Equal(t, data, compressed)
+}
+
+func TestCompressWithAnUnknownCodec(t *testing.T) {
+	_, _, err := Compress(CompressionCodec(250), []byte("raft"))
+	assert.ErrorIs(t, err, ErrUnknownCompressionCodec)
+}
+
+func TestDecompressWithAnUnexpectedUncompressedSize(t *testing.T) {
+	data := bytes.Repeat([]byte("zero disk architecture "), 100)
+	for _, compressionCodec := range []CompressionCodec{
+		CompressionCodecS2,
+		CompressionCodecSnappy,
+		CompressionCodecZstd,
+		CompressionCodecLz4,
+	} {
+		compressed, _, err := Compress(compressionCodec, data)
+		assert.NoError(t, err)
+
+		_, err = Decompress(compressionCodec, compressed, len(data)-1)
+		assert.ErrorIs(t, err, ErrDecompression)
+	}
+}
+
+func TestDecompressCorruptedData(t *testing.T) {
+	for _, compressionCodec := range []CompressionCodec{
+		CompressionCodecS2,
+		CompressionCodecSnappy,
+		CompressionCodecZstd,
+		CompressionCodecLz4,
+	} {
+		_, err := Decompress(compressionCodec, []byte{0xff, 0xff, 0xff, 0xff, 0xff}, 100)
+		assert.ErrorIs(t, err, ErrDecompression)
+	}
+}
+
+type identityCodec struct{}
+
+func (identityCodec) Compress(data []byte) ([]byte, bool) {
+	return data, true
+}
+
+func (identityCodec) Decompress(data []byte, _ int) ([]byte, error) {
+	return data, nil
+}
+
+func TestRegisterACodec(t *testing.T) {
+	RegisterCodec(CompressionCodec(200), identityCodec{})
+
+	codec, err := CodecFor(CompressionCodec(200))
+	assert.NoError(t, err)
+	assert.Equal(t, identityCodec{}, codec)
+}
+
+func TestAttemptToRegisterACodecWithAnExistingIdentifier(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterCodec(CompressionCodecS2, identityCodec{})
+	})
+}
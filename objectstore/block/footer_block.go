@@ -2,12 +2,29 @@ package block
 
 import (
 	"encoding/binary"
+	"errors"
+	"math"
 )
 
+// FooterBlockSizeLength is the number of bytes at the end of the FooterBlock which contain the block size.
+var FooterBlockSizeLength = Uint32Size
+
+var ErrInvalidFooterBlock = errors.New("invalid footer block")
+
 // FooterBlock is the footer block of the persistent sorted segment.
+// Along with the offsets, FooterBlock records the format parameters (block size, bloom filter false positive rate and
+// compression) that were used to write the persistent sorted segment, so that the readers never have to guess them.
+// Persistent sorted segments of block.FormatVersion1 and block.FormatVersion2 contain a compact FooterBlock (please check
+// EncodeCompact), and persistent sorted segments of block.FormatVersionLegacy contain a FooterBlock of block size (please
+// check Encode).
+// Footer blocks written before the format parameters were recorded, do not contain the format parameters
+// (HasFormatParameters returns false).
 type FooterBlock struct {
-	blockSize uint
-	offsets   []uint32
+	blockSize                    uint
+	offsets                      []uint64
+	bloomFilterFalsePositiveRate float64
+	enableCompression            bool
+	hasFormatParameters          bool
 }
 
 // NewFooterBlock creates a new footer block.
@@ -26,10 +43,17 @@ func NewFooterBlock(blockSize uint) *FooterBlock {
 // - end-offset of the bloom filter
 // This method does not check if the footer block has sufficient space to contain the given offset.
 // At this stage, the block does not contain too much information, so this check is left out.
-func (footerBlock *FooterBlock) AddOffset(offset uint32) {
+func (footerBlock *FooterBlock) AddOffset(offset uint64) {
 	footerBlock.offsets = append(footerBlock.offsets, offset)
 }
 
+// SetFormatParameters sets the format parameters of the persistent sorted segment.
+func (footerBlock *FooterBlock) SetFormatParameters(bloomFilterFalsePositiveRate float64, enableCompression bool) {
+	footerBlock.bloomFilterFalsePositiveRate = bloomFilterFalsePositiveRate
+	footerBlock.enableCompression = enableCompression
+	footerBlock.hasFormatParameters = true
+}
+
 // GetOffsetAsInt64At returns the offset at the given index.
 // If the index is beyond the total available indices for offsets, 0, false is returned
 func (footerBlock *FooterBlock) GetOffsetAsInt64At(index uint) (int64, bool) {
@@ -42,45 +66,186 @@ func (footerBlock *FooterBlock) GetOffsetAsInt64At(index uint) (int64, bool) {
 
 // GetOffsetAt returns the offset at the given index.
 // If the index is beyond the total available indices for offsets, 0, false is returned
-func (footerBlock *FooterBlock) GetOffsetAt(index uint) (uint32, bool) {
+func (footerBlock *FooterBlock) GetOffsetAt(index uint) (uint64, bool) {
 	if index >= uint(len(footerBlock.offsets)) {
 		return 0, false
 	}
 	return footerBlock.offsets[index], true
 }
 
+// HasFormatParameters returns true if the FooterBlock contains the format parameters.
+func (footerBlock *FooterBlock) HasFormatParameters() bool {
+	return footerBlock.hasFormatParameters
+}
+
+// BlockSize returns the block size of the persistent sorted segment.
+func (footerBlock *FooterBlock) BlockSize() uint {
+	return footerBlock.blockSize
+}
+
+// BloomFilterFalsePositiveRate returns the false positive rate of the bloom filter of the persistent sorted segment.
+func (footerBlock *FooterBlock) BloomFilterFalsePositiveRate() float64 {
+	return footerBlock.bloomFilterFalsePositiveRate
+}
+
+// IsCompressionEnabled returns true if the block meta list of the persistent sorted segment is compressed.
+func (footerBlock *FooterBlock) IsCompressionEnabled() bool {
+	return footerBlock.enableCompression
+}
+
 // Encode encodes the FooterBlock as byte slice.
 // Encoding includes:
 /*
-  -----------------------------------------------------------
- | 2 bytes for the number of offsets | 4 bytes for an offset |
-  -----------------------------------------------------------
+  --------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+ | 2 bytes for the number of offsets | 4 bytes for an offset | 1 byte for compression | 8 bytes for bloom filter false positive rate | padding | 4 bytes for the block size |
+  --------------------------------------------------------------------------------------------------------------------------------------------------------------------------
                                     <----for each offset---->
 */
+// The format parameters are encoded only if they are set. The block size is always the last 4 bytes of the FooterBlock,
+// which allows the readers to locate the FooterBlock without knowing the block size.
+// The footer blocks encoded before the format parameters were recorded, contain zero in the last 4 bytes.
 func (footerBlock *FooterBlock) Encode() []byte {
 	buffer := make([]byte, footerBlock.blockSize)
 	binary.LittleEndian.PutUint16(buffer[:], uint16(len(footerBlock.offsets)))
 
 	index := Uint16Size
 	for _, offset := range footerBlock.offsets {
-		binary.LittleEndian.PutUint32(buffer[index:], offset)
+		binary.LittleEndian.PutUint32(buffer[index:], uint32(offset))
 		index += Uint32Size
 	}
+	if footerBlock.hasFormatParameters {
+		if footerBlock.enableCompression {
+			buffer[index] = 1
+		}
+		index += 1
+		binary.LittleEndian.PutUint64(buffer[index:], math.Float64bits(footerBlock.bloomFilterFalsePositiveRate))
+		binary.LittleEndian.PutUint32(buffer[len(buffer)-FooterBlockSizeLength:], uint32(footerBlock.blockSize))
+	}
+	return buffer
+}
+
+// EncodeCompact encodes the FooterBlock as a variable length byte slice of block.CurrentFormatVersion.
+// Encoding includes:
+/*
+  ----------------------------------------------------------------------------------------------------------------------------------
+ | 2 bytes for the number of offsets | 8 bytes for an offset | 4 bytes for the block size | 8 bytes for bloom filter false positive rate |
+  ----------------------------------------------------------------------------------------------------------------------------------
+                                    <----for each offset---->
+*/
+// The compact FooterBlock of block.FormatVersion1 uses 4 bytes for an offset.
+// Compression is not a part of the compact encoding, it is a flag in the Trailer.
+func (footerBlock *FooterBlock) EncodeCompact() []byte {
+	return footerBlock.encodeCompactOfFormatVersion(CurrentFormatVersion)
+}
+
+// encodeCompactOfFormatVersion encodes the FooterBlock as a variable length byte slice of the given format version.
+func (footerBlock *FooterBlock) encodeCompactOfFormatVersion(formatVersion uint16) []byte {
+	offsetSize := compactFooterBlockOffsetSize(formatVersion)
+	buffer := make([]byte, Uint16Size+len(footerBlock.offsets)*offsetSize+Uint32Size+Uint64Size)
+	binary.LittleEndian.PutUint16(buffer[:], uint16(len(footerBlock.offsets)))
+
+	index := Uint16Size
+	for _, offset := range footerBlock.offsets {
+		if offsetSize == Uint64Size {
+			binary.LittleEndian.PutUint64(buffer[index:], offset)
+		} else {
+			binary.LittleEndian.PutUint32(buffer[index:], uint32(offset))
+		}
+		index += offsetSize
+	}
+	binary.LittleEndian.PutUint32(buffer[index:], uint32(footerBlock.blockSize))
+	binary.LittleEndian.PutUint64(buffer[index+Uint32Size:], math.Float64bits(footerBlock.bloomFilterFalsePositiveRate))
 	return buffer
 }
 
+// DecodeToCompactFooterBlock decodes the byte slice encoded using EncodeCompact and returns an instance of FooterBlock.
+// enableCompression is read from the flags of the Trailer.
+func DecodeToCompactFooterBlock(buffer []byte, enableCompression bool) (*FooterBlock, error) {
+	return DecodeToCompactFooterBlockOfFormatVersion(buffer, enableCompression, CurrentFormatVersion)
+}
+
+// DecodeToCompactFooterBlockOfFormatVersion decodes the byte slice containing the compact FooterBlock of the given format
+// version and returns an instance of FooterBlock.
+// It returns ErrInvalidFooterBlock if the byte slice is not a valid encoding of the FooterBlock.
+func DecodeToCompactFooterBlockOfFormatVersion(buffer []byte, enableCompression bool, formatVersion uint16) (*FooterBlock, error) {
+	if len(buffer) < Uint16Size {
+		return nil, ErrInvalidFooterBlock
+	}
+	offsetSize := compactFooterBlockOffsetSize(formatVersion)
+	numberOfOffsets := int(binary.LittleEndian.Uint16(buffer[:]))
+	if len(buffer) != Uint16Size+numberOfOffsets*offsetSize+Uint32Size+Uint64Size {
+		return nil, ErrInvalidFooterBlock
+	}
+	offsets := make([]uint64, 0, numberOfOffsets)
+
+	indexInBuffer := Uint16Size
+	for offsetIndex := 0; offsetIndex < numberOfOffsets; offsetIndex++ {
+		if offsetSize == Uint64Size {
+			offsets = append(offsets, binary.LittleEndian.Uint64(buffer[indexInBuffer:]))
+		} else {
+			offsets = append(offsets, uint64(binary.LittleEndian.Uint32(buffer[indexInBuffer:])))
+		}
+		indexInBuffer += offsetSize
+	}
+	footerBlock := &FooterBlock{
+		offsets:   offsets,
+		blockSize: uint(binary.LittleEndian.Uint32(buffer[indexInBuffer:])),
+	}
+	footerBlock.SetFormatParameters(
+		math.Float64frombits(binary.LittleEndian.Uint64(buffer[indexInBuffer+Uint32Size:])),
+		enableCompression,
+	)
+	return footerBlock, nil
+}
+
+// DecodeFooterBlockSize decodes the block size from the last FooterBlockSizeLength bytes of the persistent sorted segment.
+// It returns 0, if the FooterBlock does not contain the format parameters.
+// It returns ErrInvalidFooterBlock if the byte slice is smaller than FooterBlockSizeLength.
+func DecodeFooterBlockSize(buffer []byte) (uint, error) {
+	if len(buffer) < FooterBlockSizeLength {
+		return 0, ErrInvalidFooterBlock
+	}
+	return uint(binary.LittleEndian.Uint32(buffer[len(buffer)-FooterBlockSizeLength:])), nil
+}
+
 // DecodeToFooterBlock decodes the byte slice and returns an instance of FooterBlock.
-func DecodeToFooterBlock(buffer []byte, blockSize uint) *FooterBlock {
-	numberOfOffsets := binary.LittleEndian.Uint16(buffer[:])
-	offsets := make([]uint32, 0, numberOfOffsets)
+// It returns ErrInvalidFooterBlock if the byte slice is not a valid encoding of the FooterBlock.
+func DecodeToFooterBlock(buffer []byte, blockSize uint) (*FooterBlock, error) {
+	if len(buffer) < Uint16Size+FooterBlockSizeLength {
+		return nil, ErrInvalidFooterBlock
+	}
+	numberOfOffsets := int(binary.LittleEndian.Uint16(buffer[:]))
+	if Uint16Size+numberOfOffsets*Uint32Size > len(buffer) {
+		return nil, ErrInvalidFooterBlock
+	}
+	offsets := make([]uint64, 0, numberOfOffsets)
 
 	indexInBuffer := Uint16Size
-	for offsetIndex := 0; offsetIndex < int(numberOfOffsets); offsetIndex++ {
-		offsets = append(offsets, binary.LittleEndian.Uint32(buffer[indexInBuffer:]))
+	for offsetIndex := 0; offsetIndex < numberOfOffsets; offsetIndex++ {
+		offsets = append(offsets, uint64(binary.LittleEndian.Uint32(buffer[indexInBuffer:])))
 		indexInBuffer += Uint32Size
 	}
-	return &FooterBlock{
+	footerBlock := &FooterBlock{
 		offsets:   offsets,
 		blockSize: blockSize,
 	}
+	encodedBlockSize, _ := DecodeFooterBlockSize(buffer)
+	if encodedBlockSize == blockSize {
+		if indexInBuffer+1+Uint64Size > len(buffer)-FooterBlockSizeLength {
+			return nil, ErrInvalidFooterBlock
+		}
+		footerBlock.SetFormatParameters(
+			math.Float64frombits(binary.LittleEndian.Uint64(buffer[indexInBuffer+1:])),
+			buffer[indexInBuffer] == 1,
+		)
+	}
+	return footerBlock, nil
+}
+
+// compactFooterBlockOffsetSize returns the size of an offset in the compact FooterBlock of the given format version.
+func compactFooterBlockOffsetSize(formatVersion uint16) int {
+	if formatVersion >= FormatVersion2 {
+		return Uint64Size
+	}
+	return Uint32Size
 }
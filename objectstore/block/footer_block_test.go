@@ -10,9 +10,10 @@ func TestEncodeAndDecodeAFooterBlockWithSingleOffset(t *testing.T) {
 	footerBlock.AddOffset(18)
 
 	encoded := footerBlock.Encode()
-	decodedFooterBlock := DecodeToFooterBlock(encoded, DefaultBlockSize)
+	decodedFooterBlock, err := DecodeToFooterBlock(encoded, DefaultBlockSize)
+	assert.NoError(t, err)
 
-	assert.Equal(t, uint32(18), decodedFooterBlock.offsets[0])
+	assert.Equal(t, uint64(18), decodedFooterBlock.offsets[0])
 }
 
 func TestEncodeAndDecodeAFooterBlockWithAFewOffsets(t *testing.T) {
@@ -22,11 +23,12 @@ func TestEncodeAndDecodeAFooterBlockWithAFewOffsets(t *testing.T) {
 	footerBlock.AddOffset(580)
 
 	encoded := footerBlock.Encode()
-	decodedFooterBlock := DecodeToFooterBlock(encoded, DefaultBlockSize)
+	decodedFooterBlock, err := DecodeToFooterBlock(encoded, DefaultBlockSize)
+	assert.NoError(t, err)
 
-	assert.Equal(t, uint32(18), decodedFooterBlock.offsets[0])
-	assert.Equal(t, uint32(240), decodedFooterBlock.offsets[1])
-	assert.Equal(t, uint32(580), decodedFooterBlock.offsets[2])
+	assert.Equal(t, uint64(18), decodedFooterBlock.offsets[0])
+	assert.Equal(t, uint64(240), decodedFooterBlock.offsets[1])
+	assert.Equal(t, uint64(580), decodedFooterBlock.offsets[2])
 }
 
 func TestGetOffsetAsInt64AtValidIndex(t *testing.T) {
@@ -53,7 +55,7 @@ func TestGetOffsetAtValidIndex(t *testing.T) {
 
 	offset, ok := footerBlock.GetOffsetAt(0)
 	assert.True(t, ok)
-	assert.Equal(t, uint32(18), offset)
+	assert.Equal(t, uint64(18), offset)
 }
 
 func TestGetOffsetAtAnInvalidIndex(t *testing.T) {
@@ -62,5 +64,143 @@ func TestGetOffsetAtAnInvalidIndex(t *testing.T) {
 
 	offset, ok := footerBlock.GetOffsetAt(90)
 	assert.False(t, ok)
-	assert.Equal(t, uint32(0), offset)
+	assert.Equal(t, uint64(0), offset)
+}
+
+func TestEncodeAndDecodeAFooterBlockWithFormatParameters(t *testing.T) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(18)
+	footerBlock.SetFormatParameters(0.001, true)
+
+	encoded := footerBlock.Encode()
+	blockSize, err := DecodeFooterBlockSize(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultBlockSize, blockSize)
+
+	decodedFooterBlock, err := DecodeToFooterBlock(encoded, blockSize)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(18), decodedFooterBlock.offsets[0])
+	assert.True(t, decodedFooterBlock.HasFormatParameters())
+	assert.Equal(t, DefaultBlockSize, decodedFooterBlock.BlockSize())
+	assert.Equal(t, 0.001, decodedFooterBlock.BloomFilterFalsePositiveRate())
+	assert.True(t, decodedFooterBlock.IsCompressionEnabled())
+}
+
+func TestEncodeAndDecodeAFooterBlockWithoutFormatParameters(t *testing.T) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(18)
+
+	encoded := footerBlock.Encode()
+	blockSize, err := DecodeFooterBlockSize(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), blockSize)
+
+	decodedFooterBlock, err := DecodeToFooterBlock(encoded, DefaultBlockSize)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(18), decodedFooterBlock.offsets[0])
+	assert.False(t, decodedFooterBlock.HasFormatParameters())
+}
+
+func TestEncodeAndDecodeACompactFooterBlock(t *testing.T) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(18)
+	footerBlock.AddOffset(240)
+	footerBlock.SetFormatParameters(0.001, true)
+
+	decodedFooterBlock, err := DecodeToCompactFooterBlock(footerBlock.EncodeCompact(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{18, 240}, decodedFooterBlock.offsets)
+	assert.Equal(t, DefaultBlockSize, decodedFooterBlock.BlockSize())
+	assert.Equal(t, 0.001, decodedFooterBlock.BloomFilterFalsePositiveRate())
+	assert.True(t, decodedFooterBlock.IsCompressionEnabled())
+}
+
+func TestEncodeAndDecodeACompactFooterBlockWithAnOffsetBeyond4GiB(t *testing.T) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(5 << 30)
+	footerBlock.AddOffset(6 << 30)
+	footerBlock.SetFormatParameters(0.001, false)
+
+	decodedFooterBlock, err := DecodeToCompactFooterBlock(footerBlock.EncodeCompact(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{5 << 30, 6 << 30}, decodedFooterBlock.offsets)
+}
+
+func TestEncodeAndDecodeACompactFooterBlockOfFormatVersion1(t *testing.T) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(18)
+	footerBlock.AddOffset(240)
+	footerBlock.SetFormatParameters(0.001, true)
+
+	encoded := footerBlock.encodeCompactOfFormatVersion(FormatVersion1)
+	assert.Equal(t, Uint16Size+2*Uint32Size+Uint32Size+Uint64Size, len(encoded))
+
+	decodedFooterBlock, err := DecodeToCompactFooterBlockOfFormatVersion(encoded, true, FormatVersion1)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{18, 240}, decodedFooterBlock.offsets)
+	assert.Equal(t, DefaultBlockSize, decodedFooterBlock.BlockSize())
+	assert.Equal(t, 0.001, decodedFooterBlock.BloomFilterFalsePositiveRate())
+
+	_, err = DecodeToCompactFooterBlock(encoded, true)
+	assert.ErrorIs(t, err, ErrInvalidFooterBlock)
+}
+
+func TestAttemptToDecodeACompactFooterBlockFromAnInvalidBuffer(t *testing.T) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(18)
+
+	encoded := footerBlock.EncodeCompact()
+	_, err := DecodeToCompactFooterBlock(encoded[:len(encoded)-1], false)
+	assert.ErrorIs(t, err, ErrInvalidFooterBlock)
+}
+
+func TestAttemptToDecodeAFooterBlockWithOffsetsBeyondTheBuffer(t *testing.T) {
+	footerBlock := NewFooterBlock(64)
+	footerBlock.AddOffset(18)
+
+	encoded := footerBlock.Encode()
+	encoded[0] = 0xFF
+
+	_, err := DecodeToFooterBlock(encoded, 64)
+	assert.ErrorIs(t, err, ErrInvalidFooterBlock)
+}
+
+func TestAttemptToDecodeTheFooterBlockSizeFromAShortBuffer(t *testing.T) {
+	_, err := DecodeFooterBlockSize([]byte{1})
+	assert.ErrorIs(t, err, ErrInvalidFooterBlock)
+}
+
+func FuzzDecodeToFooterBlock(f *testing.F) {
+	footerBlock := NewFooterBlock(64)
+	footerBlock.AddOffset(18)
+	footerBlock.AddOffset(240)
+	f.Add(footerBlock.Encode())
+	footerBlock.SetFormatParameters(0.01, true)
+	f.Add(footerBlock.Encode())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		blockSize, err := DecodeFooterBlockSize(buffer)
+		if err != nil {
+			return
+		}
+		_, _ = DecodeToFooterBlock(buffer, blockSize)
+	})
+}
+
+func FuzzDecodeToCompactFooterBlock(f *testing.F) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(18)
+	footerBlock.AddOffset(240)
+	footerBlock.SetFormatParameters(0.01, false)
+	f.Add(footerBlock.EncodeCompact())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		decodedFooterBlock, err := DecodeToCompactFooterBlock(buffer, false)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, buffer, decodedFooterBlock.EncodeCompact())
+	})
 }
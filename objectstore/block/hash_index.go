@@ -0,0 +1,132 @@
+package block
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// hashIndexUtilizationRatio is the ratio of the number of distinct raw keys to the number of buckets in the hashIndex.
+const hashIndexUtilizationRatio = 0.75
+
+const (
+	// hashIndexNoEntry denotes a bucket which does not contain any raw key.
+	hashIndexNoEntry uint8 = 255
+	// hashIndexCollision denotes a bucket which contains raw keys belonging to different restart points.
+	hashIndexCollision uint8 = 254
+	// maxRestartPointsForHashIndex is the maximum number of restart points of a block which can be indexed, a bucket
+	// stores the index of a restart point as uint8.
+	maxRestartPointsForHashIndex = int(hashIndexCollision)
+)
+
+// hashIndex maps the hash of a raw key to the index of the restart point (in Block.restartPoints) which begins the restart
+// interval containing the first key/value pair of the raw key. Each byte of the hashIndex is a bucket.
+// It allows Block.SeekToKey to skip the binary search over the restart points for the raw keys that are present in the block.
+/*
+// hash index encoding (in a block) looks like the following:
+  -----------------------------------------------------------------------------------------------------------
+ | Restart points | bucket | bucket | .... | bucket | Number of buckets (offset size) | Start of offsets | Number of restart points (with hashIndexFlag) |
+  -----------------------------------------------------------------------------------------------------------
+*/
+type hashIndex []byte
+
+// hashIndexBuilder collects the hash of each distinct raw key along with the index of its restart point.
+type hashIndexBuilder struct {
+	rawKeyHashes        []uint32
+	restartPointIndexes []int
+}
+
+// add adds the hash of the given raw key with the given restart point index.
+func (builder *hashIndexBuilder) add(rawKey []byte, restartPointIndex int) {
+	builder.rawKeyHashes = append(builder.rawKeyHashes, hashOf(rawKey))
+	builder.restartPointIndexes = append(builder.restartPointIndexes, restartPointIndex)
+}
+
+// numberOfRawKeys returns the number of distinct raw keys added to the builder.
+func (builder *hashIndexBuilder) numberOfRawKeys() int {
+	return len(builder.rawKeyHashes)
+}
+
+// build creates the hashIndex.
+// It returns false if the block contains more than maxRestartPointsForHashIndex restart points.
+func (builder *hashIndexBuilder) build(numberOfRestartPoints int) (hashIndex, bool) {
+	if numberOfRestartPoints > maxRestartPointsForHashIndex || builder.numberOfRawKeys() == 0 {
+		return nil, false
+	}
+	buckets := make(hashIndex, numberOfHashIndexBuckets(builder.numberOfRawKeys()))
+	for index := range buckets {
+		buckets[index] = hashIndexNoEntry
+	}
+	for index, rawKeyHash := range builder.rawKeyHashes {
+		bucketIndex := rawKeyHash % uint32(len(buckets))
+		restartPointIndex := uint8(builder.restartPointIndexes[index])
+		switch buckets[bucketIndex] {
+		case hashIndexNoEntry:
+			buckets[bucketIndex] = restartPointIndex
+		case restartPointIndex:
+		default:
+			buckets[bucketIndex] = hashIndexCollision
+		}
+	}
+	return buckets, true
+}
+
+// decodeToHashIndex decodes the hashIndex which ends at endOffset in the given block data of the given layout.
+// It returns the hashIndex and its begin offset, it returns ErrInvalidBlock if any bucket refers to a restart point beyond
+// numberOfRestartPoints.
+func decodeToHashIndex(data []byte, endOffset int, numberOfRestartPoints int, layout layout) (hashIndex, int, error) {
+	offsetSize := layout.offsetSize()
+	if endOffset < offsetSize {
+		return nil, 0, fmt.Errorf("%w: hash index beyond the block", ErrInvalidBlock)
+	}
+	numberOfBuckets := int(layout.offsetAt(data[endOffset-offsetSize:]))
+	beginOffset := endOffset - offsetSize - numberOfBuckets
+	if numberOfBuckets == 0 || beginOffset < 0 {
+		return nil, 0, fmt.Errorf("%w: invalid number of hash index buckets %v", ErrInvalidBlock, numberOfBuckets)
+	}
+	buckets := hashIndex(data[beginOffset : endOffset-offsetSize])
+	for _, bucket := range buckets {
+		if bucket != hashIndexNoEntry && bucket != hashIndexCollision && int(bucket) >= numberOfRestartPoints {
+			return nil, 0, fmt.Errorf("%w: hash index bucket refers to restart point %v", ErrInvalidBlock, bucket)
+		}
+	}
+	return buckets, beginOffset, nil
+}
+
+// restartPointIndexOf returns the index of the restart point which may contain the first key/value pair of the given raw key.
+// It returns false if the hashIndex is empty, or the raw key is not present in the block, or its bucket has a collision.
+func (index hashIndex) restartPointIndexOf(rawKey []byte) (int, bool) {
+	if len(index) == 0 {
+		return 0, false
+	}
+	bucket := index[hashOf(rawKey)%uint32(len(index))]
+	if bucket == hashIndexNoEntry || bucket == hashIndexCollision {
+		return 0, false
+	}
+	return int(bucket), true
+}
+
+// encodeTo encodes the hashIndex (along with the number of buckets) to the given buffer of the given layout, such that it
+// ends at endOffset.
+func (index hashIndex) encodeTo(buffer []byte, endOffset int, layout layout) {
+	offsetSize := layout.offsetSize()
+	layout.putOffset(buffer[endOffset-offsetSize:], uint32(len(index)))
+	copy(buffer[endOffset-offsetSize-len(index):], index)
+}
+
+// hashIndexSizeInBytes returns the size of the hashIndex (along with the number of buckets) for the given number of raw keys
+// in a block of the given layout.
+func hashIndexSizeInBytes(numberOfRawKeys int, layout layout) int {
+	return numberOfHashIndexBuckets(numberOfRawKeys) + layout.offsetSize()
+}
+
+// numberOfHashIndexBuckets returns the (odd) number of buckets for the given number of raw keys.
+func numberOfHashIndexBuckets(numberOfRawKeys int) int {
+	return int(float64(numberOfRawKeys)/hashIndexUtilizationRatio) | 1
+}
+
+// hashOf returns the 32-bit FNV-1a hash of the given raw key. The hash is a part of the persistent format.
+func hashOf(rawKey []byte) uint32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write(rawKey)
+	return hash.Sum32()
+}
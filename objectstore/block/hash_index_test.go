@@ -0,0 +1,110 @@
+package block
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildHashIndexAndGetTheRestartPointIndexOfRawKeys(t *testing.T) {
+	builder := &hashIndexBuilder{}
+	for count := 0; count < 20; count++ {
+		builder.add([]byte(fmt.Sprintf("tenant/key-%02d", count)), count/4)
+	}
+
+	index, ok := builder.build(5)
+	assert.True(t, ok)
+	assert.Equal(t, numberOfHashIndexBuckets(20), len(index))
+
+	for count := 0; count < 20; count++ {
+		restartPointIndex, ok := index.restartPointIndexOf([]byte(fmt.Sprintf("tenant/key-%02d", count)))
+		if ok {
+			assert.Equal(t, count/4, restartPointIndex)
+		}
+	}
+}
+
+func TestHashIndexWithACollision(t *testing.T) {
+	numberOfBuckets := uint32(numberOfHashIndexBuckets(2))
+	collidingRawKey := ""
+	for count := 0; collidingRawKey == ""; count++ {
+		rawKey := fmt.Sprintf("distributed-%d", count)
+		if hashOf([]byte(rawKey))%numberOfBuckets == hashOf([]byte("consensus"))%numberOfBuckets {
+			collidingRawKey = rawKey
+		}
+	}
+
+	builder := &hashIndexBuilder{}
+	builder.add([]byte("consensus"), 0)
+	builder.add([]byte(collidingRawKey), 1)
+
+	index, ok := builder.build(2)
+	assert.True(t, ok)
+	assert.Equal(t, hashIndexCollision, index[hashOf([]byte("consensus"))%numberOfBuckets])
+
+	_, ok = index.restartPointIndexOf([]byte("consensus"))
+	assert.False(t, ok)
+	_, ok = index.restartPointIndexOf([]byte(collidingRawKey))
+	assert.False(t, ok)
+}
+
+func TestHashIndexWithoutTheRawKey(t *testing.T) {
+	builder := &hashIndexBuilder{}
+	builder.add([]byte("consensus"), 0)
+
+	index, ok := builder.build(1)
+	assert.True(t, ok)
+
+	restartPointIndex, ok := index.restartPointIndexOf([]byte("consensus"))
+	assert.True(t, ok)
+	assert.Equal(t, 0, restartPointIndex)
+
+	for _, rawKey := range []string{"raft", "paxos", "etcd", "bolt"} {
+		if hashOf([]byte(rawKey))%uint32(len(index)) != hashOf([]byte("consensus"))%uint32(len(index)) {
+			_, ok = index.restartPointIndexOf([]byte(rawKey))
+			assert.False(t, ok)
+		}
+	}
+}
+
+func TestAttemptToBuildHashIndexWithTooManyRestartPoints(t *testing.T) {
+	builder := &hashIndexBuilder{}
+	builder.add([]byte("consensus"), 0)
+
+	_, ok := builder.build(maxRestartPointsForHashIndex + 1)
+	assert.False(t, ok)
+}
+
+func TestEncodeAndDecodeHashIndex(t *testing.T) {
+	builder := &hashIndexBuilder{}
+	builder.add([]byte("consensus"), 0)
+	builder.add([]byte("distributed"), 1)
+	builder.add([]byte("raft"), 1)
+
+	index, ok := builder.build(2)
+	assert.True(t, ok)
+
+	for _, formatVersion := range []uint16{FormatVersion1, FormatVersion2} {
+		buffer := make([]byte, 32)
+		index.encodeTo(buffer, len(buffer), layoutOf(formatVersion))
+
+		decodedIndex, beginOffset, err := decodeToHashIndex(buffer, len(buffer), 2, layoutOf(formatVersion))
+		assert.NoError(t, err)
+		assert.Equal(t, index, decodedIndex)
+		assert.Equal(t, len(buffer)-hashIndexSizeInBytes(3, layoutOf(formatVersion)), beginOffset)
+	}
+}
+
+func TestAttemptToDecodeHashIndexWithABucketReferringToAnUnknownRestartPoint(t *testing.T) {
+	builder := &hashIndexBuilder{}
+	builder.add([]byte("consensus"), 3)
+
+	index, ok := builder.build(4)
+	assert.True(t, ok)
+
+	buffer := make([]byte, 16)
+	index.encodeTo(buffer, len(buffer), layoutOf(CurrentFormatVersion))
+
+	_, _, err := decodeToHashIndex(buffer, len(buffer), 2, layoutOf(CurrentFormatVersion))
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+}
@@ -1,16 +1,20 @@
 package block
 
 import (
-	"encoding/binary"
 	"github.com/SarthakMakhija/zero-store/kv"
 )
 
 // Iterator represents the block iterator.
+// nextOffset is the begin-offset of the key/value pair after the current one, and nextRestartPointIndex is the index
+// (in iterator.block.restartPoints) of the next restart point. encodedKey is the encoded form of the current key, the key of
+// the next key/value pair (if it is not a restart point) shares its prefix with encodedKey.
 type Iterator struct {
-	key         kv.Key
-	value       kv.Value
-	offsetIndex uint16
-	block       Block
+	key                   kv.Key
+	value                 kv.Value
+	encodedKey            []byte
+	nextOffset            int
+	nextRestartPointIndex int
+	block                 Block
 }
 
 // Key returns kv.Key.
@@ -29,44 +33,46 @@ func (iterator *Iterator) IsValid() bool {
 	return !iterator.key.IsRawKeyEmpty()
 }
 
-// Next increments the offsetIndex by one and seeks to the incremented offset.
+// Next seeks to the key/value pair after the current one.
 func (iterator *Iterator) Next() error {
-	iterator.offsetIndex++
-	iterator.seekToOffsetIndex(iterator.offsetIndex)
-
+	iterator.seekToNextKeyValuePair()
 	return nil
 }
 
 // Close does nothing.
 func (iterator *Iterator) Close() {}
 
-// seekToOffsetIndex seeks to the offset identified by the index of keyValueBeginOffsets (in iterator.block.keyValueBeginOffsets) slice.
-// If index >= len(iterator.block.keyValueBeginOffsets), iterator is marked invalid.
-func (iterator *Iterator) seekToOffsetIndex(index uint16) {
-	if index >= uint16(len(iterator.block.keyValueBeginOffsets)) {
+// seekToRestartPoint seeks to the key/value pair identified by the index of restartPoints (in iterator.block.restartPoints) slice.
+// If index >= len(iterator.block.restartPoints), iterator is marked invalid.
+func (iterator *Iterator) seekToRestartPoint(index int) {
+	if index >= len(iterator.block.restartPoints) {
 		iterator.markInvalid()
 		return
 	}
-	keyValueBeginOffset := iterator.block.keyValueBeginOffsets[index]
-	iterator.offsetIndex = index
-	iterator.seekToOffset(keyValueBeginOffset)
+	iterator.nextOffset = int(iterator.block.restartPoints[index])
+	iterator.nextRestartPointIndex = index
+	iterator.encodedKey = nil
+	iterator.seekToNextKeyValuePair()
 }
 
 // seekToGreaterOrEqual seeks to the key greater than or equal to the given key.
-// It leverages binary search within keyValueBeginOffsets (in iterator.block.keyValueBeginOffsets) to perform seek.
+// It leverages binary search within restartPoints (in iterator.block.restartPoints) to find the last restart point with a
+// key lesser than the given key, and then scans the key/value pairs linearly from the restart point.
 func (iterator *Iterator) seekToGreaterOrEqual(key kv.Key) {
 	low := 0
-	high := len(iterator.block.keyValueBeginOffsets) - 1
+	high := len(iterator.block.restartPoints) - 1
+	restartPointIndex := 0
 
 	for low <= high {
 		mid := (low + high) / 2
-		iterator.seekToOffsetIndex(uint16(mid))
+		iterator.seekToRestartPoint(mid)
 
 		if !iterator.IsValid() {
 			panic("invalid iterator")
 		}
 		switch iterator.key.CompareKeysWithDescendingTimestamp(key) {
 		case -1:
+			restartPointIndex = mid
 			low = mid + 1
 		case 0:
 			return
@@ -74,28 +80,65 @@ func (iterator *Iterator) seekToGreaterOrEqual(key kv.Key) {
 			high = mid - 1
 		}
 	}
-	iterator.seekToOffsetIndex(uint16(low))
+	iterator.seekToRestartPoint(restartPointIndex)
+	for iterator.IsValid() && iterator.key.CompareKeysWithDescendingTimestamp(key) < 0 {
+		iterator.seekToNextKeyValuePair()
+	}
 }
 
-// seekToOffset sets the key and value from the offset identified by keyValueBeginOffset.
-// Technically, it does not seek to anywhere, it uses the keyValueBeginOffset and decodes
-// the key and value.
-func (iterator *Iterator) seekToOffset(keyValueBeginOffset uint16) {
-	data := iterator.block.data[keyValueBeginOffset:]
-
-	keySize := binary.LittleEndian.Uint16(data[:])
-	key := kv.DecodeKeyFrom(data[ReservedKeySize : uint16(ReservedKeySize)+keySize])
+// seekToRawKeyUsingHashIndex seeks to the key greater than or equal to the given key, using the hashIndex of the block to
+// identify the restart point of the raw key, followed by a linear scan from the restart point.
+// It returns false if the block does not contain a hashIndex, or the raw key is not found in the hashIndex, or the scan does
+// not find a key with the same raw key (for example, when the given raw key is not present and shares its bucket with another
+// raw key). The iterator must seek using binary search (seekToGreaterOrEqual) in that case.
+func (iterator *Iterator) seekToRawKeyUsingHashIndex(key kv.Key) bool {
+	restartPointIndex, ok := iterator.block.hashIndex.restartPointIndexOf(key.RawBytes())
+	if !ok {
+		return false
+	}
+	iterator.seekToRestartPoint(restartPointIndex)
+	for iterator.IsValid() && iterator.key.CompareKeysWithDescendingTimestamp(key) < 0 {
+		iterator.seekToNextKeyValuePair()
+	}
+	return iterator.IsValid() && iterator.key.IsRawKeyEqualTo(key)
+}
 
-	valueSize := binary.LittleEndian.Uint32(data[ReservedKeySize+key.EncodedSizeInBytes():])
-	valueOffsetStart := uint32(uint16(ReservedKeySize) + keySize + uint16(ReservedValueSize))
-	value := kv.DecodeValueFrom(data[valueOffsetStart : valueOffsetStart+valueSize])
+// seekToNextKeyValuePair sets the key and value from the key/value pair at nextOffset.
+// Technically, it does not seek to anywhere, it uses the nextOffset and decodes the key and value.
+// If the value does not end within the block, the rest of the value is read from the overflow bytes of the block.
+// The key/value pairs are validated while decoding the Block (please check DecodeToBlock), so the decoding errors are ignored.
+// If there is no key/value pair at nextOffset, iterator is marked invalid.
+func (iterator *Iterator) seekToNextKeyValuePair() {
+	if iterator.nextOffset >= iterator.block.lastDataIndex {
+		iterator.markInvalid()
+		return
+	}
+	restartPoints := iterator.block.restartPoints
+	isRestartPoint := iterator.nextRestartPointIndex < len(restartPoints) &&
+		int(restartPoints[iterator.nextRestartPointIndex]) == iterator.nextOffset
+	if isRestartPoint {
+		iterator.nextRestartPointIndex++
+	}
+	pair, _ := iterator.block.keyValuePairAt(iterator.nextOffset, isRestartPoint, iterator.encodedKey)
+	key, _ := kv.DecodeKeyFrom(pair.encodedKey)
+
+	var value kv.Value
+	if uint32(len(pair.valueInBlock)) == pair.valueSize {
+		value, _ = kv.DecodeValueFrom(pair.valueInBlock)
+	} else {
+		value, _ = kv.DecodeValueFrom(iterator.block.valueWithOverflow(pair.valueInBlock, pair.valueSize))
+	}
 
 	iterator.key = key
 	iterator.value = value
+	iterator.encodedKey = pair.encodedKey
+	iterator.nextOffset = pair.endOffset
 }
 
 // markInvalid marks the iterator invalid by setting the key and value as empty.
 func (iterator *Iterator) markInvalid() {
 	iterator.value = kv.EmptyValue
 	iterator.key = kv.EmptyKey
+	iterator.encodedKey = nil
+	iterator.nextOffset = iterator.block.lastDataIndex
 }
@@ -1,6 +1,7 @@
 package block
 
 import (
+	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -144,3 +145,68 @@ func TestBlockSeekToTheNonExistingKey(t *testing.T) {
 
 	assert.False(t, iterator.IsValid())
 }
+
+func TestBlockSeekToKeysAcrossRestartPoints(t *testing.T) {
+	blockBuilder := newBlockBuilderWithRestartInterval(4096, 4)
+	for count := 0; count < 30; count += 2 {
+		blockBuilder.Add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%02d", count), 10), kv.NewStringValue(fmt.Sprintf("value-%d", count)))
+	}
+
+	block := blockBuilder.Build()
+	for count := 0; count < 30; count++ {
+		iterator := block.SeekToKey(kv.NewStringKeyWithTimestamp(fmt.Sprintf("tenant/key-%02d", count), 10))
+
+		expectedCount := count + count%2
+		if expectedCount >= 30 {
+			assert.False(t, iterator.IsValid())
+			continue
+		}
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, fmt.Sprintf("tenant/key-%02d", expectedCount), iterator.Key().RawString())
+		assert.Equal(t, fmt.Sprintf("value-%d", expectedCount), iterator.Value().String())
+
+		_ = iterator.Next()
+		if expectedCount+2 < 30 {
+			assert.Equal(t, fmt.Sprintf("tenant/key-%02d", expectedCount+2), iterator.Key().RawString())
+		} else {
+			assert.False(t, iterator.IsValid())
+		}
+		iterator.Close()
+	}
+}
+
+func TestBlockSeekToAKeyLesserThanAllTheKeys(t *testing.T) {
+	blockBuilder := newBlockBuilderWithRestartInterval(4096, 2)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/consensus", 5), kv.NewStringValue("raft"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/etcd", 10), kv.NewStringValue("kv"))
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/paxos", 10), kv.NewStringValue("consensus"))
+
+	block := blockBuilder.Build()
+	iterator := block.SeekToKey(kv.NewStringKeyWithTimestamp("tenant/bolt", 10))
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "tenant/consensus", iterator.Key().RawString())
+}
+
+func TestBlockSeekToRawKeyUsingHashIndex(t *testing.T) {
+	blockBuilder := NewBlockBuilderWithHashIndex(4096)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/consensus", 5), kv.NewStringValue("raft"))
+
+	block := blockBuilder.Build()
+
+	iterator := &Iterator{block: block}
+	assert.True(t, iterator.seekToRawKeyUsingHashIndex(kv.NewStringKeyWithTimestamp("tenant/consensus", 5)))
+	assert.Equal(t, "raft", iterator.Value().String())
+
+	iterator = &Iterator{block: block}
+	assert.False(t, iterator.seekToRawKeyUsingHashIndex(kv.NewStringKeyWithTimestamp("tenant/consensus", 4)))
+}
+
+func TestBlockWithoutHashIndexDoesNotSeekToRawKeyUsingHashIndex(t *testing.T) {
+	blockBuilder := NewBlockBuilder(4096)
+	blockBuilder.Add(kv.NewStringKeyWithTimestamp("tenant/consensus", 5), kv.NewStringValue("raft"))
+
+	iterator := &Iterator{block: blockBuilder.Build()}
+	assert.False(t, iterator.seekToRawKeyUsingHashIndex(kv.NewStringKeyWithTimestamp("tenant/consensus", 5)))
+}
@@ -0,0 +1,141 @@
+package block
+
+import (
+	"encoding/binary"
+)
+
+// layout describes the widths used in the encoding of a Block, which depend on the format version of the persistent
+// sorted segment.
+// The blocks of FormatVersionLegacy and FormatVersion1 use uint16 offsets, uint16 key sizes and uint32 value sizes, which
+// limit the blocks and the keys to 64 KiB.
+// The blocks of FormatVersion2 use uint32 offsets, and varint key and value sizes.
+type layout struct {
+	wide bool
+}
+
+// layoutOf returns the layout of the blocks of the given format version.
+func layoutOf(formatVersion uint16) layout {
+	return layout{wide: formatVersion >= FormatVersion2}
+}
+
+// offsetSize returns the size of an offset within the block (a restart point, the start of the restart points, the number
+// of restart points and the number of hash index buckets).
+func (layout layout) offsetSize() int {
+	if layout.wide {
+		return Uint32Size
+	}
+	return Uint16Size
+}
+
+// putOffset encodes the given offset at the beginning of the buffer.
+func (layout layout) putOffset(buffer []byte, offset uint32) {
+	if layout.wide {
+		binary.LittleEndian.PutUint32(buffer, offset)
+		return
+	}
+	binary.LittleEndian.PutUint16(buffer, uint16(offset))
+}
+
+// offsetAt decodes the offset at the beginning of the buffer.
+func (layout layout) offsetAt(buffer []byte) uint32 {
+	if layout.wide {
+		return binary.LittleEndian.Uint32(buffer)
+	}
+	return uint32(binary.LittleEndian.Uint16(buffer))
+}
+
+// hashIndexFlag returns the flag which is set in the number of restart points of a block which contains a hashIndex.
+// The number of restart points never reaches this bit, even if each key/value pair of a block of the maximum block size
+// is a restart point.
+func (layout layout) hashIndexFlag() uint32 {
+	if layout.wide {
+		return 1 << 31
+	}
+	return 1 << 15
+}
+
+// keySizeLength returns the number of bytes taken by the encoding of the given key size (or shared key size).
+func (layout layout) keySizeLength(size int) int {
+	if layout.wide {
+		return uvarintLength(uint64(size))
+	}
+	return ReservedKeySize
+}
+
+// valueSizeLength returns the number of bytes taken by the encoding of the given value size.
+func (layout layout) valueSizeLength(size uint32) int {
+	if layout.wide {
+		return uvarintLength(uint64(size))
+	}
+	return ReservedValueSize
+}
+
+// putKeySize encodes the given key size (or shared key size) at the beginning of the buffer, and returns the number of
+// bytes written.
+func (layout layout) putKeySize(buffer []byte, size int) int {
+	if layout.wide {
+		return binary.PutUvarint(buffer, uint64(size))
+	}
+	binary.LittleEndian.PutUint16(buffer, uint16(size))
+	return ReservedKeySize
+}
+
+// putValueSize encodes the given value size at the beginning of the buffer, and returns the number of bytes written.
+func (layout layout) putValueSize(buffer []byte, size uint32) int {
+	if layout.wide {
+		return binary.PutUvarint(buffer, uint64(size))
+	}
+	binary.LittleEndian.PutUint32(buffer, size)
+	return ReservedValueSize
+}
+
+// keySizeAt decodes the key size (or shared key size) at the beginning of the buffer, and returns the size along with the
+// number of bytes read. It returns false if the buffer does not contain a valid encoding of the size.
+func (layout layout) keySizeAt(buffer []byte) (int, int, bool) {
+	if layout.wide {
+		size, length := binary.Uvarint(buffer)
+		if length <= 0 || size > uint64(MaximumBlockSize) {
+			return 0, 0, false
+		}
+		return int(size), length, true
+	}
+	if len(buffer) < ReservedKeySize {
+		return 0, 0, false
+	}
+	return int(binary.LittleEndian.Uint16(buffer)), ReservedKeySize, true
+}
+
+// valueSizeAt decodes the value size at the beginning of the buffer, and returns the size along with the number of bytes
+// read. It returns false if the buffer does not contain a valid encoding of the size.
+func (layout layout) valueSizeAt(buffer []byte) (uint32, int, bool) {
+	if layout.wide {
+		size, length := binary.Uvarint(buffer)
+		if length <= 0 || size > uint64(^uint32(0)) {
+			return 0, 0, false
+		}
+		return uint32(size), length, true
+	}
+	if len(buffer) < ReservedValueSize {
+		return 0, 0, false
+	}
+	return binary.LittleEndian.Uint32(buffer), ReservedValueSize, true
+}
+
+// uvarintLength returns the number of bytes taken by the varint encoding of the given value.
+func uvarintLength(value uint64) int {
+	length := 1
+	for value >= 0x80 {
+		value >>= 7
+		length++
+	}
+	return length
+}
+
+// availableSizeForValueInAnEmptyBlock returns the number of bytes of a value which fit in an empty block of the given size,
+// after the (restart point) key of the given size, the sizes of the key and the value, the offsets and the hash index of
+// the given size.
+func (layout layout) availableSizeForValueInAnEmptyBlock(blockSize uint, hashIndexSize int, encodedKeySize int, valueSize uint32) int {
+	emptyBlockSize := 2 * layout.offsetSize() //the start offset and the number of restart points
+	return int(blockSize) - emptyBlockSize - hashIndexSize - layout.keySizeLength(encodedKeySize) - encodedKeySize -
+		layout.valueSizeLength(valueSize) - layout.offsetSize()
+}
@@ -3,28 +3,47 @@ package block
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/klauspost/compress/s2"
+	"unsafe"
 )
 
+var ErrInvalidBlockMetaList = errors.New("invalid block meta list")
+
 // Meta represents a block metadata including the starting (/first), ending (/last) key and the starting offset
 // of a block.
+// CompressionCodec is the codec used to compress the block (including its overflow bytes), and UncompressedSize is
+// the size of the block (including its overflow bytes) before compression.
+// UncompressedSize is 0 for the blocks of the persistent sorted segments written before the blocks were compressed,
+// such blocks are never compressed.
 type Meta struct {
-	BlockBeginOffset uint32
+	BlockBeginOffset uint64
 	StartingKey      kv.Key
 	EndingKey        kv.Key
+	CompressionCodec CompressionCodec
+	UncompressedSize uint32
 }
 
 // MetaList is a collection of metadata about multiple blocks.
+// withCompressionCodecs denotes that the encoding of each block meta contains its CompressionCodec and UncompressedSize,
+// it is false for the MetaList decoded using DecodeToLegacyBlockMetaList.
+// withVarints denotes that the number of blocks, the offsets, the sizes and the key lengths are encoded as varints, it is
+// true for the MetaList of block.FormatVersion2.
 type MetaList struct {
-	list              []Meta
-	enableCompression bool
+	list                  []Meta
+	enableCompression     bool
+	withCompressionCodecs bool
+	withVarints           bool
 }
 
-// NewBlockMetaList creates a new instance of MetaList.
+// NewBlockMetaList creates a new instance of MetaList of block.CurrentFormatVersion.
 func NewBlockMetaList(enableCompression bool) *MetaList {
 	return &MetaList{
-		enableCompression: enableCompression,
+		enableCompression:     enableCompression,
+		withCompressionCodecs: true,
+		withVarints:           true,
 	}
 }
 
@@ -36,12 +55,53 @@ func (metaList *MetaList) Add(meta Meta) {
 // Encode encodes the meta-list.
 // Encoding includes:
 /*
-  ---------------------------------------------------------------------------------------------------------------
- | 4 bytes for the number of blocks | 4 bytes for block begin-offset | Encoded starting key | Encoded ending key |
-  ---------------------------------------------------------------------------------------------------------------
-                                    <-------------------------------------for each block------------------------>
+  ----------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+ | 4 bytes for the number of blocks | 4 bytes for block begin-offset | 1 byte for compression codec | 4 bytes for uncompressed size | Encoded starting key | Encoded ending key |
+  ----------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+                                    <-----------------------------------------------------------for each block------------------------------------------------------------>
 */
+// The compression codec and the uncompressed size are not a part of the encoding of the MetaList decoded using
+// DecodeToLegacyBlockMetaList.
+// The MetaList of block.FormatVersion2 encodes the number of blocks, the block begin-offset, the uncompressed size and the
+// sizes of the keys as varints (please check encodeWithVarints).
 func (metaList *MetaList) Encode() []byte {
+	var buffer []byte
+	if metaList.withVarints {
+		buffer = metaList.encodeWithVarints()
+	} else {
+		buffer = metaList.encodeWithFixedWidths()
+	}
+	if metaList.enableCompression {
+		return s2.Encode(nil, buffer)
+	}
+	return buffer
+}
+
+// encodeWithVarints encodes the meta-list of block.FormatVersion2.
+// Encoding includes:
+/*
+  ------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+ | varint number of blocks | varint block begin-offset | 1 byte for compression codec | varint uncompressed size | varint starting key size | starting key | varint ending key size | ending key |
+  ------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+                           <-------------------------------------------------------------------------for each block------------------------------------------------------------------------------>
+*/
+func (metaList *MetaList) encodeWithVarints() []byte {
+	buffer := binary.AppendUvarint(nil, uint64(len(metaList.list)))
+	for _, blockMeta := range metaList.list {
+		buffer = binary.AppendUvarint(buffer, blockMeta.BlockBeginOffset)
+		buffer = append(buffer, byte(blockMeta.CompressionCodec))
+		buffer = binary.AppendUvarint(buffer, uint64(blockMeta.UncompressedSize))
+
+		buffer = binary.AppendUvarint(buffer, uint64(blockMeta.StartingKey.EncodedSizeInBytes()))
+		buffer = append(buffer, blockMeta.StartingKey.EncodedBytes()...)
+		buffer = binary.AppendUvarint(buffer, uint64(blockMeta.EndingKey.EncodedSizeInBytes()))
+		buffer = append(buffer, blockMeta.EndingKey.EncodedBytes()...)
+	}
+	return buffer
+}
+
+// encodeWithFixedWidths encodes the meta-list of block.FormatVersion1 (and the legacy meta-list).
+func (metaList *MetaList) encodeWithFixedWidths() []byte {
 	numberOfBlocks := make([]byte, Uint32Size)
 	binary.LittleEndian.PutUint32(numberOfBlocks, uint32(len(metaList.list)))
 
@@ -52,30 +112,33 @@ func (metaList *MetaList) Encode() []byte {
 		buffer := make(
 			[]byte,
 			Uint32Size+
+				metaList.compressionCodecSize()+
 				ReservedKeySize+
 				blockMeta.StartingKey.EncodedSizeInBytes()+
 				ReservedKeySize+
 				blockMeta.EndingKey.EncodedSizeInBytes(),
 		)
 
-		binary.LittleEndian.PutUint32(buffer[:], blockMeta.BlockBeginOffset)
+		binary.LittleEndian.PutUint32(buffer[:], uint32(blockMeta.BlockBeginOffset))
+		if metaList.withCompressionCodecs {
+			buffer[Uint32Size] = byte(blockMeta.CompressionCodec)
+			binary.LittleEndian.PutUint32(buffer[Uint32Size+1:], blockMeta.UncompressedSize)
+		}
+		startingKeyBegin := Uint32Size + metaList.compressionCodecSize()
 
-		binary.LittleEndian.PutUint16(buffer[Uint32Size:], uint16(blockMeta.StartingKey.EncodedSizeInBytes()))
-		copy(buffer[Uint32Size+ReservedKeySize:], blockMeta.StartingKey.EncodedBytes())
+		binary.LittleEndian.PutUint16(buffer[startingKeyBegin:], uint16(blockMeta.StartingKey.EncodedSizeInBytes()))
+		copy(buffer[startingKeyBegin+ReservedKeySize:], blockMeta.StartingKey.EncodedBytes())
 
 		binary.LittleEndian.PutUint16(
-			buffer[Uint32Size+ReservedKeySize+blockMeta.StartingKey.EncodedSizeInBytes():],
+			buffer[startingKeyBegin+ReservedKeySize+blockMeta.StartingKey.EncodedSizeInBytes():],
 			uint16(blockMeta.EndingKey.EncodedSizeInBytes()),
 		)
 		copy(
-			buffer[Uint32Size+ReservedKeySize+blockMeta.StartingKey.EncodedSizeInBytes()+ReservedKeySize:],
+			buffer[startingKeyBegin+ReservedKeySize+blockMeta.StartingKey.EncodedSizeInBytes()+ReservedKeySize:],
 			blockMeta.EndingKey.EncodedBytes(),
 		)
 		resultingBuffer.Write(buffer)
 	}
-	if metaList.enableCompression {
-		return s2.Encode(nil, resultingBuffer.Bytes())
-	}
 	return resultingBuffer.Bytes()
 }
 
@@ -92,6 +155,16 @@ func (metaList *MetaList) Length() int {
 	return len(metaList.list)
 }
 
+// SizeInBytes returns the memory footprint of the MetaList, which includes the starting and the ending keys of all the
+// blocks.
+func (metaList *MetaList) SizeInBytes() int {
+	sizeInBytes := int(unsafe.Sizeof(*metaList)) + cap(metaList.list)*int(unsafe.Sizeof(Meta{}))
+	for _, meta := range metaList.list {
+		sizeInBytes += meta.StartingKey.RawSizeInBytes() + meta.EndingKey.RawSizeInBytes()
+	}
+	return sizeInBytes
+}
+
 // MaybeBlockMetaContaining returns the block meta and the block index (block index starts from zero) that may contain the given key.
 // It compares the key with the StartingKey of the block meta.
 // It returns the instance of block Meta where the given key is greater than or equal to the starting key of the block.
@@ -114,47 +187,182 @@ func (metaList *MetaList) MaybeBlockMetaContaining(key kv.Key) (Meta, int) {
 	return metaList.list[possibleIndex], possibleIndex
 }
 
-// DecodeToBlockMetaList decodes the MetaList from the byte slice.
+// DecodeToBlockMetaList decodes the MetaList of block.CurrentFormatVersion from the byte slice.
 // Please look at MetaList.Encode() to understand the encoding of MetaList.
 func DecodeToBlockMetaList(buffer []byte, enableCompression bool) (*MetaList, error) {
-	var decodedBuffer = buffer
-	var err error
+	return DecodeToBlockMetaListOfFormatVersion(buffer, enableCompression, CurrentFormatVersion)
+}
+
+// DecodeToBlockMetaListOfFormatVersion decodes the MetaList of the given format version from the byte slice.
+// The MetaList of block.FormatVersion1 is encoded with fixed widths, and the MetaList of block.FormatVersion2 is encoded
+// with varints.
+func DecodeToBlockMetaListOfFormatVersion(buffer []byte, enableCompression bool, formatVersion uint16) (*MetaList, error) {
+	if formatVersion >= FormatVersion2 {
+		return decodeToBlockMetaListWithVarints(buffer, enableCompression)
+	}
+	return decodeToBlockMetaListWithFixedWidths(buffer, enableCompression, true)
+}
+
+// DecodeToLegacyBlockMetaList decodes the MetaList of the persistent sorted segments written before the blocks were
+// compressed, the encoding of such MetaList does not contain the compression codec and the uncompressed size of the blocks.
+func DecodeToLegacyBlockMetaList(buffer []byte, enableCompression bool) (*MetaList, error) {
+	return decodeToBlockMetaListWithFixedWidths(buffer, enableCompression, false)
+}
 
-	if enableCompression {
-		decodedBuffer, err = s2.Decode(nil, buffer)
+func decodeToBlockMetaListWithVarints(buffer []byte, enableCompression bool) (*MetaList, error) {
+	decodedBuffer, err := maybeDecompressBlockMetaList(buffer, enableCompression)
+	if err != nil {
+		return nil, err
+	}
+	decodeUvarint := func() (uint64, error) {
+		value, length := binary.Uvarint(decodedBuffer)
+		if length <= 0 {
+			return 0, ErrInvalidBlockMetaList
+		}
+		decodedBuffer = decodedBuffer[length:]
+		return value, nil
+	}
+	decodeKey := func() (kv.Key, error) {
+		keySize, err := decodeUvarint()
 		if err != nil {
-			return nil, err
+			return kv.EmptyKey, err
 		}
+		if keySize > uint64(len(decodedBuffer)) {
+			return kv.EmptyKey, ErrInvalidBlockMetaList
+		}
+		key, err := kv.DecodeKeyFrom(decodedBuffer[:keySize])
+		if err != nil {
+			return kv.EmptyKey, fmt.Errorf("%w: %w", ErrInvalidBlockMetaList, err)
+		}
+		decodedBuffer = decodedBuffer[keySize:]
+		return key, nil
+	}
+
+	numberOfBlocks, err := decodeUvarint()
+	if err != nil {
+		return nil, fmt.Errorf("%w: size %v bytes", ErrInvalidBlockMetaList, len(decodedBuffer))
+	}
+	// each block meta takes at least one byte for each of: the block begin offset, the compression codec, the uncompressed
+	// size and the sizes of the starting and ending keys.
+	minimumBlockMetaSize := uint64(5)
+	if numberOfBlocks > uint64(len(decodedBuffer))/minimumBlockMetaSize {
+		return nil, fmt.Errorf("%w: %v blocks in %v bytes", ErrInvalidBlockMetaList, numberOfBlocks, len(decodedBuffer))
 	}
-	numberOfBlocks := binary.LittleEndian.Uint32(decodedBuffer[:])
 	blockList := make([]Meta, 0, numberOfBlocks)
+	for blockCount := uint64(0); blockCount < numberOfBlocks; blockCount++ {
+		var blockMeta Meta
+		if blockMeta.BlockBeginOffset, err = decodeUvarint(); err != nil {
+			return nil, err
+		}
+		if len(decodedBuffer) < 1 {
+			return nil, ErrInvalidBlockMetaList
+		}
+		blockMeta.CompressionCodec = CompressionCodec(decodedBuffer[0])
+		decodedBuffer = decodedBuffer[1:]
 
-	decodedBuffer = decodedBuffer[Uint32Size:]
-	for blockCount := 0; blockCount < int(numberOfBlocks); blockCount++ {
-		offset := binary.LittleEndian.Uint32(decodedBuffer[:])
+		uncompressedSize, err := decodeUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if uncompressedSize > uint64(^uint32(0)) {
+			return nil, fmt.Errorf("%w: uncompressed size %v", ErrInvalidBlockMetaList, uncompressedSize)
+		}
+		blockMeta.UncompressedSize = uint32(uncompressedSize)
+		if blockMeta.StartingKey, err = decodeKey(); err != nil {
+			return nil, err
+		}
+		if blockMeta.EndingKey, err = decodeKey(); err != nil {
+			return nil, err
+		}
+		blockList = append(blockList, blockMeta)
+	}
+	return &MetaList{
+		list:                  blockList,
+		enableCompression:     enableCompression,
+		withCompressionCodecs: true,
+		withVarints:           true,
+	}, nil
+}
 
-		startingKeySize := binary.LittleEndian.Uint16(decodedBuffer[Uint32Size:])
-		startingKeyBegin := 0 + Uint32Size + ReservedKeySize
-		startingKey := decodedBuffer[startingKeyBegin : startingKeyBegin+int(startingKeySize)]
+func decodeToBlockMetaListWithFixedWidths(buffer []byte, enableCompression bool, withCompressionCodecs bool) (*MetaList, error) {
+	metaList := &MetaList{
+		enableCompression:     enableCompression,
+		withCompressionCodecs: withCompressionCodecs,
+	}
+	decodedBuffer, err := maybeDecompressBlockMetaList(buffer, enableCompression)
+	if err != nil {
+		return nil, err
+	}
+	if len(decodedBuffer) < Uint32Size {
+		return nil, fmt.Errorf("%w: size %v bytes", ErrInvalidBlockMetaList, len(decodedBuffer))
+	}
+	numberOfBlocks := int(binary.LittleEndian.Uint32(decodedBuffer[:]))
+	decodedBuffer = decodedBuffer[Uint32Size:]
 
-		endKeyBegin := 0 + startingKeyBegin + int(startingKeySize)
-		endingKeySize := binary.LittleEndian.Uint16(decodedBuffer[endKeyBegin:])
+	// each block meta takes at least the block begin offset, the compression codec (if any) and the sizes of the starting and ending keys.
+	minimumBlockMetaSize := Uint32Size + metaList.compressionCodecSize() + ReservedKeySize + ReservedKeySize
+	if numberOfBlocks > len(decodedBuffer)/minimumBlockMetaSize {
+		return nil, fmt.Errorf("%w: %v blocks in %v bytes", ErrInvalidBlockMetaList, numberOfBlocks, len(decodedBuffer))
+	}
+	decodeKey := func(buffer []byte, index int) (kv.Key, int, error) {
+		if index+ReservedKeySize > len(buffer) {
+			return kv.EmptyKey, 0, ErrInvalidBlockMetaList
+		}
+		keySize := int(binary.LittleEndian.Uint16(buffer[index:]))
+		keyBegin := index + ReservedKeySize
+		if keyBegin+keySize > len(buffer) {
+			return kv.EmptyKey, 0, ErrInvalidBlockMetaList
+		}
+		key, err := kv.DecodeKeyFrom(buffer[keyBegin : keyBegin+keySize])
+		if err != nil {
+			return kv.EmptyKey, 0, fmt.Errorf("%w: %w", ErrInvalidBlockMetaList, err)
+		}
+		return key, keyBegin + keySize, nil
+	}
 
-		endKeyBegin = endKeyBegin + ReservedKeySize
-		endingKey := decodedBuffer[endKeyBegin : endKeyBegin+int(endingKeySize)]
+	blockList := make([]Meta, 0, numberOfBlocks)
+	for blockCount := 0; blockCount < numberOfBlocks; blockCount++ {
+		if len(decodedBuffer) < Uint32Size+metaList.compressionCodecSize() {
+			return nil, ErrInvalidBlockMetaList
+		}
+		blockMeta := Meta{BlockBeginOffset: uint64(binary.LittleEndian.Uint32(decodedBuffer[:]))}
+		if withCompressionCodecs {
+			blockMeta.CompressionCodec = CompressionCodec(decodedBuffer[Uint32Size])
+			blockMeta.UncompressedSize = binary.LittleEndian.Uint32(decodedBuffer[Uint32Size+1:])
+		}
 
-		blockList = append(blockList, Meta{
-			BlockBeginOffset: offset,
-			StartingKey:      kv.DecodeKeyFrom(startingKey),
-			EndingKey:        kv.DecodeKeyFrom(endingKey),
-		})
-		index := endKeyBegin + int(endingKeySize)
+		startingKey, endKeyBegin, err := decodeKey(decodedBuffer, Uint32Size+metaList.compressionCodecSize())
+		if err != nil {
+			return nil, err
+		}
+		endingKey, index, err := decodeKey(decodedBuffer, endKeyBegin)
+		if err != nil {
+			return nil, err
+		}
+		blockMeta.StartingKey = startingKey
+		blockMeta.EndingKey = endingKey
+		blockList = append(blockList, blockMeta)
 		decodedBuffer = decodedBuffer[index:]
 	}
-	return &MetaList{
-		list:              blockList,
-		enableCompression: enableCompression,
-	}, nil
+	metaList.list = blockList
+	return metaList, nil
+}
+
+// maybeDecompressBlockMetaList decompresses the encoded MetaList if the compression is enabled.
+func maybeDecompressBlockMetaList(buffer []byte, enableCompression bool) ([]byte, error) {
+	if !enableCompression {
+		return buffer, nil
+	}
+	return s2.Decode(nil, buffer)
+}
+
+// compressionCodecSize returns the number of bytes taken by the compression codec and the uncompressed size in the
+// encoding of each block meta.
+func (metaList *MetaList) compressionCodecSize() int {
+	if metaList.withCompressionCodecs {
+		return 1 + Uint32Size
+	}
+	return 0
 }
 
 // StartingKeyOfFirstBlock returns the starting key of the first block.
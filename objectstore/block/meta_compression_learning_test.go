@@ -13,7 +13,7 @@ func TestCompressionRatio(t *testing.T) {
 	blockMetaList := NewBlockMetaList(doNotEnableCompression)
 	for blockCount := 0; blockCount < 100; blockCount++ {
 		blockMetaList.Add(Meta{
-			BlockBeginOffset: uint32(4196 * blockCount),
+			BlockBeginOffset: uint64(4196 * blockCount),
 			StartingKey:      kv.NewStringKeyWithTimestamp("zero disk architecture", 10),
 			EndingKey:        kv.NewStringKeyWithTimestamp("zero disk architecture is interesting", 10),
 		})
@@ -23,7 +23,7 @@ func TestCompressionRatio(t *testing.T) {
 	blockMetaList = NewBlockMetaList(enableCompression)
 	for blockCount := 0; blockCount < 100; blockCount++ {
 		blockMetaList.Add(Meta{
-			BlockBeginOffset: uint32(4196 * blockCount),
+			BlockBeginOffset: uint64(4196 * blockCount),
 			StartingKey:      kv.NewStringKeyWithTimestamp("zero disk architecture", 10),
 			EndingKey:        kv.NewStringKeyWithTimestamp("zero disk architecture is interesting", 10),
 		})
@@ -1,9 +1,11 @@
 package block
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -86,17 +88,17 @@ func TestBlockMetaListWithThreeBlockMetaWithEndingKeyOfEachBlock(t *testing.T) {
 	assert.Equal(t, 3, decodedBlockMetaList.Length())
 
 	meta, _ := decodedBlockMetaList.GetAt(0)
-	assert.Equal(t, uint32(0), meta.BlockBeginOffset)
+	assert.Equal(t, uint64(0), meta.BlockBeginOffset)
 	assert.Equal(t, "accurate", meta.StartingKey.RawString())
 	assert.Equal(t, "amorphous", meta.EndingKey.RawString())
 
 	meta, _ = decodedBlockMetaList.GetAt(1)
-	assert.Equal(t, uint32(4096), meta.BlockBeginOffset)
+	assert.Equal(t, uint64(4096), meta.BlockBeginOffset)
 	assert.Equal(t, "bolt", meta.StartingKey.RawString())
 	assert.Equal(t, "bunt", meta.EndingKey.RawString())
 
 	meta, _ = decodedBlockMetaList.GetAt(2)
-	assert.Equal(t, uint32(8192), meta.BlockBeginOffset)
+	assert.Equal(t, uint64(8192), meta.BlockBeginOffset)
 	assert.Equal(t, "consensus", meta.StartingKey.RawString())
 	assert.Equal(t, "distributed", meta.EndingKey.RawString())
 }
@@ -306,7 +308,7 @@ func TestBlockMetaListGetBlockWhichMayContainTheGivenKey7(t *testing.T) {
 		key := fmt.Sprintf("key-%d", count)
 		timestamp := uint64(count)
 		blockMetaList.Add(Meta{
-			BlockBeginOffset: uint32(count),
+			BlockBeginOffset: uint64(count),
 			StartingKey:      kv.NewStringKeyWithTimestamp(key, timestamp),
 		})
 	}
@@ -315,3 +317,168 @@ func TestBlockMetaListGetBlockWhichMayContainTheGivenKey7(t *testing.T) {
 	assert.Equal(t, "key-6", meta.StartingKey.RawString())
 	assert.Equal(t, 2, index)
 }
+
+func TestAttemptToDecodeABlockMetaListWithMoreBlocksThanItsSize(t *testing.T) {
+	blockMetaList := NewBlockMetaList(false)
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 0,
+		StartingKey:      kv.NewStringKeyWithTimestamp("accurate", 2),
+		EndingKey:        kv.NewStringKeyWithTimestamp("badger", 5),
+	})
+
+	encoded := blockMetaList.Encode()
+	encoded[0] = 0xFF
+
+	_, err := DecodeToBlockMetaList(encoded, false)
+	assert.ErrorIs(t, err, ErrInvalidBlockMetaList)
+}
+
+func TestAttemptToDecodeATruncatedBlockMetaList(t *testing.T) {
+	blockMetaList := NewBlockMetaList(false)
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 0,
+		StartingKey:      kv.NewStringKeyWithTimestamp("accurate", 2),
+		EndingKey:        kv.NewStringKeyWithTimestamp("badger", 5),
+	})
+
+	encoded := blockMetaList.Encode()
+	_, err := DecodeToBlockMetaList(encoded[:len(encoded)-3], false)
+	assert.ErrorIs(t, err, ErrInvalidBlockMetaList)
+}
+
+func FuzzDecodeToBlockMetaList(f *testing.F) {
+	blockMetaList := NewBlockMetaList(false)
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 0,
+		StartingKey:      kv.NewStringKeyWithTimestamp("accurate", 2),
+		EndingKey:        kv.NewStringKeyWithTimestamp("badger", 5),
+	})
+	f.Add(blockMetaList.Encode())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		decodedBlockMetaList, err := DecodeToBlockMetaList(buffer, false)
+		if err != nil {
+			return
+		}
+		if decodedBlockMetaList.Length() > 0 {
+			_, _ = decodedBlockMetaList.MaybeBlockMetaContaining(kv.NewStringKeyWithTimestamp("badger", 5))
+		}
+	})
+}
+
+func TestBlockMetaListWithCompressionCodecs(t *testing.T) {
+	blockMetaList := NewBlockMetaList(enableCompression)
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 0,
+		StartingKey:      kv.NewStringKeyWithTimestamp("accurate", 2),
+		EndingKey:        kv.NewStringKeyWithTimestamp("badger", 5),
+		CompressionCodec: CompressionCodecZstd,
+		UncompressedSize: 4096,
+	})
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 1024,
+		StartingKey:      kv.NewStringKeyWithTimestamp("bolt", 6),
+		EndingKey:        kv.NewStringKeyWithTimestamp("calculator", 8),
+		CompressionCodec: CompressionCodecNone,
+		UncompressedSize: 4096,
+	})
+
+	encoded := blockMetaList.Encode()
+	decodedBlockMetaList, err := DecodeToBlockMetaList(encoded, enableCompression)
+	assert.NoError(t, err)
+
+	meta, _ := decodedBlockMetaList.GetAt(0)
+	assert.Equal(t, "accurate", meta.StartingKey.RawString())
+	assert.Equal(t, CompressionCodecZstd, meta.CompressionCodec)
+	assert.Equal(t, uint32(4096), meta.UncompressedSize)
+
+	meta, _ = decodedBlockMetaList.GetAt(1)
+	assert.Equal(t, "bolt", meta.StartingKey.RawString())
+	assert.Equal(t, CompressionCodecNone, meta.CompressionCodec)
+	assert.Equal(t, uint32(4096), meta.UncompressedSize)
+}
+
+func TestLegacyBlockMetaListWithoutCompressionCodecs(t *testing.T) {
+	encoded := binary.LittleEndian.AppendUint32(nil, 1)
+	encoded = binary.LittleEndian.AppendUint32(encoded, 0)
+	for _, key := range []kv.Key{kv.NewStringKeyWithTimestamp("accurate", 2), kv.NewStringKeyWithTimestamp("badger", 5)} {
+		encoded = binary.LittleEndian.AppendUint16(encoded, uint16(key.EncodedSizeInBytes()))
+		encoded = append(encoded, key.EncodedBytes()...)
+	}
+
+	decodedBlockMetaList, err := DecodeToLegacyBlockMetaList(encoded, doNotEnableCompression)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, decodedBlockMetaList.Length())
+
+	meta, _ := decodedBlockMetaList.GetAt(0)
+	assert.Equal(t, "accurate", meta.StartingKey.RawString())
+	assert.Equal(t, "badger", meta.EndingKey.RawString())
+	assert.Equal(t, CompressionCodecNone, meta.CompressionCodec)
+	assert.Equal(t, uint32(0), meta.UncompressedSize)
+	assert.Equal(t, encoded, decodedBlockMetaList.Encode())
+}
+
+func TestBlockMetaListWithABlockBeginOffsetBeyond4GiBAndAKeyLargerThan64KiB(t *testing.T) {
+	largeRawKey := strings.Repeat("k", 70*1024)
+
+	blockMetaList := NewBlockMetaList(enableCompression)
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 5 << 30,
+		StartingKey:      kv.NewStringKeyWithTimestamp("accurate", 2),
+		EndingKey:        kv.NewStringKeyWithTimestamp(largeRawKey, 5),
+		CompressionCodec: CompressionCodecS2,
+		UncompressedSize: 1 << 20,
+	})
+
+	decodedBlockMetaList, err := DecodeToBlockMetaList(blockMetaList.Encode(), enableCompression)
+	assert.NoError(t, err)
+
+	meta, _ := decodedBlockMetaList.GetAt(0)
+	assert.Equal(t, uint64(5<<30), meta.BlockBeginOffset)
+	assert.Equal(t, "accurate", meta.StartingKey.RawString())
+	assert.Equal(t, largeRawKey, meta.EndingKey.RawString())
+	assert.Equal(t, CompressionCodecS2, meta.CompressionCodec)
+	assert.Equal(t, uint32(1<<20), meta.UncompressedSize)
+}
+
+func TestBlockMetaListOfFormatVersion1(t *testing.T) {
+	encoded := binary.LittleEndian.AppendUint32(nil, 1)
+	encoded = binary.LittleEndian.AppendUint32(encoded, 4096)
+	encoded = append(encoded, byte(CompressionCodecZstd))
+	encoded = binary.LittleEndian.AppendUint32(encoded, 8192)
+	for _, key := range []kv.Key{kv.NewStringKeyWithTimestamp("accurate", 2), kv.NewStringKeyWithTimestamp("badger", 5)} {
+		encoded = binary.LittleEndian.AppendUint16(encoded, uint16(key.EncodedSizeInBytes()))
+		encoded = append(encoded, key.EncodedBytes()...)
+	}
+
+	decodedBlockMetaList, err := DecodeToBlockMetaListOfFormatVersion(encoded, doNotEnableCompression, FormatVersion1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, decodedBlockMetaList.Length())
+
+	meta, _ := decodedBlockMetaList.GetAt(0)
+	assert.Equal(t, uint64(4096), meta.BlockBeginOffset)
+	assert.Equal(t, "accurate", meta.StartingKey.RawString())
+	assert.Equal(t, "badger", meta.EndingKey.RawString())
+	assert.Equal(t, CompressionCodecZstd, meta.CompressionCodec)
+	assert.Equal(t, uint32(8192), meta.UncompressedSize)
+	assert.Equal(t, encoded, decodedBlockMetaList.Encode())
+}
+
+func TestSizeInBytesOfBlockMetaList(t *testing.T) {
+	blockMetaList := NewBlockMetaList(doNotEnableCompression)
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 0,
+		StartingKey:      kv.NewStringKeyWithTimestamp("accurate", 2),
+		EndingKey:        kv.NewStringKeyWithTimestamp("consensus", 5),
+	})
+	sizeInBytesWithOneBlockMeta := blockMetaList.SizeInBytes()
+	assert.True(t, sizeInBytesWithOneBlockMeta >= len("accurate")+len("consensus"))
+
+	blockMetaList.Add(Meta{
+		BlockBeginOffset: 4096,
+		StartingKey:      kv.NewStringKeyWithTimestamp(strings.Repeat("distributed", 100), 2),
+		EndingKey:        kv.NewStringKeyWithTimestamp(strings.Repeat("etcd", 100), 5),
+	})
+	assert.True(t, blockMetaList.SizeInBytes() >= sizeInBytesWithOneBlockMeta+len("distributed")*100+len("etcd")*100)
+}
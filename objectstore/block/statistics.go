@@ -0,0 +1,161 @@
+package block
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/SarthakMakhija/zero-store/kv"
+	"math/bits"
+)
+
+// NumberOfSizeHistogramBuckets is the number of buckets of a SizeHistogram, the last bucket counts all the sizes larger
+// than 1 GiB.
+const NumberOfSizeHistogramBuckets = 32
+
+var ErrInvalidStatistics = errors.New("invalid statistics")
+
+// SizeHistogram counts the sizes (of the keys or the values) in power-of-two buckets.
+// The bucket at index 0 counts the empty sizes, and the bucket at index i counts the sizes in [2^(i-1), 2^i).
+type SizeHistogram [NumberOfSizeHistogramBuckets]uint64
+
+// Add adds the given size to its bucket.
+func (histogram *SizeHistogram) Add(size int) {
+	histogram[min(bits.Len(uint(size)), NumberOfSizeHistogramBuckets-1)]++
+}
+
+// Count returns the number of sizes added to the SizeHistogram.
+func (histogram *SizeHistogram) Count() uint64 {
+	var count uint64
+	for _, bucketCount := range histogram {
+		count += bucketCount
+	}
+	return count
+}
+
+// Percentile returns the (exclusive) upper bound of the bucket which contains the given percentile (between 0 and 100)
+// of the sizes. It returns 0 if the SizeHistogram is empty.
+func (histogram *SizeHistogram) Percentile(percentile float64) uint64 {
+	count := histogram.Count()
+	if count == 0 {
+		return 0
+	}
+	rank := max(uint64(float64(count)*percentile/100), 1)
+	var cumulativeCount uint64
+	for bucketIndex, bucketCount := range histogram {
+		cumulativeCount += bucketCount
+		if cumulativeCount >= rank {
+			return 1 << bucketIndex
+		}
+	}
+	return 1 << (NumberOfSizeHistogramBuckets - 1)
+}
+
+// Statistics is the statistics section of the persistent sorted segment, it allows the compaction pickers and the query
+// planners to reason about a segment without reading its data blocks.
+// NumberOfEntries counts all the key/value pairs (including the tombstones), and the timestamps are the minimum and the
+// maximum timestamps of the keys.
+// RawSizeInBytes is the size of the raw keys and the values, and CompressedSizeInBytes is the size of the data blocks
+// (including their overflow bytes) in the persistent sorted segment.
+// KeySizes and ValueSizes are the histograms of the raw key sizes and the value sizes.
+type Statistics struct {
+	NumberOfEntries       uint64
+	NumberOfTombstones    uint64
+	MinimumTimestamp      uint64
+	MaximumTimestamp      uint64
+	RawSizeInBytes        uint64
+	CompressedSizeInBytes uint64
+	KeySizes              SizeHistogram
+	ValueSizes            SizeHistogram
+}
+
+// Add adds the given key/value pair to the Statistics.
+func (statistics *Statistics) Add(key kv.Key, value kv.Value) {
+	if statistics.NumberOfEntries == 0 || key.Timestamp() < statistics.MinimumTimestamp {
+		statistics.MinimumTimestamp = key.Timestamp()
+	}
+	if key.Timestamp() > statistics.MaximumTimestamp {
+		statistics.MaximumTimestamp = key.Timestamp()
+	}
+	statistics.NumberOfEntries++
+	if value.IsDeleted() {
+		statistics.NumberOfTombstones++
+	}
+	statistics.RawSizeInBytes += uint64(key.RawSizeInBytes() + len(value.Bytes()))
+	statistics.KeySizes.Add(key.RawSizeInBytes())
+	statistics.ValueSizes.Add(len(value.Bytes()))
+}
+
+// Encode encodes the Statistics as byte slice.
+// Encoding includes:
+/*
+  ----------------------------------------------------------------------------------------------------------------------------------------------------------
+ | number of entries | number of tombstones | minimum timestamp | maximum timestamp | raw size | compressed size | key size bucket | value size bucket | 4 bytes checksum |
+  ----------------------------------------------------------------------------------------------------------------------------------------------------------
+                                                                                                                <-for each bucket-> <--for each bucket-->
+*/
+// All the fields (except the checksum) are encoded as varints, the last 4 bytes are the checksum of the Statistics section
+// itself.
+func (statistics *Statistics) Encode() []byte {
+	buffer := make([]byte, 0, binary.MaxVarintLen64*6+2*NumberOfSizeHistogramBuckets+ChecksumSize)
+	buffer = binary.AppendUvarint(buffer, statistics.NumberOfEntries)
+	buffer = binary.AppendUvarint(buffer, statistics.NumberOfTombstones)
+	buffer = binary.AppendUvarint(buffer, statistics.MinimumTimestamp)
+	buffer = binary.AppendUvarint(buffer, statistics.MaximumTimestamp)
+	buffer = binary.AppendUvarint(buffer, statistics.RawSizeInBytes)
+	buffer = binary.AppendUvarint(buffer, statistics.CompressedSizeInBytes)
+	for _, bucketCount := range statistics.KeySizes {
+		buffer = binary.AppendUvarint(buffer, bucketCount)
+	}
+	for _, bucketCount := range statistics.ValueSizes {
+		buffer = binary.AppendUvarint(buffer, bucketCount)
+	}
+	return AppendChecksum(buffer)
+}
+
+// DecodeToStatistics decodes the byte slice and returns an instance of Statistics.
+// It returns ErrChecksumMismatch if the checksum of the Statistics section does not match, and ErrInvalidStatistics if
+// the section is malformed.
+func DecodeToStatistics(buffer []byte) (*Statistics, error) {
+	content, ok := VerifyAndStripChecksum(buffer)
+	if !ok {
+		return nil, ErrChecksumMismatch
+	}
+	offset := 0
+	next := func() (uint64, error) {
+		value, length := binary.Uvarint(content[offset:])
+		if length <= 0 {
+			return 0, fmt.Errorf("%w: malformed varint at offset %v", ErrInvalidStatistics, offset)
+		}
+		offset += length
+		return value, nil
+	}
+
+	statistics := &Statistics{}
+	for _, field := range []*uint64{
+		&statistics.NumberOfEntries,
+		&statistics.NumberOfTombstones,
+		&statistics.MinimumTimestamp,
+		&statistics.MaximumTimestamp,
+		&statistics.RawSizeInBytes,
+		&statistics.CompressedSizeInBytes,
+	} {
+		value, err := next()
+		if err != nil {
+			return nil, err
+		}
+		*field = value
+	}
+	for _, histogram := range []*SizeHistogram{&statistics.KeySizes, &statistics.ValueSizes} {
+		for bucketIndex := range histogram {
+			bucketCount, err := next()
+			if err != nil {
+				return nil, err
+			}
+			histogram[bucketIndex] = bucketCount
+		}
+	}
+	if offset != len(content) {
+		return nil, fmt.Errorf("%w: %v trailing bytes", ErrInvalidStatistics, len(content)-offset)
+	}
+	return statistics, nil
+}
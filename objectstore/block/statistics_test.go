@@ -0,0 +1,89 @@
+package block
+
+import (
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAddSizesToSizeHistogram(t *testing.T) {
+	histogram := SizeHistogram{}
+	histogram.Add(0)
+	histogram.Add(1)
+	histogram.Add(3)
+	histogram.Add(4)
+	histogram.Add(1 << 40)
+
+	assert.Equal(t, uint64(1), histogram[0])
+	assert.Equal(t, uint64(1), histogram[1])
+	assert.Equal(t, uint64(1), histogram[2])
+	assert.Equal(t, uint64(1), histogram[3])
+	assert.Equal(t, uint64(1), histogram[NumberOfSizeHistogramBuckets-1])
+	assert.Equal(t, uint64(5), histogram.Count())
+}
+
+func TestPercentileOfSizeHistogram(t *testing.T) {
+	histogram := SizeHistogram{}
+	for count := 0; count < 90; count++ {
+		histogram.Add(10)
+	}
+	for count := 0; count < 10; count++ {
+		histogram.Add(1000)
+	}
+
+	assert.Equal(t, uint64(16), histogram.Percentile(50))
+	assert.Equal(t, uint64(16), histogram.Percentile(90))
+	assert.Equal(t, uint64(1024), histogram.Percentile(99))
+}
+
+func TestPercentileOfAnEmptySizeHistogram(t *testing.T) {
+	histogram := SizeHistogram{}
+	assert.Equal(t, uint64(0), histogram.Percentile(50))
+}
+
+func TestAddKeyValuePairsToStatistics(t *testing.T) {
+	statistics := &Statistics{}
+	statistics.Add(kv.NewStringKeyWithTimestamp("consensus", 20), kv.NewStringValue("raft"))
+	statistics.Add(kv.NewStringKeyWithTimestamp("distributed", 10), kv.NewDeletedValue())
+	statistics.Add(kv.NewStringKeyWithTimestamp("storage", 30), kv.NewStringValue("NVMe"))
+
+	assert.Equal(t, uint64(3), statistics.NumberOfEntries)
+	assert.Equal(t, uint64(1), statistics.NumberOfTombstones)
+	assert.Equal(t, uint64(10), statistics.MinimumTimestamp)
+	assert.Equal(t, uint64(30), statistics.MaximumTimestamp)
+	assert.Equal(t, uint64(len("consensus")+len("raft")+len("distributed")+len("storage")+len("NVMe")), statistics.RawSizeInBytes)
+	assert.Equal(t, uint64(3), statistics.KeySizes.Count())
+	assert.Equal(t, uint64(1), statistics.ValueSizes[0])
+	assert.Equal(t, uint64(2), statistics.ValueSizes[3])
+}
+
+func TestEncodeAndDecodeStatistics(t *testing.T) {
+	statistics := &Statistics{}
+	statistics.Add(kv.NewStringKeyWithTimestamp("consensus", 20), kv.NewStringValue("raft"))
+	statistics.Add(kv.NewStringKeyWithTimestamp("distributed", 10), kv.NewDeletedValue())
+	statistics.CompressedSizeInBytes = 4096
+
+	decodedStatistics, err := DecodeToStatistics(statistics.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, statistics, decodedStatistics)
+}
+
+func TestDecodeCorruptedStatistics(t *testing.T) {
+	statistics := &Statistics{}
+	statistics.Add(kv.NewStringKeyWithTimestamp("consensus", 20), kv.NewStringValue("raft"))
+
+	encoded := statistics.Encode()
+	encoded[0] = encoded[0] + 1
+
+	_, err := DecodeToStatistics(encoded)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestDecodeTruncatedStatistics(t *testing.T) {
+	statistics := &Statistics{}
+	statistics.Add(kv.NewStringKeyWithTimestamp("consensus", 20), kv.NewStringValue("raft"))
+
+	encoded := statistics.Encode()
+	_, err := DecodeToStatistics(AppendChecksum(encoded[:10]))
+	assert.ErrorIs(t, err, ErrInvalidStatistics)
+}
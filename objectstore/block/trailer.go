@@ -0,0 +1,103 @@
+package block
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Magic identifies a persistent sorted segment which ends with a Trailer.
+const Magic uint64 = 0x5a45524f53544f52
+
+const (
+	// FormatVersionLegacy is the format version of the persistent sorted segments which do not end with a Trailer.
+	// Such segments end with a FooterBlock of block size.
+	FormatVersionLegacy uint16 = 0
+	// FormatVersion1 is the format version of the persistent sorted segments which end with a compact FooterBlock and a Trailer.
+	FormatVersion1 uint16 = 1
+	// FormatVersion2 is the format version of the persistent sorted segments which encode the offsets of the footer as uint64,
+	// the offsets and the sizes of the block meta list as varints, and the offsets within the blocks as uint32 (along with
+	// varint key and value sizes). It removes the limits of 4 GiB on the segment size, and 64 KiB on the block and the key sizes.
+	FormatVersion2 uint16 = 2
+	// CurrentFormatVersion is the format version used for writing the persistent sorted segments.
+	CurrentFormatVersion = FormatVersion2
+)
+
+const (
+	// TrailerFlagBlockMetaListCompressed denotes that the block meta list of the persistent sorted segment is compressed.
+	TrailerFlagBlockMetaListCompressed uint32 = 1 << 0
+	// TrailerFlagChecksums denotes that the persistent sorted segment contains the Checksums section, and the footer
+	// block is followed by its checksum.
+	TrailerFlagChecksums uint32 = 1 << 1
+	// TrailerFlagBlockCompressionCodecs denotes that the block meta list of the persistent sorted segment records the
+	// compression codec and the uncompressed size of each data block.
+	TrailerFlagBlockCompressionCodecs uint32 = 1 << 2
+	// TrailerFlagCompressionDictionary denotes that the persistent sorted segment contains the CompressionDictionary section,
+	// which is followed by its checksum.
+	TrailerFlagCompressionDictionary uint32 = 1 << 3
+	// TrailerFlagStatistics denotes that the persistent sorted segment contains the Statistics section. The footer block
+	// records the offsets of the CompressionDictionary section even if the segment does not contain a dictionary (both the
+	// offsets are the same), such that the offsets of the Statistics section are always at the same position.
+	TrailerFlagStatistics uint32 = 1 << 4
+)
+
+// TrailerSize is the fixed size of the Trailer.
+var TrailerSize = Uint32Size + Uint32Size + Uint16Size + Uint64Size
+
+var ErrUnsupportedFormatVersion = errors.New("unsupported segment format version")
+
+// Trailer is the fixed size trailer of the persistent sorted segment, it is the last TrailerSize bytes of the segment.
+// The trailer allows the readers to locate the footer without knowing the block size, and to identify the format version
+// of the segment.
+type Trailer struct {
+	FooterLength uint32
+	Flags        uint32
+	Version      uint16
+}
+
+// NewTrailer creates a new Trailer with CurrentFormatVersion.
+func NewTrailer(footerLength uint32, flags uint32) Trailer {
+	return Trailer{
+		FooterLength: footerLength,
+		Flags:        flags,
+		Version:      CurrentFormatVersion,
+	}
+}
+
+// HasFlag returns true if the given flag is set in the Trailer.
+func (trailer Trailer) HasFlag(flag uint32) bool {
+	return trailer.Flags&flag == flag
+}
+
+// Encode encodes the Trailer as byte slice.
+// Encoding includes:
+/*
+  -----------------------------------------------------------------------------------------
+ | 4 bytes for footer length | 4 bytes for flags | 2 bytes for version | 8 bytes for magic |
+  -----------------------------------------------------------------------------------------
+*/
+// Magic is kept at the end, so that the readers can check for its presence by reading the last bytes of the segment.
+func (trailer Trailer) Encode() []byte {
+	buffer := make([]byte, TrailerSize)
+	binary.LittleEndian.PutUint32(buffer[:], trailer.FooterLength)
+	binary.LittleEndian.PutUint32(buffer[Uint32Size:], trailer.Flags)
+	binary.LittleEndian.PutUint16(buffer[Uint32Size+Uint32Size:], trailer.Version)
+	binary.LittleEndian.PutUint64(buffer[Uint32Size+Uint32Size+Uint16Size:], Magic)
+	return buffer
+}
+
+// DecodeToTrailer decodes the last TrailerSize bytes of the given byte slice to the Trailer.
+// It returns false if the byte slice does not end with Magic, which means that the segment is of FormatVersionLegacy.
+func DecodeToTrailer(buffer []byte) (Trailer, bool) {
+	if len(buffer) < TrailerSize {
+		return Trailer{}, false
+	}
+	buffer = buffer[len(buffer)-TrailerSize:]
+	if binary.LittleEndian.Uint64(buffer[Uint32Size+Uint32Size+Uint16Size:]) != Magic {
+		return Trailer{}, false
+	}
+	return Trailer{
+		FooterLength: binary.LittleEndian.Uint32(buffer[:]),
+		Flags:        binary.LittleEndian.Uint32(buffer[Uint32Size:]),
+		Version:      binary.LittleEndian.Uint16(buffer[Uint32Size+Uint32Size:]),
+	}, true
+}
@@ -0,0 +1,51 @@
+package block
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeAndDecodeATrailer(t *testing.T) {
+	trailer := NewTrailer(34, TrailerFlagBlockMetaListCompressed)
+
+	decodedTrailer, ok := DecodeToTrailer(trailer.Encode())
+	assert.True(t, ok)
+	assert.Equal(t, uint32(34), decodedTrailer.FooterLength)
+	assert.Equal(t, CurrentFormatVersion, decodedTrailer.Version)
+	assert.True(t, decodedTrailer.HasFlag(TrailerFlagBlockMetaListCompressed))
+}
+
+func TestDecodeATrailerFromTheEndOfALargerBuffer(t *testing.T) {
+	buffer := append([]byte("segment data"), NewTrailer(18, 0).Encode()...)
+
+	decodedTrailer, ok := DecodeToTrailer(buffer)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(18), decodedTrailer.FooterLength)
+	assert.False(t, decodedTrailer.HasFlag(TrailerFlagBlockMetaListCompressed))
+}
+
+func TestDecodeATrailerFromABufferWithoutMagic(t *testing.T) {
+	footerBlock := NewFooterBlock(DefaultBlockSize)
+	footerBlock.AddOffset(18)
+
+	_, ok := DecodeToTrailer(footerBlock.Encode())
+	assert.False(t, ok)
+}
+
+func TestDecodeATrailerFromAShortBuffer(t *testing.T) {
+	_, ok := DecodeToTrailer([]byte("raft"))
+	assert.False(t, ok)
+}
+
+func FuzzDecodeToTrailer(f *testing.F) {
+	f.Add(NewTrailer(34, TrailerFlagBlockMetaListCompressed).Encode())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		trailer, ok := DecodeToTrailer(buffer)
+		if !ok {
+			return
+		}
+		assert.Equal(t, buffer[len(buffer)-TrailerSize:], trailer.Encode())
+	})
+}
@@ -0,0 +1,503 @@
+package objectstore
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	diskCacheIndexFileName     = "disk_cache.index"
+	diskCacheJournalFileName   = "disk_cache.journal"
+	diskCacheEntryFileSuffix   = ".range"
+	diskCacheChecksumSize      = 4
+	diskCacheEntryFileIdFormat = "%020d"
+	// diskCacheMinimumJournalRecordsToCompact is the minimum number of the journal records before the journal is compacted
+	// into the index.
+	diskCacheMinimumJournalRecordsToCompact = 1024
+)
+
+var (
+	errInvalidDiskCacheIndex = errors.New("invalid disk cache index")
+	diskCacheChecksumTable   = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// diskCacheKey identifies a byte range of an object.
+type diskCacheKey struct {
+	pathSuffix  string
+	startOffset int64
+	length      int64
+}
+
+// contains returns true if the byte range of the diskCacheKey contains the byte range of the other diskCacheKey.
+func (key diskCacheKey) contains(other diskCacheKey) bool {
+	return key.pathSuffix == other.pathSuffix &&
+		key.startOffset <= other.startOffset &&
+		other.startOffset+other.length <= key.startOffset+key.length
+}
+
+// diskCacheEntry is a cached byte range, which is stored in the file identified by fileId.
+// sizeInBytes is the size of the file, which includes the checksum of the byte range.
+type diskCacheEntry struct {
+	key         diskCacheKey
+	fileId      uint64
+	sizeInBytes int64
+}
+
+// DiskCache is a read-through cache of the byte ranges of the objects, on a local directory.
+// Each cached byte range is stored in its own file along with its checksum, and the checksum is verified when the byte
+// range is read back. A byte range which fails the verification (or can not be read) is removed from the DiskCache.
+// A byte range is served from any cached byte range (of the same object) which contains it.
+// DiskCache evicts the least recently used byte ranges to stay within capacityInBytes.
+// The index of the DiskCache (the byte ranges in the least recently used order) is persisted on Close, and every cached
+// byte range is appended to the journal as it is cached, so the byte ranges survive a crash. The journal is compacted into
+// the index on Close, and when it grows beyond twice the number of the cached byte ranges. NewDiskCache loads the index
+// and replays the journal (the replayed byte ranges are the most recently used ones). The files which are not in the index
+// or the journal are removed, and the entries whose files are missing (evicted or invalidated before a crash) are dropped.
+// The objects of the Store are immutable, so a cached byte range never gets stale, Store.Delete invalidates all the
+// cached byte ranges of the deleted object (after the object is deleted). invalidations is the number of the
+// invalidations, a byte range read before an invalidation is not cached (please check setUnlessInvalidatedSince).
+type DiskCache struct {
+	directory       string
+	capacityInBytes int64
+	sizeInBytes     int64
+	nextFileId      atomic.Uint64
+	entries         map[diskCacheKey]*list.Element
+	pathEntries     map[string]map[diskCacheKey]*list.Element
+	lru             *list.List
+	journal         *os.File
+	journalRecords  int
+	invalidations   uint64
+	lock            sync.Mutex
+}
+
+// NewDiskCache creates a DiskCache on the given directory (created if it does not exist) with the given capacity.
+// The persisted index and the journal, if any, are loaded from the directory and compacted into a new index. An invalid
+// index is discarded along with the cached byte ranges which are not in the journal, and the journal is replayed up to its
+// first invalid (for example, partially written) record.
+func NewDiskCache(directory string, capacityInBytes int64) (*DiskCache, error) {
+	if capacityInBytes <= 0 {
+		panic("disk cache capacity must be greater than 0")
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	diskCache := &DiskCache{
+		directory:       directory,
+		capacityInBytes: capacityInBytes,
+		entries:         make(map[diskCacheKey]*list.Element),
+		pathEntries:     make(map[string]map[diskCacheKey]*list.Element),
+		lru:             list.New(),
+	}
+	if err := diskCache.loadIndex(); err != nil {
+		return nil, err
+	}
+	if err := diskCache.compact(); err != nil {
+		return nil, err
+	}
+	return diskCache, nil
+}
+
+// Get returns the cached byte range of the object at the given pathSuffix, it returns false if neither the byte range nor
+// a byte range containing it is in the DiskCache, or if the file of the cached byte range fails the checksum verification.
+func (diskCache *DiskCache) Get(pathSuffix string, startOffset int64, length int64) ([]byte, bool) {
+	key := diskCacheKey{pathSuffix: pathSuffix, startOffset: startOffset, length: length}
+
+	diskCache.lock.Lock()
+	element, ok := diskCache.elementContaining(key)
+	if !ok {
+		diskCache.lock.Unlock()
+		return nil, false
+	}
+	diskCache.lru.MoveToBack(element)
+	entry := element.Value.(*diskCacheEntry)
+	cachedKey, fileId := entry.key, entry.fileId
+	diskCache.lock.Unlock()
+
+	buffer, err := os.ReadFile(diskCache.entryFilePath(fileId))
+	if err == nil {
+		if content, ok := verifyAndStripDiskCacheChecksum(buffer); ok && int64(len(content)) == cachedKey.length {
+			beginOffset := startOffset - cachedKey.startOffset
+			return content[beginOffset : beginOffset+length], true
+		}
+	}
+	diskCache.lock.Lock()
+	if element, ok := diskCache.entries[cachedKey]; ok && element.Value.(*diskCacheEntry).fileId == fileId {
+		diskCache.removeElement(element)
+	}
+	diskCache.lock.Unlock()
+	return nil, false
+}
+
+// Set caches the given byte range of the object at the given pathSuffix, and evicts the least recently used byte ranges
+// if the DiskCache exceeds its capacity. A byte range larger than the capacity is not cached.
+func (diskCache *DiskCache) Set(pathSuffix string, startOffset int64, length int64, buffer []byte) error {
+	return diskCache.setUnlessInvalidatedSince(diskCache.invalidationVersion(), pathSuffix, startOffset, length, buffer)
+}
+
+// setUnlessInvalidatedSince is Set which does not cache the byte range if any object was invalidated after the given
+// invalidationVersion (please check invalidationVersion). A byte range which is read from the StoreDefinition while its
+// object is being deleted would otherwise be cached after the object is invalidated.
+func (diskCache *DiskCache) setUnlessInvalidatedSince(
+	invalidationVersion uint64,
+	pathSuffix string,
+	startOffset int64,
+	length int64,
+	buffer []byte,
+) error {
+	sizeInBytes := int64(len(buffer) + diskCacheChecksumSize)
+	if sizeInBytes > diskCache.capacityInBytes {
+		return nil
+	}
+	fileId := diskCache.nextFileId.Add(1)
+	entryBuffer := appendDiskCacheChecksum(append(make([]byte, 0, sizeInBytes), buffer...))
+	if err := os.WriteFile(diskCache.entryFilePath(fileId), entryBuffer, 0644); err != nil {
+		return err
+	}
+
+	diskCache.lock.Lock()
+	defer diskCache.lock.Unlock()
+
+	if diskCache.invalidations != invalidationVersion {
+		_ = os.Remove(diskCache.entryFilePath(fileId))
+		return nil
+	}
+	entry := &diskCacheEntry{
+		key:         diskCacheKey{pathSuffix: pathSuffix, startOffset: startOffset, length: length},
+		fileId:      fileId,
+		sizeInBytes: sizeInBytes,
+	}
+	if err := diskCache.appendToJournal(entry); err != nil {
+		_ = os.Remove(diskCache.entryFilePath(fileId))
+		return err
+	}
+	if element, ok := diskCache.entries[entry.key]; ok {
+		diskCache.removeElement(element)
+	}
+	diskCache.addEntry(entry)
+	for diskCache.sizeInBytes > diskCache.capacityInBytes {
+		diskCache.removeElement(diskCache.lru.Front())
+	}
+	if diskCache.journalRecords >= max(diskCacheMinimumJournalRecordsToCompact, 2*len(diskCache.entries)) {
+		return diskCache.compact()
+	}
+	return nil
+}
+
+// Invalidate removes all the cached byte ranges of the object at the given pathSuffix.
+func (diskCache *DiskCache) Invalidate(pathSuffix string) {
+	diskCache.lock.Lock()
+	defer diskCache.lock.Unlock()
+
+	diskCache.invalidations++
+	for _, element := range diskCache.pathEntries[pathSuffix] {
+		diskCache.removeElement(element)
+	}
+}
+
+// invalidationVersion returns the number of the invalidations so far. It must be taken before a byte range is read from
+// the StoreDefinition, and passed to setUnlessInvalidatedSince.
+func (diskCache *DiskCache) invalidationVersion() uint64 {
+	diskCache.lock.Lock()
+	defer diskCache.lock.Unlock()
+
+	return diskCache.invalidations
+}
+
+// SizeInBytes returns the size of all the cached byte ranges (including their checksums).
+func (diskCache *DiskCache) SizeInBytes() int64 {
+	diskCache.lock.Lock()
+	defer diskCache.lock.Unlock()
+
+	return diskCache.sizeInBytes
+}
+
+// Close compacts the journal into the index of the DiskCache, and closes the journal.
+func (diskCache *DiskCache) Close() error {
+	diskCache.lock.Lock()
+	defer diskCache.lock.Unlock()
+
+	if err := diskCache.compact(); err != nil {
+		return err
+	}
+	return diskCache.journal.Close()
+}
+
+// compact persists the index of the DiskCache, and truncates the journal. It must be called with the lock held.
+// The index is written to a temporary file which is then renamed, so a crash never leaves a partially written index. A
+// crash after the index is renamed (and before the journal is truncated) replays the journal over the new index, which
+// already contains its entries.
+func (diskCache *DiskCache) compact() error {
+	indexFilePath := filepath.Join(diskCache.directory, diskCacheIndexFileName)
+	temporaryIndexFilePath := indexFilePath + ".tmp"
+	if err := os.WriteFile(temporaryIndexFilePath, diskCache.encodeIndex(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(temporaryIndexFilePath, indexFilePath); err != nil {
+		return err
+	}
+	if diskCache.journal != nil {
+		_ = diskCache.journal.Close()
+	}
+	journal, err := os.OpenFile(filepath.Join(diskCache.directory, diskCacheJournalFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	diskCache.journal = journal
+	diskCache.journalRecords = 0
+	return nil
+}
+
+// appendToJournal appends the given entry to the journal. It must be called with the lock held.
+// Encoding of each journal record includes:
+/*
+  ----------------------------------------------------------------------------------------
+ | entry size (varint) | entry (please check appendDiskCacheEntry) | checksum             |
+  ----------------------------------------------------------------------------------------
+*/
+// The checksum covers the entry size and the entry, so a partially written record is detected when the journal is
+// replayed.
+func (diskCache *DiskCache) appendToJournal(entry *diskCacheEntry) error {
+	encodedEntry := appendDiskCacheEntry(nil, entry)
+	record := binary.AppendUvarint(nil, uint64(len(encodedEntry)))
+	record = appendDiskCacheChecksum(append(record, encodedEntry...))
+	if _, err := diskCache.journal.Write(record); err != nil {
+		return err
+	}
+	diskCache.journalRecords++
+	return nil
+}
+
+// encodeIndex encodes the entries (please check appendDiskCacheEntry) in the least recently used order, and the index
+// ends with the checksum of all the entries.
+func (diskCache *DiskCache) encodeIndex() []byte {
+	var buffer []byte
+	for element := diskCache.lru.Front(); element != nil; element = element.Next() {
+		buffer = appendDiskCacheEntry(buffer, element.Value.(*diskCacheEntry))
+	}
+	return appendDiskCacheChecksum(buffer)
+}
+
+// loadIndex loads the persisted index and replays the journal over it, and removes the files which are neither in the
+// index nor in the journal.
+// The entries whose files are missing, and the entries beyond the capacity are dropped.
+func (diskCache *DiskCache) loadIndex() error {
+	buffer, err := os.ReadFile(filepath.Join(diskCache.directory, diskCacheIndexFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var entries []*diskCacheEntry
+	if err == nil {
+		entries, err = decodeDiskCacheIndex(buffer)
+		if err != nil {
+			entries = nil
+		}
+	}
+	buffer, err = os.ReadFile(filepath.Join(diskCache.directory, diskCacheJournalFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	entries = append(entries, decodeDiskCacheJournal(buffer)...)
+
+	for _, entry := range entries {
+		info, err := os.Stat(diskCache.entryFilePath(entry.fileId))
+		if err != nil || info.Size() != entry.sizeInBytes {
+			continue
+		}
+		if element, ok := diskCache.entries[entry.key]; ok {
+			if element.Value.(*diskCacheEntry).fileId == entry.fileId {
+				diskCache.lru.MoveToBack(element)
+				continue
+			}
+			diskCache.removeElement(element)
+		}
+		diskCache.addEntry(entry)
+		if entry.fileId > diskCache.nextFileId.Load() {
+			diskCache.nextFileId.Store(entry.fileId)
+		}
+	}
+	for diskCache.sizeInBytes > diskCache.capacityInBytes {
+		diskCache.removeElement(diskCache.lru.Front())
+	}
+	return diskCache.removeOrphanFiles()
+}
+
+// removeOrphanFiles removes the files of the byte ranges which are not in the index.
+func (diskCache *DiskCache) removeOrphanFiles() error {
+	fileIds := make(map[uint64]struct{}, len(diskCache.entries))
+	for _, element := range diskCache.entries {
+		fileIds[element.Value.(*diskCacheEntry).fileId] = struct{}{}
+	}
+	directoryEntries, err := os.ReadDir(diskCache.directory)
+	if err != nil {
+		return err
+	}
+	for _, directoryEntry := range directoryEntries {
+		name := directoryEntry.Name()
+		if !strings.HasSuffix(name, diskCacheEntryFileSuffix) {
+			continue
+		}
+		var fileId uint64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(name, diskCacheEntryFileSuffix), "%d", &fileId); err == nil {
+			if _, ok := fileIds[fileId]; ok {
+				continue
+			}
+		}
+		_ = os.Remove(filepath.Join(diskCache.directory, name))
+	}
+	return nil
+}
+
+// elementContaining returns the element of the given byte range, or of a byte range of the same object which contains
+// it. It must be called with the lock held.
+func (diskCache *DiskCache) elementContaining(key diskCacheKey) (*list.Element, bool) {
+	if element, ok := diskCache.entries[key]; ok {
+		return element, true
+	}
+	for cachedKey, element := range diskCache.pathEntries[key.pathSuffix] {
+		if cachedKey.contains(key) {
+			return element, true
+		}
+	}
+	return nil, false
+}
+
+func (diskCache *DiskCache) addEntry(entry *diskCacheEntry) {
+	element := diskCache.lru.PushBack(entry)
+	diskCache.entries[entry.key] = element
+	if _, ok := diskCache.pathEntries[entry.key.pathSuffix]; !ok {
+		diskCache.pathEntries[entry.key.pathSuffix] = make(map[diskCacheKey]*list.Element)
+	}
+	diskCache.pathEntries[entry.key.pathSuffix][entry.key] = element
+	diskCache.sizeInBytes += entry.sizeInBytes
+}
+
+// removeElement removes the given element from the DiskCache along with its file. It must be called with the lock held.
+func (diskCache *DiskCache) removeElement(element *list.Element) {
+	entry := diskCache.lru.Remove(element).(*diskCacheEntry)
+	delete(diskCache.entries, entry.key)
+	delete(diskCache.pathEntries[entry.key.pathSuffix], entry.key)
+	if len(diskCache.pathEntries[entry.key.pathSuffix]) == 0 {
+		delete(diskCache.pathEntries, entry.key.pathSuffix)
+	}
+	diskCache.sizeInBytes -= entry.sizeInBytes
+	_ = os.Remove(diskCache.entryFilePath(entry.fileId))
+}
+
+func (diskCache *DiskCache) entryFilePath(fileId uint64) string {
+	return filepath.Join(diskCache.directory, fmt.Sprintf(diskCacheEntryFileIdFormat, fileId)+diskCacheEntryFileSuffix)
+}
+
+// appendDiskCacheEntry appends the encoded entry to the given buffer.
+// Encoding of an entry includes:
+/*
+  ----------------------------------------------------------------------------------------
+ | path suffix size | path suffix | start offset | length | file id | size in bytes |
+  ----------------------------------------------------------------------------------------
+*/
+// All the fields (except the path suffix) are encoded as varints.
+func appendDiskCacheEntry(buffer []byte, entry *diskCacheEntry) []byte {
+	buffer = binary.AppendUvarint(buffer, uint64(len(entry.key.pathSuffix)))
+	buffer = append(buffer, entry.key.pathSuffix...)
+	buffer = binary.AppendUvarint(buffer, uint64(entry.key.startOffset))
+	buffer = binary.AppendUvarint(buffer, uint64(entry.key.length))
+	buffer = binary.AppendUvarint(buffer, entry.fileId)
+	return binary.AppendUvarint(buffer, uint64(entry.sizeInBytes))
+}
+
+// decodeDiskCacheEntry decodes the entry at the beginning of the given buffer, and returns the number of bytes it
+// occupies. It returns errInvalidDiskCacheIndex if the entry is malformed.
+func decodeDiskCacheEntry(buffer []byte) (*diskCacheEntry, int, error) {
+	offset := 0
+	next := func() (uint64, error) {
+		value, length := binary.Uvarint(buffer[offset:])
+		if length <= 0 {
+			return 0, fmt.Errorf("%w: malformed varint at offset %v", errInvalidDiskCacheIndex, offset)
+		}
+		offset += length
+		return value, nil
+	}
+	pathSuffixSize, err := next()
+	if err != nil {
+		return nil, 0, err
+	}
+	if uint64(len(buffer)-offset) < pathSuffixSize {
+		return nil, 0, fmt.Errorf("%w: path suffix beyond the entry", errInvalidDiskCacheIndex)
+	}
+	pathSuffix := string(buffer[offset : offset+int(pathSuffixSize)])
+	offset += int(pathSuffixSize)
+
+	var fields [4]uint64
+	for index := range fields {
+		if fields[index], err = next(); err != nil {
+			return nil, 0, err
+		}
+	}
+	return &diskCacheEntry{
+		key:         diskCacheKey{pathSuffix: pathSuffix, startOffset: int64(fields[0]), length: int64(fields[1])},
+		fileId:      fields[2],
+		sizeInBytes: int64(fields[3]),
+	}, offset, nil
+}
+
+// decodeDiskCacheIndex decodes the persisted index, it returns errInvalidDiskCacheIndex if the index is malformed or
+// fails the checksum verification.
+func decodeDiskCacheIndex(buffer []byte) ([]*diskCacheEntry, error) {
+	content, ok := verifyAndStripDiskCacheChecksum(buffer)
+	if !ok {
+		return nil, fmt.Errorf("%w: checksum mismatch", errInvalidDiskCacheIndex)
+	}
+	var entries []*diskCacheEntry
+	for offset := 0; offset < len(content); {
+		entry, length, err := decodeDiskCacheEntry(content[offset:])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		offset += length
+	}
+	return entries, nil
+}
+
+// decodeDiskCacheJournal decodes the records of the journal (please check DiskCache.appendToJournal) up to the first
+// malformed record, or the first record which fails the checksum verification.
+func decodeDiskCacheJournal(buffer []byte) []*diskCacheEntry {
+	var entries []*diskCacheEntry
+	for offset := 0; offset < len(buffer); {
+		entrySize, length := binary.Uvarint(buffer[offset:])
+		if length <= 0 || uint64(len(buffer)-offset-length) < entrySize+diskCacheChecksumSize {
+			break
+		}
+		recordSize := length + int(entrySize) + diskCacheChecksumSize
+		content, ok := verifyAndStripDiskCacheChecksum(buffer[offset : offset+recordSize])
+		if !ok {
+			break
+		}
+		entry, entryLength, err := decodeDiskCacheEntry(content[length:])
+		if err != nil || entryLength != int(entrySize) {
+			break
+		}
+		entries = append(entries, entry)
+		offset += recordSize
+	}
+	return entries
+}
+
+func appendDiskCacheChecksum(buffer []byte) []byte {
+	return binary.LittleEndian.AppendUint32(buffer, crc32.Checksum(buffer, diskCacheChecksumTable))
+}
+
+func verifyAndStripDiskCacheChecksum(buffer []byte) ([]byte, bool) {
+	if len(buffer) < diskCacheChecksumSize {
+		return nil, false
+	}
+	content := buffer[:len(buffer)-diskCacheChecksumSize]
+	return content, binary.LittleEndian.Uint32(buffer[len(buffer)-diskCacheChecksumSize:]) == crc32.Checksum(content, diskCacheChecksumTable)
+}
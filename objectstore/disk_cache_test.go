@@ -0,0 +1,278 @@
+package objectstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestDiskCacheSetAndGetAByteRange(t *testing.T) {
+	diskCache, err := NewDiskCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 10, 4, []byte("raft")))
+
+	buffer, ok := diskCache.Get("segment.1", 10, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(buffer))
+
+	_, ok = diskCache.Get("segment.1", 14, 4)
+	assert.False(t, ok)
+}
+
+func TestDiskCacheEvictsTheLeastRecentlyUsedByteRange(t *testing.T) {
+	diskCache, err := NewDiskCache(t.TempDir(), 2*(4+diskCacheChecksumSize))
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Set("segment.1", 4, 4, []byte("etcd")))
+
+	_, ok := diskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+
+	assert.NoError(t, diskCache.Set("segment.1", 8, 4, []byte("zero")))
+
+	_, ok = diskCache.Get("segment.1", 4, 4)
+	assert.False(t, ok)
+	_, ok = diskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+	_, ok = diskCache.Get("segment.1", 8, 4)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2*(4+diskCacheChecksumSize)), diskCache.SizeInBytes())
+}
+
+func TestDiskCacheDoesNotCacheAByteRangeLargerThanItsCapacity(t *testing.T) {
+	diskCache, err := NewDiskCache(t.TempDir(), 4)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+
+	_, ok := diskCache.Get("segment.1", 0, 4)
+	assert.False(t, ok)
+}
+
+func TestDiskCacheInvalidatesAllTheByteRangesOfAnObject(t *testing.T) {
+	diskCache, err := NewDiskCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Set("segment.1", 4, 4, []byte("etcd")))
+	assert.NoError(t, diskCache.Set("segment.2", 0, 4, []byte("zero")))
+
+	diskCache.Invalidate("segment.1")
+
+	_, ok := diskCache.Get("segment.1", 0, 4)
+	assert.False(t, ok)
+	_, ok = diskCache.Get("segment.1", 4, 4)
+	assert.False(t, ok)
+	_, ok = diskCache.Get("segment.2", 0, 4)
+	assert.True(t, ok)
+}
+
+func TestDiskCacheRemovesAByteRangeWhichFailsTheChecksumVerification(t *testing.T) {
+	diskCache, err := NewDiskCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+
+	entryFilePath := diskCache.entryFilePath(diskCache.entries[diskCacheKey{pathSuffix: "segment.1", startOffset: 0, length: 4}].Value.(*diskCacheEntry).fileId)
+	buffer, err := os.ReadFile(entryFilePath)
+	assert.NoError(t, err)
+	buffer[0] ^= 0xFF
+	assert.NoError(t, os.WriteFile(entryFilePath, buffer, 0644))
+
+	_, ok := diskCache.Get("segment.1", 0, 4)
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), diskCache.SizeInBytes())
+
+	_, err = os.Stat(entryFilePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskCachePersistsItsIndexAcrossRestarts(t *testing.T) {
+	directory := t.TempDir()
+	diskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Set("segment.2", 8, 4, []byte("etcd")))
+	assert.NoError(t, diskCache.Close())
+
+	reopenedDiskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	buffer, ok := reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(buffer))
+
+	buffer, ok = reopenedDiskCache.Get("segment.2", 8, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "etcd", string(buffer))
+
+	assert.NoError(t, reopenedDiskCache.Set("segment.3", 0, 4, []byte("zero")))
+	buffer, ok = reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(buffer))
+}
+
+func TestDiskCacheRemovesTheFilesWhichAreNotInItsIndex(t *testing.T) {
+	directory := t.TempDir()
+	diskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Close())
+
+	orphanFilePath := diskCache.entryFilePath(100)
+	assert.NoError(t, os.WriteFile(orphanFilePath, []byte("etcd"), 0644))
+
+	reopenedDiskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	_, err = os.Stat(orphanFilePath)
+	assert.True(t, os.IsNotExist(err))
+
+	buffer, ok := reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(buffer))
+}
+
+func TestDiskCacheRecoversTheByteRangesFromItsJournalAfterACrash(t *testing.T) {
+	directory := t.TempDir()
+	diskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Set("segment.2", 8, 4, []byte("etcd")))
+
+	reopenedDiskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	buffer, ok := reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(buffer))
+
+	buffer, ok = reopenedDiskCache.Get("segment.2", 8, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "etcd", string(buffer))
+}
+
+func TestDiskCacheDropsTheByteRangesRemovedBeforeACrash(t *testing.T) {
+	directory := t.TempDir()
+	diskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Set("segment.2", 0, 4, []byte("etcd")))
+	diskCache.Invalidate("segment.1")
+
+	reopenedDiskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	_, ok := reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.False(t, ok)
+
+	buffer, ok := reopenedDiskCache.Get("segment.2", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "etcd", string(buffer))
+	assert.Equal(t, int64(4+diskCacheChecksumSize), reopenedDiskCache.SizeInBytes())
+}
+
+func TestDiskCacheReplaysItsJournalUpToAPartiallyWrittenRecord(t *testing.T) {
+	directory := t.TempDir()
+	diskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Set("segment.2", 0, 4, []byte("etcd")))
+
+	journalFilePath := directory + string(os.PathSeparator) + diskCacheJournalFileName
+	buffer, err := os.ReadFile(journalFilePath)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(journalFilePath, buffer[:len(buffer)-1], 0644))
+
+	reopenedDiskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	buffer, ok := reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(buffer))
+
+	_, ok = reopenedDiskCache.Get("segment.2", 0, 4)
+	assert.False(t, ok)
+}
+
+func TestDiskCacheCompactsItsJournal(t *testing.T) {
+	directory := t.TempDir()
+	diskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	for count := 0; count < diskCacheMinimumJournalRecordsToCompact; count++ {
+		assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	}
+	assert.Equal(t, 0, diskCache.journalRecords)
+
+	reopenedDiskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	buffer, ok := reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "raft", string(buffer))
+}
+
+func TestDiskCacheGetsAByteRangeWithinACachedByteRange(t *testing.T) {
+	diskCache, err := NewDiskCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 10, 12, []byte("raftetcdzero")))
+
+	buffer, ok := diskCache.Get("segment.1", 14, 4)
+	assert.True(t, ok)
+	assert.Equal(t, "etcd", string(buffer))
+
+	buffer, ok = diskCache.Get("segment.1", 10, 12)
+	assert.True(t, ok)
+	assert.Equal(t, "raftetcdzero", string(buffer))
+
+	_, ok = diskCache.Get("segment.1", 18, 8)
+	assert.False(t, ok)
+
+	_, ok = diskCache.Get("segment.2", 14, 4)
+	assert.False(t, ok)
+}
+
+func TestDiskCacheDoesNotCacheAByteRangeReadBeforeAnInvalidation(t *testing.T) {
+	diskCache, err := NewDiskCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+
+	invalidationVersion := diskCache.invalidationVersion()
+	diskCache.Invalidate("segment.1")
+
+	assert.NoError(t, diskCache.setUnlessInvalidatedSince(invalidationVersion, "segment.1", 0, 4, []byte("raft")))
+
+	_, ok := diskCache.Get("segment.1", 0, 4)
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), diskCache.SizeInBytes())
+}
+
+func TestDiskCacheDiscardsACorruptedIndex(t *testing.T) {
+	directory := t.TempDir()
+	diskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, diskCache.Set("segment.1", 0, 4, []byte("raft")))
+	assert.NoError(t, diskCache.Close())
+
+	indexFilePath := directory + string(os.PathSeparator) + diskCacheIndexFileName
+	buffer, err := os.ReadFile(indexFilePath)
+	assert.NoError(t, err)
+	buffer[0] ^= 0xFF
+	assert.NoError(t, os.WriteFile(indexFilePath, buffer, 0644))
+
+	reopenedDiskCache, err := NewDiskCache(directory, 1024)
+	assert.NoError(t, err)
+
+	_, ok := reopenedDiskCache.Get("segment.1", 0, 4)
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), reopenedDiskCache.SizeInBytes())
+}
@@ -3,10 +3,22 @@ package filter
 import (
 	"bufio"
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/bits-and-blooms/bloom/v3"
+	"unsafe"
 )
 
+// maxNumberOfHashFunctions is the maximum number of hash functions accepted while decoding a BloomFilter.
+// bloom.NewWithEstimates uses ~33 hash functions for a false positive rate of 1e-10.
+const maxNumberOfHashFunctions = 64
+
+// encodedHeaderSize is the size of the number of bits, the number of hash functions and the length of the bitset.
+const encodedHeaderSize = 3 * 8
+
+var ErrInvalidBloomFilter = errors.New("invalid bloom filter")
+
 // BloomFilter is a wrapper over filter.BloomFilter.
 type BloomFilter struct {
 	filter *bloom.BloomFilter
@@ -20,7 +32,26 @@ func newBloomFilter(filter *bloom.BloomFilter) BloomFilter {
 }
 
 // DecodeToBloomFilter creates a new instance of BloomFilter from the given byte slice.
+// The encoding (by bloom.BloomFilter) contains big-endian number of bits, number of hash functions, length of the bitset
+// followed by the words of the bitset.
+// It returns ErrInvalidBloomFilter if the byte slice is not a valid encoding of the BloomFilter.
 func DecodeToBloomFilter(data []byte) (BloomFilter, error) {
+	if len(data) < encodedHeaderSize {
+		return BloomFilter{}, ErrInvalidBloomFilter
+	}
+	numberOfBits := binary.BigEndian.Uint64(data[:])
+	numberOfHashFunctions := binary.BigEndian.Uint64(data[8:])
+	bitsetLength := binary.BigEndian.Uint64(data[16:])
+	if numberOfBits == 0 || numberOfBits != bitsetLength {
+		return BloomFilter{}, ErrInvalidBloomFilter
+	}
+	if numberOfHashFunctions == 0 || numberOfHashFunctions > maxNumberOfHashFunctions {
+		return BloomFilter{}, ErrInvalidBloomFilter
+	}
+	encodedBitsetSize := uint64(len(data) - encodedHeaderSize)
+	if encodedBitsetSize%8 != 0 || numberOfBits > encodedBitsetSize*8 || (numberOfBits+63)/64 != encodedBitsetSize/8 {
+		return BloomFilter{}, ErrInvalidBloomFilter
+	}
 	filter := &bloom.BloomFilter{}
 	_, err := filter.ReadFrom(bytes.NewReader(data))
 	if err != nil {
@@ -44,6 +75,12 @@ func (filter BloomFilter) Encode() ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// SizeInBytes returns the memory footprint of the BloomFilter, which is dominated by its bit array.
+func (filter BloomFilter) SizeInBytes() int {
+	bitset := filter.filter.BitSet()
+	return int(unsafe.Sizeof(*filter.filter)) + int(unsafe.Sizeof(*bitset)) + int((filter.filter.Cap()+63)/64)*8
+}
+
 // MayContain returns true if the given key may be present in the bloom filter, false otherwise.
 func (filter BloomFilter) MayContain(key kv.Key) bool {
 	return filter.filter.Test(key.RawBytes())
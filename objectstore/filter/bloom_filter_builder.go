@@ -5,14 +5,25 @@ import (
 	"github.com/bits-and-blooms/bloom/v3"
 )
 
+// DefaultFalsePositiveRate is the false positive rate used by NewBloomFilterBuilder.
+const DefaultFalsePositiveRate = 0.01
+
 // BloomFilterBuilder represents a bloom filter builder.
 type BloomFilterBuilder struct {
-	keys []kv.Key
+	keys              []kv.Key
+	falsePositiveRate float64
 }
 
-// NewBloomFilterBuilder creates a new instance of bloom filter builder.
+// NewBloomFilterBuilder creates a new instance of bloom filter builder with DefaultFalsePositiveRate.
 func NewBloomFilterBuilder() *BloomFilterBuilder {
-	return &BloomFilterBuilder{}
+	return NewBloomFilterBuilderWithFalsePositiveRate(DefaultFalsePositiveRate)
+}
+
+// NewBloomFilterBuilderWithFalsePositiveRate creates a new instance of bloom filter builder with the given false positive rate.
+func NewBloomFilterBuilderWithFalsePositiveRate(falsePositiveRate float64) *BloomFilterBuilder {
+	return &BloomFilterBuilder{
+		falsePositiveRate: falsePositiveRate,
+	}
 }
 
 // Add adds the given key to the collection of keys in BloomFilterBuilder.
@@ -22,7 +33,7 @@ func (builder *BloomFilterBuilder) Add(key kv.Key) {
 
 // Build creates a new instance of BloomFilter.
 func (builder *BloomFilterBuilder) Build() BloomFilter {
-	filter := newBloomFilter(bloom.NewWithEstimates(uint(len(builder.keys)), 0.01))
+	filter := newBloomFilter(bloom.NewWithEstimates(uint(len(builder.keys)), builder.falsePositiveRate))
 	for _, key := range builder.keys {
 		filter.add(key)
 	}
@@ -1,6 +1,7 @@
 package filter
 
 import (
+	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -54,3 +55,59 @@ func TestEncodeAndDecodeBloomFilter(t *testing.T) {
 	assert.True(t, filter.MayContain(kv.NewStringKeyWithTimestamp("storage", 5)))
 	assert.True(t, filter.MayContain(kv.NewStringKeyWithTimestamp("zero disk", 5)))
 }
+
+func TestAddAFewKeysToBloomFilterWithAGivenFalsePositiveRate(t *testing.T) {
+	builder := NewBloomFilterBuilderWithFalsePositiveRate(0.001)
+	builder.Add(kv.NewStringKeyWithTimestamp("consensus", 2))
+	builder.Add(kv.NewStringKeyWithTimestamp("storage", 5))
+
+	filter := builder.Build()
+	assert.True(t, filter.MayContain(kv.NewStringKeyWithTimestamp("consensus", 5)))
+	assert.True(t, filter.MayContain(kv.NewStringKeyWithTimestamp("storage", 5)))
+	assert.False(t, filter.MayContain(kv.NewStringKeyWithTimestamp("disk", 5)))
+}
+
+func TestAttemptToDecodeATruncatedBloomFilter(t *testing.T) {
+	builder := NewBloomFilterBuilder()
+	builder.Add(kv.NewStringKeyWithTimestamp("consensus", 3))
+
+	buffer, err := builder.Build().Encode()
+	assert.NoError(t, err)
+
+	_, err = DecodeToBloomFilter(buffer[:len(buffer)-8])
+	assert.ErrorIs(t, err, ErrInvalidBloomFilter)
+}
+
+func TestAttemptToDecodeABloomFilterWithoutBits(t *testing.T) {
+	_, err := DecodeToBloomFilter(make([]byte, 24))
+	assert.ErrorIs(t, err, ErrInvalidBloomFilter)
+}
+
+func FuzzDecodeToBloomFilter(f *testing.F) {
+	builder := NewBloomFilterBuilder()
+	builder.Add(kv.NewStringKeyWithTimestamp("consensus", 3))
+	builder.Add(kv.NewStringKeyWithTimestamp("storage", 5))
+	buffer, _ := builder.Build().Encode()
+	f.Add(buffer)
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		filter, err := DecodeToBloomFilter(buffer)
+		if err != nil {
+			return
+		}
+		_ = filter.MayContain(kv.NewStringKeyWithTimestamp("consensus", 3))
+	})
+}
+
+func TestSizeInBytesOfBloomFilter(t *testing.T) {
+	builder := NewBloomFilterBuilder()
+	for count := 0; count < 1000; count++ {
+		builder.Add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%d", count), 5))
+	}
+	filter := builder.Build()
+
+	encoded, err := filter.Encode()
+	assert.NoError(t, err)
+	assert.True(t, filter.SizeInBytes() >= len(encoded)-encodedHeaderSize)
+}
@@ -0,0 +1,58 @@
+package objectstore
+
+import "context"
+
+// IOScheduler bounds the number of reads which are issued concurrently to the object store.
+// A read is run on its own goroutine once it acquires one of the permits, the permits are shared by all the reads
+// scheduled on the same IOScheduler (one IOScheduler per Db).
+type IOScheduler struct {
+	permits chan struct{}
+}
+
+// ScheduledRead is a read scheduled on the IOScheduler, Wait returns its result.
+type ScheduledRead[Result any] struct {
+	done   chan struct{}
+	result Result
+	err    error
+}
+
+// NewIOScheduler creates a new instance of IOScheduler which allows at most maxConcurrentReads concurrent reads.
+func NewIOScheduler(maxConcurrentReads uint) *IOScheduler {
+	if maxConcurrentReads == 0 {
+		panic("max concurrent reads must be greater than 0")
+	}
+	return &IOScheduler{
+		permits: make(chan struct{}, maxConcurrentReads),
+	}
+}
+
+// Schedule schedules the given read on the IOScheduler, the read runs concurrently with the caller once it acquires a permit.
+// The read receives the given context, which allows the caller to cancel the reads whose results are no longer needed.
+// If the context is cancelled before the read acquires a permit, the read is never run and its result is the context error.
+func Schedule[Result any](ctx context.Context, scheduler *IOScheduler, read func(ctx context.Context) (Result, error)) *ScheduledRead[Result] {
+	scheduledRead := &ScheduledRead[Result]{done: make(chan struct{})}
+	go func() {
+		defer close(scheduledRead.done)
+		select {
+		case scheduler.permits <- struct{}{}:
+		case <-ctx.Done():
+			scheduledRead.err = ctx.Err()
+			return
+		}
+		defer func() {
+			<-scheduler.permits
+		}()
+		if err := ctx.Err(); err != nil {
+			scheduledRead.err = err
+			return
+		}
+		scheduledRead.result, scheduledRead.err = read(ctx)
+	}()
+	return scheduledRead
+}
+
+// Wait waits until the ScheduledRead is done, and returns its result.
+func (scheduledRead *ScheduledRead[Result]) Wait() (Result, error) {
+	<-scheduledRead.done
+	return scheduledRead.result, scheduledRead.err
+}
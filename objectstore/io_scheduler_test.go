@@ -0,0 +1,96 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduleARead(t *testing.T) {
+	scheduler := NewIOScheduler(2)
+	scheduledRead := Schedule(context.Background(), scheduler, func(ctx context.Context) (string, error) {
+		return "raft", nil
+	})
+
+	result, err := scheduledRead.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", result)
+}
+
+func TestScheduleAReadWhichFails(t *testing.T) {
+	errRead := errors.New("read failed")
+	scheduledRead := Schedule(context.Background(), NewIOScheduler(2), func(ctx context.Context) (string, error) {
+		return "", errRead
+	})
+
+	_, err := scheduledRead.Wait()
+	assert.ErrorIs(t, err, errRead)
+}
+
+func TestScheduleReadsWithinTheConcurrencyLimit(t *testing.T) {
+	scheduler := NewIOScheduler(2)
+
+	var concurrentReads, maxObservedConcurrentReads atomic.Int32
+	var scheduledReads []*ScheduledRead[int]
+	for index := 0; index < 10; index++ {
+		scheduledReads = append(scheduledReads, Schedule(context.Background(), scheduler, func(ctx context.Context) (int, error) {
+			current := concurrentReads.Add(1)
+			for {
+				observed := maxObservedConcurrentReads.Load()
+				if current <= observed || maxObservedConcurrentReads.CompareAndSwap(observed, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			concurrentReads.Add(-1)
+			return index, nil
+		}))
+	}
+	for index, scheduledRead := range scheduledReads {
+		result, err := scheduledRead.Wait()
+		assert.NoError(t, err)
+		assert.Equal(t, index, result)
+	}
+	assert.True(t, maxObservedConcurrentReads.Load() <= 2)
+}
+
+func TestCancelAScheduledReadWaitingForAPermit(t *testing.T) {
+	scheduler := NewIOScheduler(1)
+
+	var blockingReadStarted sync.WaitGroup
+	blockingReadStarted.Add(1)
+	release := make(chan struct{})
+	blockingRead := Schedule(context.Background(), scheduler, func(ctx context.Context) (string, error) {
+		blockingReadStarted.Done()
+		<-release
+		return "raft", nil
+	})
+	blockingReadStarted.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran atomic.Bool
+	cancelledRead := Schedule(ctx, scheduler, func(ctx context.Context) (string, error) {
+		ran.Store(true)
+		return "paxos", nil
+	})
+	cancel()
+
+	_, err := cancelledRead.Wait()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, ran.Load())
+
+	close(release)
+	result, err := blockingRead.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", result)
+}
+
+func TestAttemptToCreateAnIOSchedulerWithZeroConcurrentReads(t *testing.T) {
+	assert.Panics(t, func() {
+		NewIOScheduler(0)
+	})
+}
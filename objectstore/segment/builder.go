@@ -2,6 +2,7 @@ package segment
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/objectstore"
@@ -9,16 +10,41 @@ import (
 	"github.com/SarthakMakhija/zero-store/objectstore/filter"
 )
 
+var ErrKeyTooLargeForBlock = errors.New("key does not fit in a block")
+
+// compressionDictionarySamplesMultiplier limits the total size of the sampled values to the given multiple of the
+// compression dictionary size, zstd recommends around 100 times the dictionary size for training.
+const compressionDictionarySamplesMultiplier = 100
+
 // SortedSegmentBuilder allows building persistent sorted segment in a step-by-step manner.
+// formatOptions are the format parameters which are used to build the persistent sorted segment, and are recorded in its footer block.
+// finishedBlocks are the encoded blocks which are compressed in build, after the block.CompressionDictionary (if any) is trained
+// from valueSamples.
+// compressionDictionary is the block.CompressionDictionary of the persistent sorted segment, it is available after build.
+// statistics are the block.Statistics of all the key/value pairs added to the builder.
+// err is the first error that occurred while adding the key/value pairs, it is returned from build.
 type SortedSegmentBuilder struct {
-	blockBuilder       *block.Builder
-	blockMetaList      *block.MetaList
-	bloomFilterBuilder *filter.BloomFilterBuilder
-	startingKey        kv.Key
-	endingKey          kv.Key
-	allBlocksData      []byte
-	blockSize          uint
-	store              objectstore.Store
+	blockBuilder            *block.Builder
+	blockMetaList           *block.MetaList
+	bloomFilterBuilder      *filter.BloomFilterBuilder
+	startingKey             kv.Key
+	endingKey               kv.Key
+	finishedBlocks          []finishedBlock
+	allBlocksData           []byte
+	valueSamples            [][]byte
+	valueSamplesSizeInBytes uint
+	compressionDictionary   *block.CompressionDictionary
+	statistics              block.Statistics
+	formatOptions           SortedSegmentFormatOptions
+	store                   objectstore.Store
+	err                     error
+}
+
+// finishedBlock is an encoded block (including its overflow bytes) along with its starting and ending key.
+type finishedBlock struct {
+	data        []byte
+	startingKey kv.Key
+	endingKey   kv.Key
 }
 
 // newSortedSegmentBuilderWithDefaultBlockSize creates a new instance of SortedSegmentBuilder with block.DefaultBlockSize.
@@ -29,11 +55,19 @@ func newSortedSegmentBuilderWithDefaultBlockSize(store objectstore.Store, enable
 // newSortedSegmentBuilder creates a new instance of SortedSegmentBuilder with the given block size.
 // The specified block size will be used to limit the size of each block that will be a part of the final sorted segment.
 func newSortedSegmentBuilder(store objectstore.Store, blockSize uint, enableCompression bool) *SortedSegmentBuilder {
+	return newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptions(blockSize, filter.DefaultFalsePositiveRate, enableCompression),
+	)
+}
+
+// newSortedSegmentBuilderWithFormatOptions creates a new instance of SortedSegmentBuilder with the given format options.
+func newSortedSegmentBuilderWithFormatOptions(store objectstore.Store, formatOptions SortedSegmentFormatOptions) *SortedSegmentBuilder {
 	return &SortedSegmentBuilder{
-		blockBuilder:       block.NewBlockBuilder(blockSize),
-		blockMetaList:      block.NewBlockMetaList(enableCompression),
-		bloomFilterBuilder: filter.NewBloomFilterBuilder(),
-		blockSize:          blockSize,
+		blockBuilder:       formatOptions.newBlockBuilder(),
+		blockMetaList:      block.NewBlockMetaList(formatOptions.enableCompression),
+		bloomFilterBuilder: filter.NewBloomFilterBuilderWithFalsePositiveRate(formatOptions.bloomFilterFalsePositiveRate),
+		formatOptions:      formatOptions,
 		store:              store,
 	}
 }
@@ -41,21 +75,46 @@ func newSortedSegmentBuilder(store objectstore.Store, blockSize uint, enableComp
 // add adds the key/value pair in the current block builder.
 // add involves:
 // 1) Keeping a track of the starting key and ending key of the current block.
-// 2) Adding the key to the filter.BloomFilter.
+// 2) Adding the key to the filter.BloomFilter, and the key/value pair to the block.Statistics.
 // 3) Adding the key/value pair to the current block.Builder.
 // 4) Finishing the current block, if it is full and starting a new block (or block.Builder).
+// 5) Adding the key/value pair as an overflowing key/value pair, if it does not fit even in an empty block.
+// An error (if any) is kept in the builder and returned from build.
 func (builder *SortedSegmentBuilder) add(key kv.Key, value kv.Value) {
-	if builder.startingKey.IsRawKeyEmpty() {
+	if builder.err != nil {
+		return
+	}
+	if builder.blockBuilder.IsEmpty() {
 		builder.startingKey = key
 	}
-	builder.endingKey = key
 	builder.bloomFilterBuilder.Add(key)
+	builder.statistics.Add(key, value)
+	builder.maybeSampleValue(value)
 	if builder.blockBuilder.Add(key, value) {
+		builder.endingKey = key
 		return
 	}
-	builder.finishBlock()
-	builder.startNewBlockBuilder(key)
-	builder.blockBuilder.Add(key, value)
+	if !builder.blockBuilder.IsEmpty() {
+		builder.finishBlock()
+		builder.startNewBlockBuilder(key)
+		if builder.blockBuilder.Add(key, value) {
+			return
+		}
+	}
+	builder.addOverflowing(key, value)
+}
+
+// addOverflowing adds the key/value pair which does not fit in an empty block.
+// The block containing the key/value pair is finished right away, and it is followed by the overflow bytes of the value.
+func (builder *SortedSegmentBuilder) addOverflowing(key kv.Key, value kv.Value) {
+	overflow, ok := builder.blockBuilder.AddOverflowing(key, value)
+	if !ok {
+		builder.err = fmt.Errorf("%w: key size %v bytes, block size %v bytes", ErrKeyTooLargeForBlock, key.EncodedSizeInBytes(), builder.formatOptions.blockSize)
+		return
+	}
+	builder.endingKey = key
+	builder.finishBlockWithOverflow(overflow)
+	builder.blockBuilder = builder.formatOptions.newBlockBuilder()
 }
 
 // build builds the SortedSegment using the given segment id.
@@ -65,36 +124,62 @@ func (builder *SortedSegmentBuilder) add(key kv.Key, value kv.Value) {
 // 3) Creating an instance of SortedSegment.
 // The encoding of the SortedSegment looks like:
 /**
-  ----------------------------------------------------------------------------------------------------------------------------------------------------------------------------
-| data block | data block |...| data block | metadata section |  bloom filter section | footer block 																		   |
-|										   |				  |			              | blockMetaBeginOffset, blockMetaEndOffset, bloomFilterBeginOffset, bloomFilterEndOffset |
- -----------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+  ----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+| data block | data block |...| data block | metadata section |  bloom filter section | checksums section | footer block                                                                           | trailer                        |
+|										   |				  |			              |                   | blockMetaBeginOffset, blockMetaEndOffset, bloomFilterBeginOffset, bloomFilterEndOffset | footer length, flags, version, |
+|										   |				  |			              |                   | checksumsBeginOffset, checksumsEndOffset, block size, bloom filter false positive rate | magic                          |
+ ----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
 */
 //
 // The size of the data blocks is fixed, defaults to block.DefaultBlockSize.
+// A data block containing a key/value pair larger than the block size is followed by the overflow bytes of its value.
+// If compression is enabled, each data block (along with its overflow bytes) is compressed using the compression codec of
+// SortedSegmentFormatOptions, the codec and the uncompressed size of each data block are recorded in its block.Meta.
+// If a block.CompressionDictionary is trained, all the data blocks are compressed using the dictionary, and the dictionary
+// is stored in its own section (followed by its CRC32C checksum), whose offsets are recorded in the footer block.
+// The offsets of the dictionary section are recorded even if there is no dictionary, the section is empty in that case.
+// Statistics section (block.Statistics) follows the dictionary section, it contains the entry count, the tombstone count,
+// the timestamp range, the sizes and the key/value size histograms. It carries its own CRC32C checksum, and its offsets
+// are recorded in the footer block.
 // Metadata and bloom filter are variable length byte sections.
-// Footer block is a fixed size block, defaults to block.DefaultBlockSize.
+// Footer block is a variable length byte section (please check block.FooterBlock.EncodeCompact), which records the offsets
+// and the format parameters (SortedSegmentFormatOptions).
+// Checksums section (block.Checksums) contains the CRC32C checksums of the metadata, the bloom filter and each data block.
+// Footer block also records the offsets of the checksums section, and it is followed by its own CRC32C checksum.
+// Trailer is a fixed size section (block.Trailer), it is always the last block.TrailerSize bytes of the segment.
+// The segments are written in block.CurrentFormatVersion, please check load for reading the segments of older format versions.
 func (builder *SortedSegmentBuilder) build(id uint64) (SortedSegment, *block.MetaList, filter.BloomFilter, error) {
-	blockMetaBeginOffset := func() uint32 {
-		return uint32(len(builder.allBlocksData))
+	blockMetaBeginOffset := func() uint64 {
+		return uint64(len(builder.allBlocksData))
 	}
-	blockMetaEndOffset := func(buffer *bytes.Buffer) uint32 {
-		return uint32(buffer.Len())
+	blockMetaEndOffset := func(buffer *bytes.Buffer) uint64 {
+		return uint64(buffer.Len())
 	}
-	bloomFilterBeginOffset := func(buffer *bytes.Buffer) uint32 {
-		return uint32(buffer.Len())
+	bloomFilterBeginOffset := func(buffer *bytes.Buffer) uint64 {
+		return uint64(buffer.Len())
 	}
-	bloomFilterEndOffset := func(buffer *bytes.Buffer) uint32 {
-		return uint32(buffer.Len())
+	bloomFilterEndOffset := func(buffer *bytes.Buffer) uint64 {
+		return uint64(buffer.Len())
 	}
 
-	builder.finishBlock()
+	if builder.err != nil {
+		return EmptySortedSegment, nil, filter.BloomFilter{}, builder.err
+	}
+	if !builder.blockBuilder.IsEmpty() {
+		builder.finishBlock()
+	}
+	builder.maybeTrainCompressionDictionary()
+	if err := builder.compressBlocks(); err != nil {
+		return EmptySortedSegment, nil, filter.BloomFilter{}, err
+	}
 
 	buffer := new(bytes.Buffer)
-	footerBlock := block.NewFooterBlock(builder.blockSize)
+	footerBlock := block.NewFooterBlock(builder.formatOptions.blockSize)
+	footerBlock.SetFormatParameters(builder.formatOptions.bloomFilterFalsePositiveRate, builder.formatOptions.enableCompression)
 
+	encodedBlockMetaList := builder.blockMetaList.Encode()
 	buffer.Write(builder.allBlocksData)
-	buffer.Write(builder.blockMetaList.Encode())
+	buffer.Write(encodedBlockMetaList)
 
 	footerBlock.AddOffset(blockMetaBeginOffset())
 	footerBlock.AddOffset(blockMetaEndOffset(buffer))
@@ -106,9 +191,40 @@ func (builder *SortedSegmentBuilder) build(id uint64) (SortedSegment, *block.Met
 	}
 	footerBlock.AddOffset(bloomFilterBeginOffset(buffer))
 	buffer.Write(encodedFilter)
-
 	footerBlock.AddOffset(bloomFilterEndOffset(buffer))
-	buffer.Write(footerBlock.Encode())
+
+	checksums := &block.Checksums{
+		BlockMetaList: block.Checksum(encodedBlockMetaList),
+		BloomFilter:   block.Checksum(encodedFilter),
+		Blocks:        builder.blockChecksums(),
+	}
+	footerBlock.AddOffset(uint64(buffer.Len()))
+	buffer.Write(checksums.Encode())
+	footerBlock.AddOffset(uint64(buffer.Len()))
+
+	footerBlock.AddOffset(uint64(buffer.Len()))
+	if builder.compressionDictionary != nil {
+		buffer.Write(block.AppendChecksum(builder.compressionDictionary.Encode()))
+	}
+	footerBlock.AddOffset(uint64(buffer.Len()))
+
+	statistics := builder.statistics
+	statistics.CompressedSizeInBytes = uint64(len(builder.allBlocksData))
+	footerBlock.AddOffset(uint64(buffer.Len()))
+	buffer.Write(statistics.Encode())
+	footerBlock.AddOffset(uint64(buffer.Len()))
+
+	encodedFooterBlock := block.AppendChecksum(footerBlock.EncodeCompact())
+	buffer.Write(encodedFooterBlock)
+
+	flags := block.TrailerFlagChecksums | block.TrailerFlagBlockCompressionCodecs | block.TrailerFlagStatistics
+	if builder.formatOptions.enableCompression {
+		flags |= block.TrailerFlagBlockMetaListCompressed
+	}
+	if builder.compressionDictionary != nil {
+		flags |= block.TrailerFlagCompressionDictionary
+	}
+	buffer.Write(block.NewTrailer(uint32(len(encodedFooterBlock)), flags).Encode())
 
 	// write the result to the object store.
 	if err := builder.store.Set(PathSuffixForSegment(id), buffer.Bytes()); err != nil {
@@ -117,14 +233,20 @@ func (builder *SortedSegmentBuilder) build(id uint64) (SortedSegment, *block.Met
 	startingKey, _ := builder.blockMetaList.StartingKeyOfFirstBlock()
 	endingKey, _ := builder.blockMetaList.EndingKeyOfLastBlock()
 	return SortedSegment{
-		id:                   id,
-		blockMetaBeginOffset: uint32(len(builder.allBlocksData)),
-		blockSize:            builder.blockSize,
-		startingKey:          startingKey,
-		endingKey:            endingKey,
-		store:                builder.store,
-		numberOfBlocks:       builder.blockMetaList.Length(),
-		footerBlock:          footerBlock,
+		id:                       id,
+		blockMetaBeginOffset:     uint64(len(builder.allBlocksData)),
+		formatVersion:            block.CurrentFormatVersion,
+		formatOptions:            builder.formatOptions,
+		startingKey:              startingKey,
+		endingKey:                endingKey,
+		store:                    builder.store,
+		numberOfBlocks:           builder.blockMetaList.Length(),
+		footerBlock:              footerBlock,
+		checksums:                checksums,
+		withCompressionCodecs:    true,
+		hasCompressionDictionary: builder.compressionDictionary != nil,
+		compressionStats:         compressionStatsOf(builder.blockMetaList, uint64(len(builder.allBlocksData))),
+		statistics:               &statistics,
 	}, builder.blockMetaList, bloomFilter, nil
 }
 
@@ -133,23 +255,97 @@ func PathSuffixForSegment(id uint64) string {
 	return fmt.Sprintf("%v.segment", id)
 }
 
-// finishBlock finishes the current block. It involves:
-// 1) Encoding the current block.
-// 2) Storing the block.Meta in the block meta-list.
-// 3) Collecting the encoded data of the current block in allBlocksData.
+// finishBlock finishes the current block which does not have overflow bytes.
 func (builder *SortedSegmentBuilder) finishBlock() {
-	encodedBlock := builder.blockBuilder.Build().Encode()
-	builder.blockMetaList.Add(block.Meta{
-		BlockBeginOffset: uint32(len(builder.allBlocksData)),
-		StartingKey:      builder.startingKey,
-		EndingKey:        builder.endingKey,
+	builder.finishBlockWithOverflow(nil)
+}
+
+// finishBlockWithOverflow finishes the current block followed by the given overflow bytes.
+// The encoded block is compressed in build, please check compressBlocks.
+func (builder *SortedSegmentBuilder) finishBlockWithOverflow(overflow []byte) {
+	builder.finishedBlocks = append(builder.finishedBlocks, finishedBlock{
+		data:        append(builder.blockBuilder.Build().Encode(), overflow...),
+		startingKey: builder.startingKey,
+		endingKey:   builder.endingKey,
 	})
-	builder.allBlocksData = append(builder.allBlocksData, encodedBlock...)
+}
+
+// compressBlocks compresses all the finished blocks. It involves:
+// 1) Compressing each block along with its overflow bytes, using the block.CompressionDictionary (if any) or the compression
+// codec of SortedSegmentFormatOptions.
+// 2) Storing the block.Meta (including the compression codec) in the block meta-list.
+// 3) Collecting the (compressed) data of each block in allBlocksData.
+func (builder *SortedSegmentBuilder) compressBlocks() error {
+	for _, finishedBlock := range builder.finishedBlocks {
+		var compressed []byte
+		var compressionCodec block.CompressionCodec
+
+		if builder.compressionDictionary != nil {
+			compressed, compressionCodec = block.CompressWithDictionary(builder.compressionDictionary, finishedBlock.data)
+		} else {
+			var err error
+			compressed, compressionCodec, err = block.Compress(builder.formatOptions.compressionCodec, finishedBlock.data)
+			if err != nil {
+				return err
+			}
+		}
+		builder.blockMetaList.Add(block.Meta{
+			BlockBeginOffset: uint64(len(builder.allBlocksData)),
+			StartingKey:      finishedBlock.startingKey,
+			EndingKey:        finishedBlock.endingKey,
+			CompressionCodec: compressionCodec,
+			UncompressedSize: uint32(len(finishedBlock.data)),
+		})
+		builder.allBlocksData = append(builder.allBlocksData, compressed...)
+	}
+	builder.finishedBlocks = nil
+	return nil
+}
+
+// maybeSampleValue samples the given value for training the block.CompressionDictionary, until the total size of the
+// sampled values reaches compressionDictionarySamplesMultiplier times the dictionary size.
+func (builder *SortedSegmentBuilder) maybeSampleValue(value kv.Value) {
+	if !builder.formatOptions.shouldTrainCompressionDictionary() || value.IsDeleted() {
+		return
+	}
+	if builder.valueSamplesSizeInBytes >= builder.formatOptions.compressionDictionarySizeInBytes*compressionDictionarySamplesMultiplier {
+		return
+	}
+	sample := append([]byte{}, value.Bytes()...)
+	builder.valueSamples = append(builder.valueSamples, sample)
+	builder.valueSamplesSizeInBytes += uint(len(sample))
+}
+
+// maybeTrainCompressionDictionary trains the block.CompressionDictionary from the sampled values.
+// The data blocks are compressed without the dictionary, if the sampled values are not sufficient to train a dictionary.
+func (builder *SortedSegmentBuilder) maybeTrainCompressionDictionary() {
+	if !builder.formatOptions.shouldTrainCompressionDictionary() || len(builder.valueSamples) == 0 {
+		return
+	}
+	compressionDictionary, err := block.TrainCompressionDictionary(builder.valueSamples, builder.formatOptions.compressionDictionarySizeInBytes)
+	if err == nil {
+		builder.compressionDictionary = compressionDictionary
+	}
+	builder.valueSamples = nil
+}
+
+// blockChecksums returns the checksums of all the data blocks, the checksum of a data block includes its overflow bytes.
+func (builder *SortedSegmentBuilder) blockChecksums() []uint32 {
+	checksums := make([]uint32, 0, builder.blockMetaList.Length())
+	for blockIndex := 0; blockIndex < builder.blockMetaList.Length(); blockIndex++ {
+		blockMeta, _ := builder.blockMetaList.GetAt(blockIndex)
+		endOffset := uint64(len(builder.allBlocksData))
+		if nextBlockMeta, ok := builder.blockMetaList.GetAt(blockIndex + 1); ok {
+			endOffset = nextBlockMeta.BlockBeginOffset
+		}
+		checksums = append(checksums, block.Checksum(builder.allBlocksData[blockMeta.BlockBeginOffset:endOffset]))
+	}
+	return checksums
 }
 
 // startNewBlockBuilder creates a new instance of block.Builder.
 func (builder *SortedSegmentBuilder) startNewBlockBuilder(key kv.Key) {
-	builder.blockBuilder = block.NewBlockBuilder(builder.blockSize)
+	builder.blockBuilder = builder.formatOptions.newBlockBuilder()
 	builder.startingKey = key
 	builder.endingKey = key
 }
@@ -7,15 +7,21 @@ import (
 )
 
 type SortedSegmentCacheOptions struct {
-	bloomFilterCacheOptions   cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter]
-	blockMetaListCacheOptions cache.ComparableKeyCacheOptions[uint64, *block.MetaList]
+	bloomFilterCacheOptions           cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter]
+	blockMetaListCacheOptions         cache.ComparableKeyCacheOptions[uint64, *block.MetaList]
+	compressionDictionaryCacheOptions cache.ComparableKeyCacheOptions[uint64, *block.CompressionDictionary]
+	blockCacheOptions                 cache.ComparableKeyCacheOptions[cache.BlockCacheKey, block.Block]
 }
 
 func NewSortedSegmentCacheOptions(
 	bloomFilterCacheOptions cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter],
-	blockMetaListCacheOptions cache.ComparableKeyCacheOptions[uint64, *block.MetaList]) SortedSegmentCacheOptions {
+	blockMetaListCacheOptions cache.ComparableKeyCacheOptions[uint64, *block.MetaList],
+	compressionDictionaryCacheOptions cache.ComparableKeyCacheOptions[uint64, *block.CompressionDictionary],
+	blockCacheOptions cache.ComparableKeyCacheOptions[cache.BlockCacheKey, block.Block]) SortedSegmentCacheOptions {
 	return SortedSegmentCacheOptions{
-		bloomFilterCacheOptions:   bloomFilterCacheOptions,
-		blockMetaListCacheOptions: blockMetaListCacheOptions,
+		bloomFilterCacheOptions:           bloomFilterCacheOptions,
+		blockMetaListCacheOptions:         blockMetaListCacheOptions,
+		compressionDictionaryCacheOptions: compressionDictionaryCacheOptions,
+		blockCacheOptions:                 blockCacheOptions,
 	}
 }
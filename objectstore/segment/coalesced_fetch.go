@@ -0,0 +1,45 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// coalescedFetch runs the given fetch once for all the concurrent callers with the same key, and returns its result to
+// all of them.
+// The fetch runs with the context of the caller which starts it. If that context is cancelled, the other callers (whose
+// contexts are not cancelled) retry the fetch instead of failing with the cancellation of another caller.
+// A caller stops waiting as soon as its own context is cancelled. The fetch is not coalesced if the group is nil.
+func coalescedFetch[Result any](
+	ctx context.Context,
+	group *singleflight.Group,
+	key string,
+	fetch func(ctx context.Context) (Result, error),
+) (Result, error) {
+	if group == nil {
+		return fetch(ctx)
+	}
+	var zero Result
+	for {
+		resultChannel := group.DoChan(key, func() (any, error) {
+			return fetch(ctx)
+		})
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case result := <-resultChannel:
+			if result.Err != nil {
+				if isCancellation(result.Err) && ctx.Err() == nil {
+					continue
+				}
+				return zero, result.Err
+			}
+			return result.Val.(Result), nil
+		}
+	}
+}
+
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,86 @@
+package segment
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/singleflight"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalescedFetchRunsTheFetchOnceForConcurrentCallers(t *testing.T) {
+	group := &singleflight.Group{}
+	release := make(chan struct{})
+	var numberOfFetches atomic.Int32
+
+	fetch := func(ctx context.Context) (string, error) {
+		numberOfFetches.Add(1)
+		<-release
+		return "raft", nil
+	}
+
+	var waitGroup sync.WaitGroup
+	results := make([]string, 8)
+	for index := range results {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			result, err := coalescedFetch(context.Background(), group, "block/1/0", fetch)
+			assert.NoError(t, err)
+			results[index] = result
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	waitGroup.Wait()
+
+	assert.Equal(t, int32(1), numberOfFetches.Load())
+	for _, result := range results {
+		assert.Equal(t, "raft", result)
+	}
+}
+
+func TestCoalescedFetchRetriesWhenTheContextOfTheFirstCallerIsCancelled(t *testing.T) {
+	group := &singleflight.Group{}
+	started := make(chan struct{})
+	var numberOfFetches atomic.Int32
+
+	fetch := func(ctx context.Context) (string, error) {
+		if numberOfFetches.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "raft", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstCallerDone := make(chan error)
+	go func() {
+		_, err := coalescedFetch(ctx, group, "block/1/0", fetch)
+		firstCallerDone <- err
+	}()
+	<-started
+
+	secondCallerDone := make(chan string)
+	go func() {
+		result, err := coalescedFetch(context.Background(), group, "block/1/0", fetch)
+		assert.NoError(t, err)
+		secondCallerDone <- result
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-firstCallerDone, context.Canceled)
+	assert.Equal(t, "raft", <-secondCallerDone)
+}
+
+func TestCoalescedFetchWithoutAGroup(t *testing.T) {
+	result, err := coalescedFetch(context.Background(), nil, "block/1/0", func(ctx context.Context) (string, error) {
+		return "raft", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", result)
+}
@@ -0,0 +1,48 @@
+package segment
+
+import "github.com/SarthakMakhija/zero-store/objectstore/block"
+
+// CompressionStats are the compression statistics of the data blocks of a persistent sorted segment.
+// UncompressedSizeInBytes is the size of the data blocks (including their overflow bytes) before compression, and
+// StoredSizeInBytes is the size of the data blocks in the persistent sorted segment.
+type CompressionStats struct {
+	NumberOfBlocks           int
+	NumberOfCompressedBlocks int
+	UncompressedSizeInBytes  uint64
+	StoredSizeInBytes        uint64
+}
+
+// Ratio returns the compression ratio, which is the uncompressed size divided by the stored size.
+// It returns 1, if the persistent sorted segment does not contain any data block.
+func (stats CompressionStats) Ratio() float64 {
+	if stats.StoredSizeInBytes == 0 {
+		return 1
+	}
+	return float64(stats.UncompressedSizeInBytes) / float64(stats.StoredSizeInBytes)
+}
+
+// compressionStatsOf returns the CompressionStats of the data blocks described by the given block meta list.
+// blockMetaBeginOffset is the offset where the data blocks end.
+// The blocks of the persistent sorted segments written before the blocks were compressed do not record their uncompressed
+// size, their stored size is their uncompressed size.
+func compressionStatsOf(blockMetaList *block.MetaList, blockMetaBeginOffset uint64) CompressionStats {
+	stats := CompressionStats{NumberOfBlocks: blockMetaList.Length()}
+	for blockIndex := 0; blockIndex < blockMetaList.Length(); blockIndex++ {
+		blockMeta, _ := blockMetaList.GetAt(blockIndex)
+		endOffset := blockMetaBeginOffset
+		if nextBlockMeta, ok := blockMetaList.GetAt(blockIndex + 1); ok {
+			endOffset = nextBlockMeta.BlockBeginOffset
+		}
+		storedSize := uint64(endOffset - blockMeta.BlockBeginOffset)
+		stats.StoredSizeInBytes += storedSize
+		if blockMeta.CompressionCodec != block.CompressionCodecNone {
+			stats.NumberOfCompressedBlocks++
+		}
+		if blockMeta.UncompressedSize == 0 {
+			stats.UncompressedSizeInBytes += storedSize
+			continue
+		}
+		stats.UncompressedSizeInBytes += uint64(blockMeta.UncompressedSize)
+	}
+	return stats
+}
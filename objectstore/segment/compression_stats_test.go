@@ -0,0 +1,15 @@
+package segment
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompressionRatio(t *testing.T) {
+	stats := CompressionStats{UncompressedSizeInBytes: 8192, StoredSizeInBytes: 2048}
+	assert.Equal(t, float64(4), stats.Ratio())
+}
+
+func TestCompressionRatioWithoutBlocks(t *testing.T) {
+	assert.Equal(t, float64(1), CompressionStats{}.Ratio())
+}
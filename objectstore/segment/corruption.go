@@ -0,0 +1,44 @@
+package segment
+
+import "fmt"
+
+const (
+	SectionDataBlock             = "data block"
+	SectionBlockMetaList         = "block meta list"
+	SectionBloomFilter           = "bloom filter"
+	SectionChecksums             = "checksums"
+	SectionFooterBlock           = "footer block"
+	SectionCompressionDictionary = "compression dictionary"
+	SectionStatistics            = "statistics"
+)
+
+// ErrCorruption is returned when a section of the persistent sorted segment fails its checksum verification.
+// BlockIndex is the index of the data block, it is -1 for the sections other than the data block.
+type ErrCorruption struct {
+	SegmentId  uint64
+	BlockIndex int
+	Section    string
+}
+
+func newBlockCorruptionError(segmentId uint64, blockIndex int) *ErrCorruption {
+	return &ErrCorruption{
+		SegmentId:  segmentId,
+		BlockIndex: blockIndex,
+		Section:    SectionDataBlock,
+	}
+}
+
+func newSectionCorruptionError(segmentId uint64, section string) *ErrCorruption {
+	return &ErrCorruption{
+		SegmentId:  segmentId,
+		BlockIndex: -1,
+		Section:    section,
+	}
+}
+
+func (err *ErrCorruption) Error() string {
+	if err.BlockIndex >= 0 {
+		return fmt.Sprintf("corrupted %v %v in segment %v", err.Section, err.BlockIndex, err.SegmentId)
+	}
+	return fmt.Sprintf("corrupted %v in segment %v", err.Section, err.SegmentId)
+}
@@ -0,0 +1,194 @@
+package segment
+
+import (
+	"errors"
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/SarthakMakhija/zero-store/objectstore"
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestReadACorruptedBlockOfSortedSegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("distributed", 10), kv.NewStringValue("etcd"))
+
+	segment, blockMetaList, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, segment.noOfBlocks())
+
+	secondBlockMeta, _ := blockMetaList.GetAt(1)
+	testFlipByteAt(t, segmentId, int(secondBlockMeta.BlockBeginOffset)+2)
+
+	_, err = segment.readBlock(0, blockMetaList)
+	assert.NoError(t, err)
+
+	_, err = segment.readBlock(1, blockMetaList)
+	var corruption *ErrCorruption
+	assert.True(t, errors.As(err, &corruption))
+	assert.Equal(t, segmentId, corruption.SegmentId)
+	assert.Equal(t, 1, corruption.BlockIndex)
+	assert.Equal(t, SectionDataBlock, corruption.Section)
+}
+
+func TestLoadASortedSegmentWithACorruptedBlockMetaList(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	segment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	testFlipByteAt(t, segmentId, int(segment.blockMetaBeginOffset)+1)
+
+	_, _, _, err = load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	var corruption *ErrCorruption
+	assert.True(t, errors.As(err, &corruption))
+	assert.Equal(t, -1, corruption.BlockIndex)
+	assert.Equal(t, SectionBlockMetaList, corruption.Section)
+}
+
+func TestLoadASortedSegmentWithACorruptedBloomFilter(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	segment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	bloomFilterBeginOffset, _ := segment.footerBlock.GetOffsetAt(2)
+	testFlipByteAt(t, segmentId, int(bloomFilterBeginOffset)+1)
+
+	_, _, _, err = load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	var corruption *ErrCorruption
+	assert.True(t, errors.As(err, &corruption))
+	assert.Equal(t, SectionBloomFilter, corruption.Section)
+}
+
+func TestLoadASortedSegmentWithACorruptedFooterBlock(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	segment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	statisticsEndOffset, _ := segment.footerBlock.GetOffsetAt(9)
+	testFlipByteAt(t, segmentId, int(statisticsEndOffset)+2)
+
+	_, _, _, err = load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	var corruption *ErrCorruption
+	assert.True(t, errors.As(err, &corruption))
+	assert.Equal(t, SectionFooterBlock, corruption.Section)
+}
+
+func TestLoadASortedSegmentWithCorruptedStatistics(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	segment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	statisticsBeginOffset, _ := segment.footerBlock.GetOffsetAt(8)
+	testFlipByteAt(t, segmentId, int(statisticsBeginOffset))
+
+	_, _, _, err = load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	var corruption *ErrCorruption
+	assert.True(t, errors.As(err, &corruption))
+	assert.Equal(t, SectionStatistics, corruption.Section)
+}
+
+func TestReadABlockOfSortedSegmentWithACorruptedCompressionDictionary(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionDictionary(block.DefaultBlockSize, 0.01, 1024),
+	)
+	for _, keyValue := range testJsonKeyValues(100) {
+		segmentBuilder.add(keyValue.key, keyValue.value)
+	}
+	segment, blockMetaList, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	assert.True(t, segment.hasCompressionDictionary)
+
+	compressionDictionaryBeginOffset, _ := segment.footerBlock.GetOffsetAt(6)
+	testFlipByteAt(t, segmentId, int(compressionDictionaryBeginOffset)+1)
+
+	_, err = segment.readBlock(0, blockMetaList)
+	var corruption *ErrCorruption
+	assert.True(t, errors.As(err, &corruption))
+	assert.Equal(t, -1, corruption.BlockIndex)
+	assert.Equal(t, SectionCompressionDictionary, corruption.Section)
+}
+
+func testFlipByteAt(t *testing.T, segmentId uint64, offset int) {
+	segmentBytes, err := os.ReadFile(PathSuffixForSegment(segmentId))
+	assert.NoError(t, err)
+
+	segmentBytes[offset] ^= 0xFF
+	assert.NoError(t, os.WriteFile(PathSuffixForSegment(segmentId), segmentBytes, 0644))
+}
@@ -0,0 +1,102 @@
+package segment
+
+import (
+	"fmt"
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+	"github.com/SarthakMakhija/zero-store/objectstore/filter"
+)
+
+// SortedSegmentFormatOptions are the format parameters used to write a persistent sorted segment.
+// The format parameters are recorded in the block.FooterBlock of each persistent sorted segment, so the readers never
+// have to guess them.
+// The format parameters are used for reading a persistent sorted segment only if its footer does not contain them
+// (segments written before the format parameters were recorded).
+// If compression is enabled, the block meta list is compressed using s2, and the data blocks are compressed using
+// compressionCodec. compressionCodec is only used for writing, the codec of each data block is recorded in its block.Meta.
+// A non-zero compressionDictionarySizeInBytes trains a zstd dictionary (block.CompressionDictionary) from the sampled
+// values of each persistent sorted segment, it is used for compressing all the data blocks of that segment.
+// If block hash index is enabled, each data block contains a hash index for point lookups. The hash index is recorded in
+// each data block, so it is only used for writing.
+type SortedSegmentFormatOptions struct {
+	blockSize                        uint
+	bloomFilterFalsePositiveRate     float64
+	enableCompression                bool
+	compressionCodec                 block.CompressionCodec
+	compressionDictionarySizeInBytes uint
+	enableBlockHashIndex             bool
+}
+
+// NewSortedSegmentFormatOptions creates SortedSegmentFormatOptions, the data blocks are compressed using
+// block.CompressionCodecS2 if compression is enabled.
+func NewSortedSegmentFormatOptions(blockSize uint, bloomFilterFalsePositiveRate float64, enableCompression bool) SortedSegmentFormatOptions {
+	compressionCodec := block.CompressionCodecNone
+	if enableCompression {
+		compressionCodec = block.CompressionCodecS2
+	}
+	return NewSortedSegmentFormatOptionsWithCompressionCodec(blockSize, bloomFilterFalsePositiveRate, compressionCodec)
+}
+
+// NewSortedSegmentFormatOptionsWithCompressionCodec creates SortedSegmentFormatOptions, the data blocks are compressed
+// using the given compressionCodec. Compression is disabled if the compressionCodec is block.CompressionCodecNone.
+func NewSortedSegmentFormatOptionsWithCompressionCodec(
+	blockSize uint,
+	bloomFilterFalsePositiveRate float64,
+	compressionCodec block.CompressionCodec,
+) SortedSegmentFormatOptions {
+	return SortedSegmentFormatOptions{
+		blockSize:                    blockSize,
+		bloomFilterFalsePositiveRate: bloomFilterFalsePositiveRate,
+		enableCompression:            compressionCodec != block.CompressionCodecNone,
+		compressionCodec:             compressionCodec,
+	}
+}
+
+// DefaultSortedSegmentFormatOptions returns SortedSegmentFormatOptions with block.DefaultBlockSize,
+// filter.DefaultFalsePositiveRate and without compression.
+func DefaultSortedSegmentFormatOptions() SortedSegmentFormatOptions {
+	return NewSortedSegmentFormatOptions(block.DefaultBlockSize, filter.DefaultFalsePositiveRate, false)
+}
+
+// NewSortedSegmentFormatOptionsWithCompressionDictionary creates SortedSegmentFormatOptions, the data blocks are compressed
+// using a zstd dictionary of at most compressionDictionarySizeInBytes, trained from the sampled values of each persistent
+// sorted segment. The data blocks are compressed using block.CompressionCodecZstd, if the sampled values are not
+// sufficient to train a dictionary.
+func NewSortedSegmentFormatOptionsWithCompressionDictionary(
+	blockSize uint,
+	bloomFilterFalsePositiveRate float64,
+	compressionDictionarySizeInBytes uint,
+) SortedSegmentFormatOptions {
+	formatOptions := NewSortedSegmentFormatOptionsWithCompressionCodec(blockSize, bloomFilterFalsePositiveRate, block.CompressionCodecZstd)
+	formatOptions.compressionDictionarySizeInBytes = compressionDictionarySizeInBytes
+	return formatOptions
+}
+
+// shouldTrainCompressionDictionary returns true if a block.CompressionDictionary needs to be trained for each persistent sorted segment.
+func (formatOptions SortedSegmentFormatOptions) shouldTrainCompressionDictionary() bool {
+	return formatOptions.enableCompression && formatOptions.compressionDictionarySizeInBytes > 0
+}
+
+// WithBlockHashIndex returns a copy of SortedSegmentFormatOptions which appends a hash index to each data block.
+func (formatOptions SortedSegmentFormatOptions) WithBlockHashIndex() SortedSegmentFormatOptions {
+	formatOptions.enableBlockHashIndex = true
+	return formatOptions
+}
+
+// newBlockBuilder creates a new instance of block.Builder, with the hash index if block hash index is enabled.
+func (formatOptions SortedSegmentFormatOptions) newBlockBuilder() *block.Builder {
+	if formatOptions.enableBlockHashIndex {
+		return block.NewBlockBuilderWithHashIndex(formatOptions.blockSize)
+	}
+	return block.NewBlockBuilder(formatOptions.blockSize)
+}
+
+// ValidateKeySize returns ErrKeyTooLargeForBlock if the key (along with at-least one byte of the given value) does not fit
+// in an empty data block. Such a key/value pair can not be written to a persistent sorted segment, please check
+// SortedSegmentBuilder.
+func (formatOptions SortedSegmentFormatOptions) ValidateKeySize(key kv.Key, value kv.Value) error {
+	if !block.CanFitKeyInAnEmptyBlock(formatOptions.blockSize, formatOptions.enableBlockHashIndex, key, value.SizeAsUint32()) {
+		return fmt.Errorf("%w: key size %v bytes, block size %v bytes", ErrKeyTooLargeForBlock, key.EncodedSizeInBytes(), formatOptions.blockSize)
+	}
+	return nil
+}
@@ -9,12 +9,15 @@ import (
 // A SortedSegment consists of multiple data blocks, so blockIndex maintains the current block which is
 // being iterated over.
 // blockIterator is a pointer to the block.Iterator.
+// readahead reads the blocks following the current block in the background once the Iterator starts moving forward, it is
+// nil until the Iterator moves forward, and it stays nil if the readahead is disabled, please check ReadaheadOptions.
 // Effectively, a SortedSegment Iterator is an iterator which iterates over the blocks of SortedSegment.
 type Iterator struct {
 	sortedSegment SortedSegment
 	blockIndex    int
 	blockIterator *block.Iterator
 	blockMetaList *block.MetaList
+	readahead     *readahead
 }
 
 // Key returns the kv.Key from block.Iterator.
@@ -22,11 +25,18 @@ func (iterator *Iterator) Key() kv.Key {
 	return iterator.blockIterator.Key()
 }
 
-// Value returns the kv.Value from block.Iterator.
+// Value returns the raw kv.Value from block.Iterator.
+// A value separated into a value log is returned as an encoded valuelog.Pointer (kv.Value.IsValuePointer is true), please
+// use ResolvedValue to get the value itself.
 func (iterator *Iterator) Value() kv.Value {
 	return iterator.blockIterator.Value()
 }
 
+// ResolvedValue returns the kv.Value from block.Iterator, a separated value is read from its value log.
+func (iterator *Iterator) ResolvedValue() (kv.Value, error) {
+	return resolveValue(iterator.sortedSegment.valueLogs, iterator.Value())
+}
+
 // IsValid returns true of the block.Iterator is valid.
 func (iterator *Iterator) IsValid() bool {
 	return iterator.blockIterator.IsValid()
@@ -34,14 +44,21 @@ func (iterator *Iterator) IsValid() bool {
 
 // Next advance the block.Iterator to the next key/value within the current block, or
 // move to the next block, if such a block exists.
+// The readahead (if enabled) is created when the Iterator moves forward for the first time.
 func (iterator *Iterator) Next() error {
+	if iterator.readahead == nil {
+		iterator.readahead = newReadahead(iterator.sortedSegment, iterator.blockMetaList, iterator.blockIndex)
+	}
+	if iterator.readahead != nil {
+		iterator.readahead.prefetchAfter(iterator.blockIndex)
+	}
 	if err := iterator.blockIterator.Next(); err != nil {
 		return err
 	}
 	if !iterator.blockIterator.IsValid() {
 		iterator.blockIndex += 1
 		if iterator.blockIndex < iterator.sortedSegment.noOfBlocks() {
-			readBlock, err := iterator.sortedSegment.readBlock(iterator.blockIndex, iterator.blockMetaList)
+			readBlock, err := iterator.readBlock(iterator.blockIndex)
 			if err != nil {
 				return err
 			}
@@ -51,5 +68,17 @@ func (iterator *Iterator) Next() error {
 	return nil
 }
 
-// Close does nothing.
-func (iterator *Iterator) Close() {}
+// Close abandons the reads of the readahead, if any.
+func (iterator *Iterator) Close() {
+	if iterator.readahead != nil {
+		iterator.readahead.close()
+	}
+}
+
+// readBlock reads the block at the given blockIndex, through the readahead if it is enabled.
+func (iterator *Iterator) readBlock(blockIndex int) (block.Block, error) {
+	if iterator.readahead != nil {
+		return iterator.readahead.blockAt(blockIndex)
+	}
+	return iterator.sortedSegment.readBlock(blockIndex, iterator.blockMetaList)
+}
@@ -1,11 +1,16 @@
 package segment
 
 import (
+	"context"
+	"fmt"
+	"github.com/SarthakMakhija/zero-store/cache"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/objectstore"
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestIterateOverASortedSegmentWithASingleBlockContainingSingleKeyValue(t *testing.T) {
@@ -289,3 +294,268 @@ func TestIterateOverASortedSegmentWithTwoBlocksUsingSeekToKeyWithTheKeyLessThanT
 	_ = iterator.Next()
 	assert.False(t, iterator.IsValid())
 }
+
+func TestIterateOverASortedSegmentWithMultipleBlocksUsingReadahead(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	for count := 0; count < 20; count++ {
+		sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%02d", count), 5), kv.NewStringValue(fmt.Sprintf("raft%02d", count)))
+	}
+	segment, blockMetaList, _, err := sortedSegmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	assert.True(t, segment.noOfBlocks() > 4)
+
+	segment.readaheadOptions = NewReadaheadOptions(1, 4)
+	segment.ioScheduler = objectstore.NewIOScheduler(4)
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+
+	defer iterator.Close()
+
+	for count := 0; count < 20; count++ {
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, kv.NewStringValue(fmt.Sprintf("raft%02d", count)), iterator.Value())
+		assert.NoError(t, iterator.Next())
+	}
+	assert.False(t, iterator.IsValid())
+	assert.Equal(t, 4, iterator.readahead.window)
+}
+
+func TestIterateOverASortedSegmentUsingReadaheadWithAFailedReadAhead(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	for count := 0; count < 8; count++ {
+		sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%02d", count), 5), kv.NewStringValue(fmt.Sprintf("raft%02d", count)))
+	}
+	segment, blockMetaList, _, err := sortedSegmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segment.readaheadOptions = NewReadaheadOptions(2, 2)
+	segment.ioScheduler = objectstore.NewIOScheduler(4)
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, kv.NewStringValue("raft00"), iterator.Value())
+	assert.NoError(t, iterator.Next())
+
+	iterator.Close()
+
+	for count := 1; count < 8; count++ {
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, kv.NewStringValue(fmt.Sprintf("raft%02d", count)), iterator.Value())
+		assert.NoError(t, iterator.Next())
+	}
+	assert.False(t, iterator.IsValid())
+}
+
+func TestSeekToKeyInASortedSegmentDoesNotReadAhead(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	for count := 0; count < 8; count++ {
+		sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%02d", count), 5), kv.NewStringValue(fmt.Sprintf("raft%02d", count)))
+	}
+	segment, blockMetaList, _, err := sortedSegmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segment.readaheadOptions = NewReadaheadOptions(2, 4)
+	segment.ioScheduler = objectstore.NewIOScheduler(4)
+	iterator, err := segment.seekToKey(kv.NewStringKeyWithTimestamp("consensus03", 5), blockMetaList)
+	assert.NoError(t, err)
+
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, kv.NewStringValue("raft03"), iterator.Value())
+	assert.Nil(t, iterator.readahead)
+
+	assert.NoError(t, iterator.Next())
+	assert.NotNil(t, iterator.readahead)
+}
+
+func TestReadaheadOfASortedSegmentIsBoundedByTheIOScheduler(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	for count := 0; count < 8; count++ {
+		sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%02d", count), 5), kv.NewStringValue(fmt.Sprintf("raft%02d", count)))
+	}
+	segment, blockMetaList, _, err := sortedSegmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	ioScheduler := objectstore.NewIOScheduler(1)
+	segment.readaheadOptions = NewReadaheadOptions(2, 4)
+	segment.ioScheduler = ioScheduler
+
+	acquired, release := make(chan struct{}), make(chan struct{})
+	blockingRead := objectstore.Schedule(context.Background(), ioScheduler, func(ctx context.Context) (struct{}, error) {
+		close(acquired)
+		<-release
+		return struct{}{}, nil
+	})
+	<-acquired
+
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		for iterator.IsValid() {
+			assert.NoError(t, iterator.Next())
+		}
+	}()
+
+	select {
+	case <-scanned:
+		assert.Fail(t, "the readahead did not wait for a permit of the io scheduler")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	_, _ = blockingRead.Wait()
+	<-scanned
+}
+
+func testBlockCache(t *testing.T) *cache.BlockCache {
+	blockCache, err := cache.NewBlockCache(cache.NewComparableKeyCacheOptions[cache.BlockCacheKey, block.Block](
+		1<<20,
+		5*time.Minute,
+		func(key cache.BlockCacheKey, value block.Block) uint32 {
+			return uint32(value.SizeInBytes())
+		},
+	))
+	assert.NoError(t, err)
+	return &blockCache
+}
+
+func TestReadaheadOfASortedSegmentDoesNotReadTheCachedBlocks(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	for count := 0; count < 8; count++ {
+		sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%02d", count), 5), kv.NewStringValue(fmt.Sprintf("raft%02d", count)))
+	}
+	segment, blockMetaList, _, err := sortedSegmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segment.blockCache = testBlockCache(t)
+	segment.readaheadOptions = NewReadaheadOptions(2, 4)
+	segment.ioScheduler = objectstore.NewIOScheduler(4)
+	for blockIndex := 0; blockIndex < segment.noOfBlocks(); blockIndex++ {
+		_, err := segment.readBlock(blockIndex, blockMetaList)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, os.Remove(PathSuffixForSegment(segmentId)))
+
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	for count := 0; count < 8; count++ {
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, kv.NewStringValue(fmt.Sprintf("raft%02d", count)), iterator.Value())
+		assert.NoError(t, iterator.Next())
+		assert.Equal(t, 0, len(iterator.readahead.runs))
+	}
+	assert.False(t, iterator.IsValid())
+}
+
+func TestReadAdjacentBlocksOfASortedSegmentWithSomeCachedBlocks(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	for count := 0; count < 8; count++ {
+		sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus%02d", count), 5), kv.NewStringValue(fmt.Sprintf("raft%02d", count)))
+	}
+	segment, blockMetaList, _, err := sortedSegmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segment.blockCache = testBlockCache(t)
+	for _, blockIndex := range []int{2, 4} {
+		_, err := segment.readBlock(blockIndex, blockMetaList)
+		assert.NoError(t, err)
+	}
+
+	blocks, err := segment.readAdjacentBlocksWithContext(context.Background(), 1, 5, blockMetaList)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(blocks))
+	for index, readBlock := range blocks {
+		blockIterator := readBlock.SeekToFirst()
+		assert.True(t, blockIterator.IsValid())
+		assert.Equal(t, kv.NewStringValue(fmt.Sprintf("raft%02d", index+1)), blockIterator.Value())
+	}
+	for blockIndex := 1; blockIndex <= 5; blockIndex++ {
+		_, ok := segment.cachedBlock(blockIndex)
+		assert.True(t, ok)
+	}
+}
+
+func TestReadaheadOptionsWithZeroInitialWindow(t *testing.T) {
+	assert.Panics(t, func() {
+		NewReadaheadOptions(0, 4)
+	})
+}
+
+func TestReadaheadOptionsWithMaximumWindowLessThanTheInitialWindow(t *testing.T) {
+	assert.Panics(t, func() {
+		NewReadaheadOptions(4, 2)
+	})
+}
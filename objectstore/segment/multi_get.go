@@ -0,0 +1,133 @@
+package segment
+
+import (
+	"context"
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/SarthakMakhija/zero-store/objectstore"
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+	"slices"
+)
+
+// blockRun is a run of adjacent blocks (from beginBlockIndex to endBlockIndex, both inclusive) which are read with a
+// single object store read.
+type blockRun struct {
+	beginBlockIndex int
+	endBlockIndex   int
+}
+
+// Version is a version of a key found in a SortedSegment, Timestamp is the timestamp of the version.
+// Value is the value as stored, it may be deleted or may be a pointer to a value in the value log, please check
+// ResolveValue.
+type Version struct {
+	Value     kv.Value
+	Timestamp uint64
+}
+
+// MultiGet looks up the given keys in the given SortedSegment, and returns the newest Version (at or below the timestamp
+// of the key) of each key found in the SortedSegment, indexed by the position of the key in keys.
+// MultiGet involves:
+// 1) Grouping the keys by the block which may contain them, using the block.MetaList.
+// 2) Taking the blocks which are in the block cache, and coalescing the adjacent remaining blocks into runs, each run is read with a single object store read (GetRange).
+// 3) Scheduling the reads of all the runs concurrently on the given objectstore.IOScheduler.
+// 4) Seeking to each key within its block, the next block is read if the version of the key is not in its block.
+// Each needed block is read once.
+func (sortedSegments *SortedSegments) MultiGet(
+	ctx context.Context,
+	ioScheduler *objectstore.IOScheduler,
+	keys []kv.Key,
+	sortedSegment SortedSegment,
+) (map[int]Version, error) {
+	if sortedSegment.isEmpty() {
+		return nil, ErrEmptySegment
+	}
+	blockMetaList, err := sortedSegments.getOrFetchBlockMetaList(sortedSegment)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[int]block.Block)
+	blockIndexOfKeys := make([]int, len(keys))
+	var blockIndexes []int
+	for index, key := range keys {
+		_, blockIndex := blockMetaList.MaybeBlockMetaContaining(key)
+		blockIndexOfKeys[index] = blockIndex
+		if cachedBlock, ok := sortedSegment.cachedBlock(blockIndex); ok {
+			blocks[blockIndex] = cachedBlock
+			continue
+		}
+		blockIndexes = append(blockIndexes, blockIndex)
+	}
+	slices.Sort(blockIndexes)
+	blockIndexes = slices.Compact(blockIndexes)
+
+	readBlocks, err := sortedSegment.readBlockRuns(ctx, ioScheduler, blockRunsOf(blockIndexes), blockMetaList)
+	if err != nil {
+		return nil, err
+	}
+	for blockIndex, readBlock := range readBlocks {
+		blocks[blockIndex] = readBlock
+	}
+	versions := make(map[int]Version)
+	for index, key := range keys {
+		blockIndex := blockIndexOfKeys[index]
+		blockIterator := blocks[blockIndex].SeekToKey(key)
+		if !blockIterator.IsValid() && blockIndex+1 < sortedSegment.noOfBlocks() {
+			nextBlock, ok := blocks[blockIndex+1]
+			if !ok {
+				nextBlock, err = sortedSegment.readBlockWithContext(ctx, blockIndex+1, blockMetaList)
+				if err != nil {
+					return nil, err
+				}
+				blocks[blockIndex+1] = nextBlock
+			}
+			blockIterator = nextBlock.SeekToKey(key)
+		}
+		if blockIterator.IsValid() && blockIterator.Key().IsRawKeyEqualTo(key) {
+			versions[index] = Version{Value: blockIterator.Value(), Timestamp: blockIterator.Key().Timestamp()}
+		}
+	}
+	return versions, nil
+}
+
+// readBlockRuns reads all the given runs of blocks concurrently on the given objectstore.IOScheduler, and returns the
+// blocks indexed by their block index.
+func (segment SortedSegment) readBlockRuns(
+	ctx context.Context,
+	ioScheduler *objectstore.IOScheduler,
+	runs []blockRun,
+	blockMetaList *block.MetaList,
+) (map[int]block.Block, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scheduledReads := make([]*objectstore.ScheduledRead[[]block.Block], 0, len(runs))
+	for _, run := range runs {
+		scheduledReads = append(scheduledReads, objectstore.Schedule(ctx, ioScheduler, func(ctx context.Context) ([]block.Block, error) {
+			return segment.readAdjacentBlocksWithContext(ctx, run.beginBlockIndex, run.endBlockIndex, blockMetaList)
+		}))
+	}
+	blocks := make(map[int]block.Block)
+	for runIndex, scheduledRead := range scheduledReads {
+		readBlocks, err := scheduledRead.Wait()
+		if err != nil {
+			return nil, err
+		}
+		for offset, readBlock := range readBlocks {
+			blocks[runs[runIndex].beginBlockIndex+offset] = readBlock
+		}
+	}
+	return blocks, nil
+}
+
+// blockRunsOf coalesces the given (sorted and distinct) block indexes into the runs of adjacent blocks.
+func blockRunsOf(blockIndexes []int) []blockRun {
+	var runs []blockRun
+	for _, blockIndex := range blockIndexes {
+		if len(runs) > 0 && runs[len(runs)-1].endBlockIndex+1 == blockIndex {
+			runs[len(runs)-1].endBlockIndex = blockIndex
+			continue
+		}
+		runs = append(runs, blockRun{beginBlockIndex: blockIndex, endBlockIndex: blockIndex})
+	}
+	return runs
+}
@@ -0,0 +1,102 @@
+package segment
+
+import (
+	"context"
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/SarthakMakhija/zero-store/objectstore"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMultiGetKeysAcrossBlocksOfASortedSegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegmentsWithFormatOptions(store, NewSortedSegmentFormatOptions(256, 0.01, false), 0)
+	assert.NoError(t, err)
+
+	sortedSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys: []kv.Key{
+				kv.NewStringKeyWithTimestamp("consensus", 10),
+				kv.NewStringKeyWithTimestamp("distributed", 10),
+				kv.NewStringKeyWithTimestamp("raft", 50),
+				kv.NewStringKeyWithTimestamp("raft", 10),
+				kv.NewStringKeyWithTimestamp("storage", 5),
+			},
+			values: []kv.Value{
+				kv.NewStringValue(strings.Repeat("paxos", 30)),
+				kv.NewStringValue(strings.Repeat("TiKV", 30)),
+				kv.NewStringValue(strings.Repeat("etcd", 30)),
+				kv.NewStringValue(strings.Repeat("raft", 30)),
+				kv.NewDeletedValue(),
+			},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+	assert.True(t, sortedSegment.noOfBlocks() > 1)
+
+	versions, err := segments.MultiGet(
+		context.Background(),
+		objectstore.NewIOScheduler(2),
+		[]kv.Key{
+			kv.NewStringKeyWithTimestamp("storage", 20),
+			kv.NewStringKeyWithTimestamp("consensus", 20),
+			kv.NewStringKeyWithTimestamp("raft", 20),
+			kv.NewStringKeyWithTimestamp("quorum", 20),
+			kv.NewStringKeyWithTimestamp("distributed", 5),
+		},
+		sortedSegment,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(versions))
+	assert.True(t, versions[0].Value.IsDeleted())
+	assert.Equal(t, strings.Repeat("paxos", 30), versions[1].Value.String())
+	assert.Equal(t, strings.Repeat("raft", 30), versions[2].Value.String())
+	assert.Equal(t, uint64(5), versions[0].Timestamp)
+	assert.Equal(t, uint64(10), versions[1].Timestamp)
+	assert.Equal(t, uint64(10), versions[2].Timestamp)
+}
+
+func TestMultiGetKeysInAnEmptySortedSegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	defer store.Close()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	_, err = segments.MultiGet(
+		context.Background(),
+		objectstore.NewIOScheduler(2),
+		[]kv.Key{kv.NewStringKeyWithTimestamp("raft", 20)},
+		EmptySortedSegment,
+	)
+	assert.ErrorIs(t, err, ErrEmptySegment)
+}
+
+func TestCoalesceAdjacentBlocksIntoRuns(t *testing.T) {
+	runs := blockRunsOf([]int{0, 1, 2, 4, 6, 7})
+	assert.Equal(t, []blockRun{
+		{beginBlockIndex: 0, endBlockIndex: 2},
+		{beginBlockIndex: 4, endBlockIndex: 4},
+		{beginBlockIndex: 6, endBlockIndex: 7},
+	}, runs)
+}
+
+func TestCoalesceNoBlocksIntoRuns(t *testing.T) {
+	assert.Empty(t, blockRunsOf(nil))
+}
@@ -0,0 +1,146 @@
+package segment
+
+import (
+	"context"
+	"github.com/SarthakMakhija/zero-store/objectstore"
+	"github.com/SarthakMakhija/zero-store/objectstore/block"
+)
+
+// ReadaheadOptions configure the readahead of the segment Iterator.
+// The readahead reads the blocks following the current block of the Iterator in the background, so that a scan does not
+// stall on every block boundary. initialWindow is the number of blocks read ahead when the Iterator starts moving
+// forward, and the window doubles (up to maximumWindow) every time the Iterator moves to a block which was read ahead.
+// A maximumWindow of 0 disables the readahead.
+// The reads of the readahead are scheduled on the objectstore.IOScheduler of SortedSegments, so they count towards the
+// concurrent reads of the Db. Hence, an Iterator must not be moved forward from within a read scheduled on the same
+// objectstore.IOScheduler.
+type ReadaheadOptions struct {
+	initialWindow uint
+	maximumWindow uint
+}
+
+// NewReadaheadOptions creates ReadaheadOptions with the given initial and maximum windows (in blocks).
+func NewReadaheadOptions(initialWindow uint, maximumWindow uint) ReadaheadOptions {
+	if initialWindow == 0 {
+		panic("readahead initial window must be greater than 0")
+	}
+	if maximumWindow < initialWindow {
+		panic("readahead maximum window must be greater than or equal to the initial window")
+	}
+	return ReadaheadOptions{
+		initialWindow: initialWindow,
+		maximumWindow: maximumWindow,
+	}
+}
+
+// NoReadahead returns ReadaheadOptions which disable the readahead.
+func NoReadahead() ReadaheadOptions {
+	return ReadaheadOptions{}
+}
+
+func (options ReadaheadOptions) isEnabled() bool {
+	return options.maximumWindow > 0
+}
+
+// prefetchedRun is a run of adjacent blocks which is being read in the background, read is scheduled on the
+// objectstore.IOScheduler.
+type prefetchedRun struct {
+	run  blockRun
+	read *objectstore.ScheduledRead[[]block.Block]
+}
+
+// readahead reads the blocks following the current block of an Iterator in the background.
+// Each read is a contiguous multi-block range (please check SortedSegment.readAdjacentBlocksWithContext), which
+// is issued when less than half of the window is ahead of the current block.
+// nextBlockIndex is the index of the first block which is not read ahead yet, and runs are the runs being read ahead in
+// the increasing order of their blocks.
+// All the reads are abandoned when the readahead is closed.
+// A readahead is created when the Iterator moves forward for the first time, so an Iterator which is only positioned
+// (for a point get) never reads ahead.
+type readahead struct {
+	segment        SortedSegment
+	blockMetaList  *block.MetaList
+	window         int
+	maximumWindow  int
+	nextBlockIndex int
+	runs           []*prefetchedRun
+	ctx            context.Context
+	cancel         context.CancelFunc
+}
+
+// newReadahead creates a readahead for an Iterator positioned at the given blockIndex, it returns nil if the readahead is
+// disabled, or if the SortedSegment does not have an objectstore.IOScheduler to schedule the reads on.
+func newReadahead(segment SortedSegment, blockMetaList *block.MetaList, blockIndex int) *readahead {
+	if !segment.readaheadOptions.isEnabled() || segment.ioScheduler == nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &readahead{
+		segment:        segment,
+		blockMetaList:  blockMetaList,
+		window:         int(segment.readaheadOptions.initialWindow),
+		maximumWindow:  int(segment.readaheadOptions.maximumWindow),
+		nextBlockIndex: blockIndex + 1,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// prefetchAfter reads ahead the blocks after the given (current) blockIndex, up to the window, if less than half of the
+// window is already read ahead. A run whose blocks are all in the block cache is not read (and does not take a permit of
+// the objectstore.IOScheduler), and only the blocks which are not in the block cache are read for the other runs.
+func (readahead *readahead) prefetchAfter(blockIndex int) {
+	lastBlockIndex := readahead.segment.noOfBlocks() - 1
+	readahead.nextBlockIndex = max(readahead.nextBlockIndex, blockIndex+1)
+	if readahead.nextBlockIndex > lastBlockIndex {
+		return
+	}
+	if readahead.nextBlockIndex-blockIndex-1 > readahead.window/2 {
+		return
+	}
+	endBlockIndex := min(blockIndex+readahead.window, lastBlockIndex)
+	if endBlockIndex < readahead.nextBlockIndex {
+		return
+	}
+	run := blockRun{beginBlockIndex: readahead.nextBlockIndex, endBlockIndex: endBlockIndex}
+	readahead.nextBlockIndex = endBlockIndex + 1
+	if readahead.segment.allBlocksCached(run.beginBlockIndex, run.endBlockIndex) {
+		return
+	}
+	readahead.runs = append(readahead.runs, &prefetchedRun{
+		run: run,
+		read: objectstore.Schedule(readahead.ctx, readahead.segment.ioScheduler, func(ctx context.Context) ([]block.Block, error) {
+			return readahead.segment.readAdjacentBlocksWithContext(ctx, run.beginBlockIndex, run.endBlockIndex, readahead.blockMetaList)
+		}),
+	})
+}
+
+// blockAt returns the block at the given blockIndex, which the Iterator moves to.
+// The block is taken from the runs read ahead (waiting for its run, if needed), and the window grows. The block is read
+// synchronously if it was not read ahead, or its run failed, so that the Iterator surfaces the error of its own read.
+func (readahead *readahead) blockAt(blockIndex int) (block.Block, error) {
+	for len(readahead.runs) > 0 && readahead.runs[0].run.endBlockIndex < blockIndex {
+		readahead.runs = readahead.runs[1:]
+	}
+	if len(readahead.runs) > 0 && readahead.runs[0].run.beginBlockIndex <= blockIndex {
+		prefetched := readahead.runs[0]
+		blocks, err := prefetched.read.Wait()
+		if err == nil {
+			readahead.window = min(readahead.window*2, readahead.maximumWindow)
+			readahead.prefetchAfter(blockIndex)
+			return blocks[blockIndex-prefetched.run.beginBlockIndex], nil
+		}
+		readahead.runs = readahead.runs[1:]
+	}
+	readBlock, err := readahead.segment.readBlock(blockIndex, readahead.blockMetaList)
+	if err != nil {
+		return block.Block{}, err
+	}
+	readahead.prefetchAfter(blockIndex)
+	return readBlock, nil
+}
+
+// close abandons all the reads of the readahead.
+func (readahead *readahead) close() {
+	readahead.cancel()
+}
@@ -1,75 +1,157 @@
 package segment
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"github.com/SarthakMakhija/zero-store/cache"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/block"
 	"github.com/SarthakMakhija/zero-store/objectstore/filter"
+	"github.com/SarthakMakhija/zero-store/objectstore/valuelog"
+	"golang.org/x/sync/singleflight"
 )
 
 // SortedSegment is the on-disk representation of the memory.SortedSegment on object store.
 // A persistent SortedSegment contains the data sorted by key.
 // The abstraction SortedSegment does not contain the data, it mainly contains the bloom filter (filter.BloomFilter) and
 // block meta-list (block.MetaList).
+// checksums are the checksums of the sections of the SortedSegment, they are nil for the SortedSegment written without
+// checksums.
+// formatVersion is the block.Trailer version of the SortedSegment, it determines the encoding of the block meta list and
+// the blocks.
+// withCompressionCodecs denotes that the block meta list records the compression codec of each block, it is false for the
+// SortedSegment written before the blocks were compressed.
+// hasCompressionDictionary denotes that the blocks of the SortedSegment are compressed using a block.CompressionDictionary.
+// The dictionary is not a part of the SortedSegment, it is read from compressionDictionaryCache (or loaded from the
+// object store), please check compressionDictionary.
+// statistics are the block.Statistics of the SortedSegment, they are nil for the SortedSegment written without the
+// statistics section.
+// blockCache caches the decoded blocks of the SortedSegment, the blocks are read from the object store on every read if
+// the SortedSegment does not have a blockCache.
+// fetches coalesces the concurrent reads of the same block (or the same run of blocks) into a single object store read,
+// please check coalescedFetch.
+// readaheadOptions configure the readahead of the Iterators of the SortedSegment, and the reads of the readahead are
+// scheduled on the ioScheduler.
+// valueLogs resolve the separated values (valuelog.Pointer) of the SortedSegment, please check Iterator.ResolvedValue.
 type SortedSegment struct {
-	id                   uint64
-	blockMetaBeginOffset uint32
-	blockSize            uint
-	startingKey          kv.Key
-	endingKey            kv.Key
-	store                objectstore.Store
-	numberOfBlocks       int
-	footerBlock          *block.FooterBlock
+	id                         uint64
+	blockMetaBeginOffset       uint64
+	formatVersion              uint16
+	formatOptions              SortedSegmentFormatOptions
+	startingKey                kv.Key
+	endingKey                  kv.Key
+	store                      objectstore.Store
+	numberOfBlocks             int
+	footerBlock                *block.FooterBlock
+	checksums                  *block.Checksums
+	withCompressionCodecs      bool
+	hasCompressionDictionary   bool
+	compressionDictionaryCache *cache.CompressionDictionaryCache
+	compressionStats           CompressionStats
+	statistics                 *block.Statistics
+	blockCache                 *cache.BlockCache
+	fetches                    *singleflight.Group
+	readaheadOptions           ReadaheadOptions
+	ioScheduler                *objectstore.IOScheduler
+	valueLogs                  *valuelog.ValueLogs
 }
 
 var EmptySortedSegment = SortedSegment{}
 
 // load loads the entire SortedSegment from the given rootPath.
+// load reads the block.Trailer of the SortedSegment and dispatches on its format version to load the footer block.
+// The SortedSegment which does not end with a block.Trailer is of block.FormatVersionLegacy.
+// The format parameters are read from the footer block of the SortedSegment. fallbackFormatOptions are used only if
+// the footer block does not contain the format parameters (SortedSegment written before the format parameters were recorded).
+// If the SortedSegment contains checksums, every section is verified, and ErrCorruption is returned if the verification fails.
 // Please take a look at segment.SortedSegmentBuilder to understand the encoding of SortedSegment.
-func load(id uint64, blockSize uint, enableCompression bool, store objectstore.Store) (SortedSegment, *block.MetaList, filter.BloomFilter, error) {
-	// loadFooterBlock loads the footer block from the actual object-store.
-	// The last block of the SortedSegment contains offsets.
-	// Please take a look at segment.SortedSegmentBuilder to understand the encoding of SortedSegment.
-	// Please take a look at block.FooterBlock to understand its encoding.
-	loadFooterBlock := func(id uint64, store objectstore.Store, blockSize uint) (*block.FooterBlock, error) {
-		segmentSize, err := store.SizeInBytes(PathSuffixForSegment(id))
-		if err != nil {
-			return nil, err
-		}
-		footerBlockBeginOffset := segmentSize - int64(blockSize)
-		footerBlockBytes, err := store.GetRange(PathSuffixForSegment(id), footerBlockBeginOffset, int64(blockSize))
-		if err != nil {
-			return nil, err
-		}
-		return block.DecodeToFooterBlock(footerBlockBytes, blockSize), nil
+func load(id uint64, fallbackFormatOptions SortedSegmentFormatOptions, store objectstore.Store) (SortedSegment, *block.MetaList, filter.BloomFilter, error) {
+	segmentSize, err := store.SizeInBytes(PathSuffixForSegment(id))
+	if err != nil {
+		return EmptySortedSegment, nil, filter.BloomFilter{}, err
+	}
+	trailerSize := min(int64(block.TrailerSize), segmentSize)
+	trailerBytes, err := store.GetRange(PathSuffixForSegment(id), segmentSize-trailerSize, trailerSize)
+	if err != nil {
+		return EmptySortedSegment, nil, filter.BloomFilter{}, err
 	}
 
-	footerBlock, err := loadFooterBlock(id, store, blockSize)
+	var footerBlock *block.FooterBlock
+	trailer, ok := block.DecodeToTrailer(trailerBytes)
+	if !ok {
+		trailer = block.Trailer{Version: block.FormatVersionLegacy}
+	}
+	switch trailer.Version {
+	case block.FormatVersionLegacy:
+		footerBlock, err = loadLegacyFooterBlock(id, segmentSize, trailerBytes, fallbackFormatOptions.blockSize, store)
+	case block.FormatVersion1, block.FormatVersion2:
+		footerBlock, err = loadFooterBlock(id, segmentSize, trailer, store)
+	default:
+		err = fmt.Errorf("%w: version %v, segment id %v", block.ErrUnsupportedFormatVersion, trailer.Version, id)
+	}
 	if err != nil {
 		return EmptySortedSegment, nil, filter.BloomFilter{}, err
 	}
-	blockMetaList, err := loadBlockMetaList(id, footerBlock, enableCompression, store)
+	formatOptions := fallbackFormatOptions
+	if footerBlock.HasFormatParameters() {
+		formatOptions = NewSortedSegmentFormatOptions(
+			footerBlock.BlockSize(),
+			footerBlock.BloomFilterFalsePositiveRate(),
+			footerBlock.IsCompressionEnabled(),
+		)
+	}
+	var checksums *block.Checksums
+	if trailer.HasFlag(block.TrailerFlagChecksums) {
+		checksums, err = loadChecksums(id, footerBlock, store)
+		if err != nil {
+			return EmptySortedSegment, nil, filter.BloomFilter{}, err
+		}
+	}
+	withCompressionCodecs := trailer.HasFlag(block.TrailerFlagBlockCompressionCodecs)
+	blockMetaList, err := loadBlockMetaList(
+		id,
+		footerBlock,
+		formatOptions.enableCompression,
+		withCompressionCodecs,
+		trailer.Version,
+		checksums,
+		store,
+	)
 	if err != nil {
 		return EmptySortedSegment, nil, filter.BloomFilter{}, err
 	}
-	bloomFilter, err := loadBloomFilter(id, footerBlock, store)
+	bloomFilter, err := loadBloomFilter(id, footerBlock, checksums, store)
 	if err != nil {
 		return EmptySortedSegment, nil, filter.BloomFilter{}, err
 	}
+	var statistics *block.Statistics
+	if trailer.HasFlag(block.TrailerFlagStatistics) {
+		statistics, err = loadStatistics(id, footerBlock, store)
+		if err != nil {
+			return EmptySortedSegment, nil, filter.BloomFilter{}, err
+		}
+	}
 
 	startingKey, _ := blockMetaList.StartingKeyOfFirstBlock()
 	endingKey, _ := blockMetaList.EndingKeyOfLastBlock()
 	blockMetaBeginOffset, _ := footerBlock.GetOffsetAt(0)
 	return SortedSegment{
-		id:                   id,
-		blockSize:            blockSize,
-		blockMetaBeginOffset: blockMetaBeginOffset,
-		startingKey:          startingKey,
-		endingKey:            endingKey,
-		store:                store,
-		numberOfBlocks:       blockMetaList.Length(),
-		footerBlock:          footerBlock,
+		id:                       id,
+		formatVersion:            trailer.Version,
+		formatOptions:            formatOptions,
+		blockMetaBeginOffset:     blockMetaBeginOffset,
+		startingKey:              startingKey,
+		endingKey:                endingKey,
+		store:                    store,
+		numberOfBlocks:           blockMetaList.Length(),
+		footerBlock:              footerBlock,
+		checksums:                checksums,
+		withCompressionCodecs:    withCompressionCodecs,
+		hasCompressionDictionary: trailer.HasFlag(block.TrailerFlagCompressionDictionary),
+		compressionStats:         compressionStatsOf(blockMetaList, blockMetaBeginOffset),
+		statistics:               statistics,
 	}, blockMetaList, bloomFilter, nil
 }
 
@@ -97,8 +179,13 @@ func (segment SortedSegment) seekToFirst(blockMetaList *block.MetaList) (*Iterat
 // 3) Seek to the key within the read block (seeks to the offset where the key >= the given key)
 // 4) Handle the case where block.Iterator may become invalid.
 func (segment SortedSegment) seekToKey(key kv.Key, blockMetaList *block.MetaList) (*Iterator, error) {
+	return segment.seekToKeyWithContext(context.Background(), key, blockMetaList)
+}
+
+// seekToKeyWithContext is seekToKey whose block reads are abandoned if the given context is cancelled.
+func (segment SortedSegment) seekToKeyWithContext(ctx context.Context, key kv.Key, blockMetaList *block.MetaList) (*Iterator, error) {
 	_, blockIndex := blockMetaList.MaybeBlockMetaContaining(key)
-	readBlock, err := segment.readBlock(blockIndex, blockMetaList)
+	readBlock, err := segment.readBlockWithContext(ctx, blockIndex, blockMetaList)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +194,7 @@ func (segment SortedSegment) seekToKey(key kv.Key, blockMetaList *block.MetaList
 	if !blockIterator.IsValid() {
 		blockIndex += 1
 		if blockIndex < segment.noOfBlocks() {
-			readBlock, err := segment.readBlock(blockIndex, blockMetaList)
+			readBlock, err := segment.readBlockWithContext(ctx, blockIndex, blockMetaList)
 			if err != nil {
 				return nil, err
 			}
@@ -146,14 +233,235 @@ func (segment SortedSegment) isEmpty() bool {
 	return segment.noOfBlocks() == 0
 }
 
+// CompressionStats returns the CompressionStats of the data blocks of the SortedSegment.
+func (segment SortedSegment) CompressionStats() CompressionStats {
+	return segment.compressionStats
+}
+
+// Statistics returns the block.Statistics of the SortedSegment, it returns false if the SortedSegment was written without
+// the statistics section.
+func (segment SortedSegment) Statistics() (block.Statistics, bool) {
+	if segment.statistics == nil {
+		return block.Statistics{}, false
+	}
+	return *segment.statistics, true
+}
+
+// MayContainVersionsVisibleAt returns true if the SortedSegment may contain a version visible at the given read timestamp,
+// it returns false if the minimum timestamp of the SortedSegment is above the read timestamp.
+// It returns true if the SortedSegment was written without the statistics section.
+func (segment SortedSegment) MayContainVersionsVisibleAt(readTimestamp uint64) bool {
+	if segment.statistics == nil || segment.statistics.NumberOfEntries == 0 {
+		return true
+	}
+	return segment.statistics.MinimumTimestamp <= readTimestamp
+}
+
+// ContainsOnlyVersionsOlderThan returns true if the maximum timestamp of the SortedSegment is below the given timestamp.
+// Such a SortedSegment can not contain a version newer than a version (with the given timestamp) which is already found.
+// It returns false if the SortedSegment was written without the statistics section.
+func (segment SortedSegment) ContainsOnlyVersionsOlderThan(timestamp uint64) bool {
+	if segment.statistics == nil || segment.statistics.NumberOfEntries == 0 {
+		return false
+	}
+	return segment.statistics.MaximumTimestamp < timestamp
+}
+
 // readBlock reads the block at the given blockIndex.
+// The block is read from the blockCache, and it is read from the object store (and cached) if it is not in the cache.
+// The byte range of a block includes its overflow bytes, if any.
+// Please check decodeBlock for the verification and the decompression of the block.
 func (segment SortedSegment) readBlock(blockIndex int, blockMetaList *block.MetaList) (block.Block, error) {
-	startingOffset, endOffset := segment.offsetRangeOfBlockAt(blockIndex, blockMetaList)
-	buffer, err := segment.store.GetRange(PathSuffixForSegment(segment.id), int64(startingOffset), int64(endOffset-startingOffset))
+	return segment.readBlockWithContext(context.Background(), blockIndex, blockMetaList)
+}
+
+// readBlockWithContext is readBlock whose object store read is abandoned if the given context is cancelled.
+// Concurrent reads of the same block are coalesced into a single object store read.
+func (segment SortedSegment) readBlockWithContext(ctx context.Context, blockIndex int, blockMetaList *block.MetaList) (block.Block, error) {
+	if cachedBlock, ok := segment.cachedBlock(blockIndex); ok {
+		return cachedBlock, nil
+	}
+	return coalescedFetch(
+		ctx,
+		segment.fetches,
+		fmt.Sprintf("block/%v/%v", segment.id, blockIndex),
+		func(ctx context.Context) (block.Block, error) {
+			startingOffset, endOffset := segment.offsetRangeOfBlockAt(blockIndex, blockMetaList)
+			buffer, err := segment.store.GetRangeWithContext(
+				ctx,
+				PathSuffixForSegment(segment.id),
+				int64(startingOffset),
+				int64(endOffset-startingOffset),
+			)
+			if err != nil {
+				return block.Block{}, err
+			}
+			decodedBlock, err := segment.decodeBlock(blockIndex, buffer, blockMetaList)
+			if err != nil {
+				return block.Block{}, err
+			}
+			segment.cacheBlock(blockIndex, decodedBlock)
+			return decodedBlock, nil
+		},
+	)
+}
+
+// readAdjacentBlocksWithContext reads the adjacent blocks from beginBlockIndex to endBlockIndex (both inclusive), the
+// reads are abandoned if the given context is cancelled.
+// The blocks which are in the blockCache are taken from the cache, and each run of the adjacent blocks which are not in
+// the cache is read with a single object store read (please check readBlockRunWithContext).
+func (segment SortedSegment) readAdjacentBlocksWithContext(
+	ctx context.Context,
+	beginBlockIndex int,
+	endBlockIndex int,
+	blockMetaList *block.MetaList,
+) ([]block.Block, error) {
+	blocks := make([]block.Block, endBlockIndex-beginBlockIndex+1)
+	var missingBlockIndexes []int
+	for blockIndex := beginBlockIndex; blockIndex <= endBlockIndex; blockIndex++ {
+		if cachedBlock, ok := segment.cachedBlock(blockIndex); ok {
+			blocks[blockIndex-beginBlockIndex] = cachedBlock
+			continue
+		}
+		missingBlockIndexes = append(missingBlockIndexes, blockIndex)
+	}
+	for _, run := range blockRunsOf(missingBlockIndexes) {
+		readBlocks, err := segment.readBlockRunWithContext(ctx, run.beginBlockIndex, run.endBlockIndex, blockMetaList)
+		if err != nil {
+			return nil, err
+		}
+		copy(blocks[run.beginBlockIndex-beginBlockIndex:], readBlocks)
+	}
+	return blocks, nil
+}
+
+// allBlocksCached returns true if all the blocks from beginBlockIndex to endBlockIndex (both inclusive) are in the
+// blockCache.
+func (segment SortedSegment) allBlocksCached(beginBlockIndex int, endBlockIndex int) bool {
+	for blockIndex := beginBlockIndex; blockIndex <= endBlockIndex; blockIndex++ {
+		if _, ok := segment.cachedBlock(blockIndex); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// readBlockRunWithContext reads the adjacent blocks from beginBlockIndex to endBlockIndex (both inclusive) with a
+// single object store read, which is abandoned if the given context is cancelled.
+// Concurrent reads of the same run of blocks are coalesced into a single object store read.
+// The blocks are cached in the blockCache. A block which is cached does not share the buffer of the read, so that it does
+// not retain the bytes of its adjacent blocks.
+func (segment SortedSegment) readBlockRunWithContext(
+	ctx context.Context,
+	beginBlockIndex int,
+	endBlockIndex int,
+	blockMetaList *block.MetaList,
+) ([]block.Block, error) {
+	return coalescedFetch(
+		ctx,
+		segment.fetches,
+		fmt.Sprintf("blocks/%v/%v-%v", segment.id, beginBlockIndex, endBlockIndex),
+		func(ctx context.Context) ([]block.Block, error) {
+			beginOffset, _ := segment.offsetRangeOfBlockAt(beginBlockIndex, blockMetaList)
+			_, endOffset := segment.offsetRangeOfBlockAt(endBlockIndex, blockMetaList)
+			buffer, err := segment.store.GetRangeWithContext(
+				ctx,
+				PathSuffixForSegment(segment.id),
+				int64(beginOffset),
+				int64(endOffset-beginOffset),
+			)
+			if err != nil {
+				return nil, err
+			}
+			blocks := make([]block.Block, 0, endBlockIndex-beginBlockIndex+1)
+			for blockIndex := beginBlockIndex; blockIndex <= endBlockIndex; blockIndex++ {
+				startingOffset, endOffset := segment.offsetRangeOfBlockAt(blockIndex, blockMetaList)
+				blockBuffer := buffer[startingOffset-beginOffset : endOffset-beginOffset]
+				if segment.blockCache != nil {
+					blockBuffer = bytes.Clone(blockBuffer)
+				}
+				decodedBlock, err := segment.decodeBlock(blockIndex, blockBuffer, blockMetaList)
+				if err != nil {
+					return nil, err
+				}
+				segment.cacheBlock(blockIndex, decodedBlock)
+				blocks = append(blocks, decodedBlock)
+			}
+			return blocks, nil
+		},
+	)
+}
+
+// decodeBlock decodes the given bytes of the block at the given blockIndex, as they are stored in the SortedSegment.
+// The block is verified against its checksum (if the SortedSegment contains checksums), and then decompressed using the
+// compression codec recorded in its block.Meta.
+func (segment SortedSegment) decodeBlock(blockIndex int, buffer []byte, blockMetaList *block.MetaList) (block.Block, error) {
+	if segment.checksums != nil && !segment.checksums.VerifyBlock(blockIndex, buffer) {
+		return block.Block{}, newBlockCorruptionError(segment.id, blockIndex)
+	}
+	blockMeta, _ := blockMetaList.GetAt(blockIndex)
+	if blockMeta.CompressionCodec != block.CompressionCodecNone {
+		compressionDictionary, err := segment.compressionDictionary()
+		if err != nil {
+			return block.Block{}, err
+		}
+		buffer, err = block.DecompressWithDictionary(blockMeta.CompressionCodec, buffer, int(blockMeta.UncompressedSize), compressionDictionary)
+		if err != nil {
+			return block.Block{}, fmt.Errorf("%w: segment id %v, block index %v", err, segment.id, blockIndex)
+		}
+	}
+	decodedBlock, err := block.DecodeToBlockWithOverflowOfFormatVersion(buffer, segment.formatOptions.blockSize, segment.formatVersion)
 	if err != nil {
-		return block.Block{}, err
+		return block.Block{}, fmt.Errorf("%w: segment id %v, block index %v", err, segment.id, blockIndex)
+	}
+	return decodedBlock, nil
+}
+
+// cachedBlock returns the block at the given blockIndex from the blockCache, it returns false if the SortedSegment does
+// not have a blockCache or the block is not in the cache.
+func (segment SortedSegment) cachedBlock(blockIndex int) (block.Block, bool) {
+	if segment.blockCache == nil {
+		return block.Block{}, false
 	}
-	return block.DecodeToBlock(buffer), nil
+	return segment.blockCache.Get(cache.BlockCacheKey{SegmentId: segment.id, BlockIndex: blockIndex})
+}
+
+// cacheBlock caches the given block at the given blockIndex in the blockCache, if the SortedSegment has a blockCache.
+func (segment SortedSegment) cacheBlock(blockIndex int, decodedBlock block.Block) {
+	if segment.blockCache != nil {
+		segment.blockCache.Set(cache.BlockCacheKey{SegmentId: segment.id, BlockIndex: blockIndex}, decodedBlock)
+	}
+}
+
+// compressionDictionary returns the block.CompressionDictionary of the SortedSegment, it returns nil if the SortedSegment
+// does not have a block.CompressionDictionary.
+// The dictionary is read from compressionDictionaryCache, and it is loaded from the object store (and cached) if it is
+// not in the cache. The dictionary is loaded on every call if the SortedSegment does not have a compressionDictionaryCache.
+// Concurrent loads of the dictionary are coalesced into a single load.
+func (segment SortedSegment) compressionDictionary() (*block.CompressionDictionary, error) {
+	if !segment.hasCompressionDictionary {
+		return nil, nil
+	}
+	if segment.compressionDictionaryCache != nil {
+		if compressionDictionary, ok := segment.compressionDictionaryCache.Get(segment.id); ok {
+			return compressionDictionary, nil
+		}
+	}
+	return coalescedFetch(
+		context.Background(),
+		segment.fetches,
+		fmt.Sprintf("compression-dictionary/%v", segment.id),
+		func(ctx context.Context) (*block.CompressionDictionary, error) {
+			compressionDictionary, err := loadCompressionDictionary(segment.id, segment.footerBlock, segment.store)
+			if err != nil {
+				return nil, err
+			}
+			if segment.compressionDictionaryCache != nil {
+				segment.compressionDictionaryCache.Set(segment.id, compressionDictionary)
+			}
+			return compressionDictionary, nil
+		},
+	)
 }
 
 // offsetRangeOfBlockAt returns the byte offset range of the block at the given index.
@@ -163,14 +471,14 @@ func (segment SortedSegment) readBlock(blockIndex int, blockMetaList *block.Meta
 // If the block.Meta is not available at the next index, it returns the BlockBeginOffset of block.Meta at the given index,
 // and table.blockMetaOffsetMarker, which is essentially the offset which denotes the meta starting offset.
 // Please take a look at the segment.SortedSegmentBuilder for encoding of SortedSegment.
-func (segment SortedSegment) offsetRangeOfBlockAt(blockIndex int, blockMetaList *block.MetaList) (uint32, uint32) {
+func (segment SortedSegment) offsetRangeOfBlockAt(blockIndex int, blockMetaList *block.MetaList) (uint64, uint64) {
 	blockMeta, blockPresent := blockMetaList.GetAt(blockIndex)
 	if !blockPresent {
 		panic(fmt.Errorf("block meta not found at index %v", blockIndex))
 	}
 	nextBlockMeta, nextBlockPresent := blockMetaList.GetAt(blockIndex + 1)
 
-	var endOffset uint32
+	var endOffset uint64
 	if nextBlockPresent {
 		endOffset = nextBlockMeta.BlockBeginOffset
 	} else {
@@ -179,26 +487,163 @@ func (segment SortedSegment) offsetRangeOfBlockAt(blockIndex int, blockMetaList
 	return blockMeta.BlockBeginOffset, endOffset
 }
 
+// loadFooterBlock loads the compact footer block of block.FormatVersion1 (or block.FormatVersion2) from the actual object-store.
+// The footer block is right before the block.Trailer, and its length is recorded in the block.Trailer.
+// If the block.Trailer has block.TrailerFlagChecksums, the footer block is followed by its checksum which is verified.
+// Please take a look at block.FooterBlock.EncodeCompact to understand its encoding.
+func loadFooterBlock(id uint64, segmentSize int64, trailer block.Trailer, store objectstore.Store) (*block.FooterBlock, error) {
+	footerBlockBeginOffset := segmentSize - int64(block.TrailerSize) - int64(trailer.FooterLength)
+	if footerBlockBeginOffset < 0 {
+		return nil, fmt.Errorf("%w: footer length %v, segment id %v", block.ErrInvalidFooterBlock, trailer.FooterLength, id)
+	}
+	footerBlockBytes, err := store.GetRange(PathSuffixForSegment(id), footerBlockBeginOffset, int64(trailer.FooterLength))
+	if err != nil {
+		return nil, err
+	}
+	if trailer.HasFlag(block.TrailerFlagChecksums) {
+		var ok bool
+		if footerBlockBytes, ok = block.VerifyAndStripChecksum(footerBlockBytes); !ok {
+			return nil, newSectionCorruptionError(id, SectionFooterBlock)
+		}
+	}
+	return block.DecodeToCompactFooterBlockOfFormatVersion(
+		footerBlockBytes,
+		trailer.HasFlag(block.TrailerFlagBlockMetaListCompressed),
+		trailer.Version,
+	)
+}
+
+// loadLegacyFooterBlock loads the footer block of block.FormatVersionLegacy from the actual object-store.
+// The last block of the SortedSegment contains offsets, and the last 4 bytes of the SortedSegment may contain the block size.
+// If the block size is not recorded, fallbackBlockSize is used.
+// Please take a look at block.FooterBlock.Encode to understand its encoding.
+func loadLegacyFooterBlock(id uint64, segmentSize int64, lastBytes []byte, fallbackBlockSize uint, store objectstore.Store) (*block.FooterBlock, error) {
+	blockSize, err := block.DecodeFooterBlockSize(lastBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: segment id %v", err, id)
+	}
+	if blockSize == 0 {
+		blockSize = fallbackBlockSize
+	}
+	footerBlockBeginOffset := segmentSize - int64(blockSize)
+	if footerBlockBeginOffset < 0 {
+		return nil, fmt.Errorf("%w: block size %v, segment id %v", block.ErrInvalidFooterBlock, blockSize, id)
+	}
+	footerBlockBytes, err := store.GetRange(PathSuffixForSegment(id), footerBlockBeginOffset, int64(blockSize))
+	if err != nil {
+		return nil, err
+	}
+	return block.DecodeToFooterBlock(footerBlockBytes, blockSize)
+}
+
+// loadChecksums loads the checksums section from the actual object-store.
+// Please take a look at segment.SortedSegmentBuilder to understand the encoding of SortedSegment.
+func loadChecksums(id uint64, footerBlock *block.FooterBlock, store objectstore.Store) (*block.Checksums, error) {
+	checksumsBeginOffset, beginOk := footerBlock.GetOffsetAsInt64At(4)
+	checksumsEndOffset, endOk := footerBlock.GetOffsetAsInt64At(5)
+	if !beginOk || !endOk || checksumsEndOffset < checksumsBeginOffset {
+		return nil, newSectionCorruptionError(id, SectionFooterBlock)
+	}
+	checksumsBytes, err := store.GetRange(PathSuffixForSegment(id), checksumsBeginOffset, checksumsEndOffset-checksumsBeginOffset)
+	if err != nil {
+		return nil, err
+	}
+	checksums, err := block.DecodeToChecksums(checksumsBytes)
+	if err != nil {
+		return nil, newSectionCorruptionError(id, SectionChecksums)
+	}
+	return checksums, nil
+}
+
+// loadCompressionDictionary loads the block.CompressionDictionary from the actual object-store.
+// The dictionary is followed by its checksum which is verified.
+// Please take a look at segment.SortedSegmentBuilder to understand the encoding of SortedSegment.
+func loadCompressionDictionary(id uint64, footerBlock *block.FooterBlock, store objectstore.Store) (*block.CompressionDictionary, error) {
+	compressionDictionaryBeginOffset, beginOk := footerBlock.GetOffsetAsInt64At(6)
+	compressionDictionaryEndOffset, endOk := footerBlock.GetOffsetAsInt64At(7)
+	if !beginOk || !endOk || compressionDictionaryEndOffset < compressionDictionaryBeginOffset {
+		return nil, newSectionCorruptionError(id, SectionFooterBlock)
+	}
+	compressionDictionaryBytes, err := store.GetRange(
+		PathSuffixForSegment(id),
+		compressionDictionaryBeginOffset,
+		compressionDictionaryEndOffset-compressionDictionaryBeginOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	compressionDictionaryBytes, ok := block.VerifyAndStripChecksum(compressionDictionaryBytes)
+	if !ok {
+		return nil, newSectionCorruptionError(id, SectionCompressionDictionary)
+	}
+	compressionDictionary, err := block.DecodeToCompressionDictionary(compressionDictionaryBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: segment id %v", err, id)
+	}
+	return compressionDictionary, nil
+}
+
+// loadStatistics loads the block.Statistics from the actual object-store.
+// The statistics section contains its own checksum which is verified.
+// Please take a look at segment.SortedSegmentBuilder to understand the encoding of SortedSegment.
+func loadStatistics(id uint64, footerBlock *block.FooterBlock, store objectstore.Store) (*block.Statistics, error) {
+	statisticsBeginOffset, beginOk := footerBlock.GetOffsetAsInt64At(8)
+	statisticsEndOffset, endOk := footerBlock.GetOffsetAsInt64At(9)
+	if !beginOk || !endOk || statisticsEndOffset < statisticsBeginOffset {
+		return nil, newSectionCorruptionError(id, SectionFooterBlock)
+	}
+	statisticsBytes, err := store.GetRange(PathSuffixForSegment(id), statisticsBeginOffset, statisticsEndOffset-statisticsBeginOffset)
+	if err != nil {
+		return nil, err
+	}
+	statistics, err := block.DecodeToStatistics(statisticsBytes)
+	if err != nil {
+		return nil, newSectionCorruptionError(id, SectionStatistics)
+	}
+	return statistics, nil
+}
+
 // loadBlockMetaList loads the block meta list from the actual object-store.
+// The block meta list is verified against its checksum, if checksums are not nil.
+// The block meta list of the SortedSegment written before the blocks were compressed does not contain the compression
+// codecs (withCompressionCodecs is false), otherwise the block meta list is decoded as per the given format version.
 // Please take a look at segment.SortedSegmentBuilder to understand the encoding of SortedSegment.
-func loadBlockMetaList(id uint64, footerBlock *block.FooterBlock, enableCompression bool, store objectstore.Store) (*block.MetaList, error) {
+func loadBlockMetaList(
+	id uint64,
+	footerBlock *block.FooterBlock,
+	enableCompression bool,
+	withCompressionCodecs bool,
+	formatVersion uint16,
+	checksums *block.Checksums,
+	store objectstore.Store,
+) (*block.MetaList, error) {
 	blockMetaBeginOffset, _ := footerBlock.GetOffsetAsInt64At(0)
 	blockMetaEndOffset, _ := footerBlock.GetOffsetAsInt64At(1)
-	blockMetaBytes, err := store.GetRange(PathSuffixForSegment(id), blockMetaBeginOffset, blockMetaEndOffset-blockMetaBeginOffset+1)
+	blockMetaBytes, err := store.GetRange(PathSuffixForSegment(id), blockMetaBeginOffset, blockMetaEndOffset-blockMetaBeginOffset)
 	if err != nil {
 		return nil, err
 	}
-	return block.DecodeToBlockMetaList(blockMetaBytes, enableCompression)
+	if checksums != nil && checksums.BlockMetaList != block.Checksum(blockMetaBytes) {
+		return nil, newSectionCorruptionError(id, SectionBlockMetaList)
+	}
+	if !withCompressionCodecs {
+		return block.DecodeToLegacyBlockMetaList(blockMetaBytes, enableCompression)
+	}
+	return block.DecodeToBlockMetaListOfFormatVersion(blockMetaBytes, enableCompression, formatVersion)
 }
 
 // loadBloomFilter loads the bloom filter from the actual object-store.
+// The bloom filter is verified against its checksum, if checksums are not nil.
 // Please take a look at segment.SortedSegmentBuilder to understand the encoding of SortedSegment.
-func loadBloomFilter(id uint64, footerBlock *block.FooterBlock, store objectstore.Store) (filter.BloomFilter, error) {
+func loadBloomFilter(id uint64, footerBlock *block.FooterBlock, checksums *block.Checksums, store objectstore.Store) (filter.BloomFilter, error) {
 	bloomFilterBeginOffset, _ := footerBlock.GetOffsetAsInt64At(2)
 	bloomFilterEndOffset, _ := footerBlock.GetOffsetAsInt64At(3)
-	bloomFilterBytes, err := store.GetRange(PathSuffixForSegment(id), bloomFilterBeginOffset, bloomFilterEndOffset-bloomFilterBeginOffset+1)
+	bloomFilterBytes, err := store.GetRange(PathSuffixForSegment(id), bloomFilterBeginOffset, bloomFilterEndOffset-bloomFilterBeginOffset)
 	if err != nil {
 		return filter.BloomFilter{}, err
 	}
+	if checksums != nil && checksums.BloomFilter != block.Checksum(bloomFilterBytes) {
+		return filter.BloomFilter{}, newSectionCorruptionError(id, SectionBloomFilter)
+	}
 	return filter.DecodeToBloomFilter(bloomFilterBytes)
 }
@@ -1,11 +1,16 @@
 package segment
 
 import (
+	"encoding/binary"
+	"fmt"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/block"
+	"github.com/klauspost/compress/s2"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -61,6 +66,60 @@ func TestSortedSegmentWithATwoBlocks(t *testing.T) {
 	assert.Equal(t, 2, segment.noOfBlocks())
 }
 
+func TestSortedSegmentWithAKeyValueLargerThanTheBlockSize(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	largeValue := strings.Repeat("raft", 100)
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp("badgerDB", 20), kv.NewStringValue("LSM"))
+	sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 20), kv.NewStringValue(largeValue))
+	sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp("distributed", 20), kv.NewStringValue("TiKV"))
+
+	segment, blockMetaList, _, err := sortedSegmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, segment.noOfBlocks())
+
+	iterator, err := segment.seekToKey(kv.NewStringKeyWithTimestamp("consensus", 20), blockMetaList)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "consensus", iterator.Key().RawString())
+	assert.Equal(t, largeValue, iterator.Value().String())
+
+	_ = iterator.Next()
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, "distributed", iterator.Key().RawString())
+	assert.Equal(t, "TiKV", iterator.Value().String())
+
+	_ = iterator.Next()
+	assert.False(t, iterator.IsValid())
+}
+
+func TestSortedSegmentWithAKeyLargerThanTheBlockSize(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	defer store.Close()
+
+	sortedSegmentBuilder := newSortedSegmentBuilder(store, 50, false)
+	sortedSegmentBuilder.add(kv.NewStringKeyWithTimestamp(strings.Repeat("consensus", 10), 20), kv.NewStringValue("raft"))
+
+	_, _, _, err = sortedSegmentBuilder.build(1)
+	assert.ErrorIs(t, err, ErrKeyTooLargeForBlock)
+}
+
 func TestLoadSortedSegmentWithSingleBlockContainingMultipleKeyValuePairs(t *testing.T) {
 	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
 	assert.NoError(t, err)
@@ -81,7 +140,7 @@ func TestLoadSortedSegmentWithSingleBlockContainingMultipleKeyValuePairs(t *test
 	_, _, _, err = segmentBuilder.build(segmentId)
 	assert.NoError(t, err)
 
-	segment, blockMetaList, _, err := load(segmentId, block.DefaultBlockSize, false, store)
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
 	assert.NoError(t, err)
 
 	iterator, err := segment.seekToFirst(blockMetaList)
@@ -124,7 +183,7 @@ func TestLoadSortedSegmentWithSingleBlockContainingMultipleKeyValuePairsWithVali
 	_, _, _, err = segmentBuilder.build(segmentId)
 	assert.NoError(t, err)
 
-	segment, _, _, err := load(1, block.DefaultBlockSize, false, store)
+	segment, _, _, err := load(1, DefaultSortedSegmentFormatOptions(), store)
 	assert.NoError(t, err)
 	assert.Equal(t, "consensus", segment.startingKey.RawString())
 	assert.Equal(t, "etcd", segment.endingKey.RawString())
@@ -149,7 +208,7 @@ func TestLoadASortedSegmentWithTwoBlocks(t *testing.T) {
 	_, _, _, err = sortedSegmentBuilder.build(segmentId)
 	assert.NoError(t, err)
 
-	segment, blockMetaList, _, err := load(1, 50, false, store)
+	segment, blockMetaList, _, err := load(1, DefaultSortedSegmentFormatOptions(), store)
 	assert.NoError(t, err)
 
 	iterator, err := segment.seekToFirst(blockMetaList)
@@ -186,7 +245,7 @@ func TestLoadASortedSegmentWithTwoBlocksWithValidationOfStartingAndEndingKey(t *
 	_, _, _, err = sortedSegmentBuilder.build(segmentId)
 	assert.NoError(t, err)
 
-	segment, _, _, err := load(1, 50, false, store)
+	segment, _, _, err := load(1, DefaultSortedSegmentFormatOptions(), store)
 	assert.NoError(t, err)
 	assert.Equal(t, "consensus", segment.startingKey.RawString())
 	assert.Equal(t, "distributed", segment.endingKey.RawString())
@@ -257,3 +316,752 @@ func TestSortedSegmentDoesNotContainTheKeyInItsRangeGivenTheKeyIsGreaterThanTheE
 
 	assert.False(t, segment.containsInItsRange(kv.NewStringKeyWithTimestamp("foundation", 32)))
 }
+
+func TestLoadASortedSegmentWithTheFormatParametersRecordedInTheFooter(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	formatOptions := NewSortedSegmentFormatOptions(256, 0.001, true)
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(store, formatOptions)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("distributed", 20), kv.NewStringValue("TiKV"))
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segment, blockMetaList, bloomFilter, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, formatOptions, segment.formatOptions)
+	assert.True(t, bloomFilter.MayContain(kv.NewStringKeyWithTimestamp("consensus", 10)))
+
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, kv.NewStringValue("raft"), iterator.Value())
+
+	_ = iterator.Next()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, kv.NewStringValue("TiKV"), iterator.Value())
+}
+
+func TestLoadASortedSegmentOfLegacyFormatVersionWithoutTheFormatParameters(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	testRewriteAsLegacySortedSegment(t, segmentId, false)
+
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSortedSegmentFormatOptions(), segment.formatOptions)
+
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, kv.NewStringValue("raft"), iterator.Value())
+}
+
+func TestLoadASortedSegmentOfLegacyFormatVersionWithTheFormatParameters(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(store, NewSortedSegmentFormatOptions(block.DefaultBlockSize, 0.001, true))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	testRewriteAsLegacySortedSegment(t, segmentId, true)
+
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, NewSortedSegmentFormatOptions(block.DefaultBlockSize, 0.001, true), segment.formatOptions)
+
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, kv.NewStringValue("raft"), iterator.Value())
+}
+
+func TestLoadASortedSegmentWithCompressedBlocks(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	compressionCodecs := []block.CompressionCodec{
+		block.CompressionCodecS2,
+		block.CompressionCodecSnappy,
+		block.CompressionCodecZstd,
+		block.CompressionCodecLz4,
+	}
+
+	defer func() {
+		store.Close()
+		for _, compressionCodec := range compressionCodecs {
+			_ = os.Remove(PathSuffixForSegment(uint64(compressionCodec)))
+		}
+	}()
+
+	for _, compressionCodec := range compressionCodecs {
+		segmentId := uint64(compressionCodec)
+		formatOptions := NewSortedSegmentFormatOptionsWithCompressionCodec(256, 0.01, compressionCodec)
+		segmentBuilder := newSortedSegmentBuilderWithFormatOptions(store, formatOptions)
+		segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(strings.Repeat("raft", 10)))
+		segmentBuilder.add(kv.NewStringKeyWithTimestamp("distributed", 20), kv.NewStringValue(strings.Repeat("TiKV", 200)))
+		segmentBuilder.add(kv.NewStringKeyWithTimestamp("etcd", 30), kv.NewStringValue(strings.Repeat("bolt", 10)))
+
+		_, _, _, err = segmentBuilder.build(segmentId)
+		assert.NoError(t, err)
+
+		segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+		assert.NoError(t, err)
+
+		blockMeta, _ := blockMetaList.GetAt(1)
+		assert.Equal(t, compressionCodec, blockMeta.CompressionCodec)
+
+		iterator, err := segment.seekToFirst(blockMetaList)
+		assert.NoError(t, err)
+
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, kv.NewStringValue(strings.Repeat("raft", 10)), iterator.Value())
+
+		_ = iterator.Next()
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, kv.NewStringValue(strings.Repeat("TiKV", 200)), iterator.Value())
+
+		_ = iterator.Next()
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, kv.NewStringValue(strings.Repeat("bolt", 10)), iterator.Value())
+
+		_ = iterator.Next()
+		assert.False(t, iterator.IsValid())
+		iterator.Close()
+	}
+}
+
+func TestCompressionStatsOfASortedSegmentWithCompressedBlocks(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionCodec(256, 0.01, block.CompressionCodecZstd),
+	)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(strings.Repeat("raft", 40)))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("distributed", 20), kv.NewStringValue(strings.Repeat("TiKV", 40)))
+
+	builtSegment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	stats := builtSegment.CompressionStats()
+	assert.Equal(t, 2, stats.NumberOfBlocks)
+	assert.Equal(t, 2, stats.NumberOfCompressedBlocks)
+	assert.Equal(t, uint64(512), stats.UncompressedSizeInBytes)
+	assert.True(t, stats.StoredSizeInBytes < stats.UncompressedSizeInBytes)
+	assert.True(t, stats.Ratio() > 1)
+
+	loadedSegment, _, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, stats, loadedSegment.CompressionStats())
+}
+
+func TestStatisticsOfASortedSegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionCodec(256, 0.01, block.CompressionCodecZstd),
+	)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(strings.Repeat("raft", 40)))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("distributed", 20), kv.NewDeletedValue())
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("storage", 5), kv.NewStringValue(strings.Repeat("TiKV", 40)))
+
+	builtSegment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	statistics, ok := builtSegment.Statistics()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), statistics.NumberOfEntries)
+	assert.Equal(t, uint64(1), statistics.NumberOfTombstones)
+	assert.Equal(t, uint64(5), statistics.MinimumTimestamp)
+	assert.Equal(t, uint64(20), statistics.MaximumTimestamp)
+	assert.Equal(t, uint64(len("consensus")+len("distributed")+len("storage")+320), statistics.RawSizeInBytes)
+	assert.Equal(t, builtSegment.CompressionStats().StoredSizeInBytes, statistics.CompressedSizeInBytes)
+	assert.Equal(t, uint64(3), statistics.KeySizes.Count())
+	assert.Equal(t, uint64(1), statistics.ValueSizes[0])
+	assert.Equal(t, uint64(2), statistics.ValueSizes[8])
+
+	loadedSegment, _, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+
+	loadedStatistics, ok := loadedSegment.Statistics()
+	assert.True(t, ok)
+	assert.Equal(t, statistics, loadedStatistics)
+}
+
+func TestTimestampRangeOfASortedSegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("distributed", 20), kv.NewStringValue("TiKV"))
+
+	segment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	assert.True(t, segment.MayContainVersionsVisibleAt(10))
+	assert.True(t, segment.MayContainVersionsVisibleAt(25))
+	assert.False(t, segment.MayContainVersionsVisibleAt(9))
+
+	assert.True(t, segment.ContainsOnlyVersionsOlderThan(21))
+	assert.False(t, segment.ContainsOnlyVersionsOlderThan(20))
+}
+
+func TestStatisticsOfASortedSegmentOfLegacyFormatVersion(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	testRewriteAsLegacySortedSegment(t, segmentId, false)
+
+	segment, _, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+
+	_, ok := segment.Statistics()
+	assert.False(t, ok)
+	assert.True(t, segment.MayContainVersionsVisibleAt(0))
+	assert.False(t, segment.ContainsOnlyVersionsOlderThan(100))
+}
+
+func TestCompressionStatsOfASortedSegmentOfLegacyFormatVersion(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, true)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	testRewriteAsLegacySortedSegment(t, segmentId, true)
+
+	segment, _, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+
+	stats := segment.CompressionStats()
+	assert.Equal(t, 1, stats.NumberOfBlocks)
+	assert.Equal(t, 0, stats.NumberOfCompressedBlocks)
+	assert.Equal(t, uint64(block.DefaultBlockSize), stats.UncompressedSizeInBytes)
+	assert.Equal(t, uint64(block.DefaultBlockSize), stats.StoredSizeInBytes)
+	assert.Equal(t, float64(1), stats.Ratio())
+}
+
+func TestLoadASortedSegmentWithACompressionDictionary(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	keyValues := testJsonKeyValues(200)
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionDictionary(block.DefaultBlockSize, 0.01, 1024),
+	)
+	for _, keyValue := range keyValues {
+		segmentBuilder.add(keyValue.key, keyValue.value)
+	}
+	builtSegment, _, _, err := segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	assert.NotNil(t, segmentBuilder.compressionDictionary)
+
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.True(t, segment.hasCompressionDictionary)
+	assert.Equal(t, builtSegment.CompressionStats(), segment.CompressionStats())
+	assert.Equal(t, segment.noOfBlocks(), segment.CompressionStats().NumberOfCompressedBlocks)
+
+	for blockIndex := 0; blockIndex < blockMetaList.Length(); blockIndex++ {
+		blockMeta, _ := blockMetaList.GetAt(blockIndex)
+		assert.Equal(t, block.CompressionCodecZstdDictionary, blockMeta.CompressionCodec)
+	}
+
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	for _, keyValue := range keyValues {
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, keyValue.key.RawString(), iterator.Key().RawString())
+		assert.Equal(t, keyValue.value, iterator.Value())
+		_ = iterator.Next()
+	}
+	assert.False(t, iterator.IsValid())
+}
+
+func TestCompressionDictionaryCompressesBetterThanZstdForSmallJsonValues(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentIdWithDictionary, segmentIdWithoutDictionary := uint64(1), uint64(2)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentIdWithDictionary))
+		_ = os.Remove(PathSuffixForSegment(segmentIdWithoutDictionary))
+	}()
+
+	keyValues := testJsonKeyValues(200)
+	segmentBuilderWithDictionary := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionDictionary(block.DefaultBlockSize, 0.01, 1024),
+	)
+	segmentBuilderWithoutDictionary := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionCodec(block.DefaultBlockSize, 0.01, block.CompressionCodecZstd),
+	)
+	for _, keyValue := range keyValues {
+		segmentBuilderWithDictionary.add(keyValue.key, keyValue.value)
+		segmentBuilderWithoutDictionary.add(keyValue.key, keyValue.value)
+	}
+	segmentWithDictionary, _, _, err := segmentBuilderWithDictionary.build(segmentIdWithDictionary)
+	assert.NoError(t, err)
+	segmentWithoutDictionary, _, _, err := segmentBuilderWithoutDictionary.build(segmentIdWithoutDictionary)
+	assert.NoError(t, err)
+
+	assert.True(t, segmentWithDictionary.CompressionStats().Ratio() > segmentWithoutDictionary.CompressionStats().Ratio())
+}
+
+func TestSortedSegmentWithoutCompressionDictionaryGivenNoValuesToSample(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionDictionary(block.DefaultBlockSize, 0.01, 1024),
+	)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewDeletedValue())
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	assert.Nil(t, segmentBuilder.compressionDictionary)
+
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.False(t, segment.hasCompressionDictionary)
+
+	iterator, err := segment.seekToFirst(blockMetaList)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.True(t, iterator.Value().IsDeleted())
+}
+
+func TestLoadASortedSegmentWithBlockHashIndexAndSeekToEachKey(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	keyValues := testJsonKeyValues(200)
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptions(block.DefaultBlockSize, 0.01, true).WithBlockHashIndex(),
+	)
+	for _, keyValue := range keyValues {
+		segmentBuilder.add(keyValue.key, keyValue.value)
+	}
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+
+	for _, keyValue := range keyValues {
+		iterator, err := segment.seekToKey(keyValue.key, blockMetaList)
+		assert.NoError(t, err)
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, keyValue.key.RawString(), iterator.Key().RawString())
+		assert.Equal(t, keyValue.value, iterator.Value())
+		iterator.Close()
+	}
+}
+
+func TestLoadASortedSegmentOfFormatVersion1(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	// each block contains two key/value pairs, which leaves sufficient space in the block to rewrite it in FormatVersion1.
+	var keyValues []testKeyValue
+	for count := 0; count < 10; count++ {
+		keyValues = append(keyValues, testKeyValue{
+			key:   kv.NewStringKeyWithTimestamp(fmt.Sprintf("consensus-%02d", count), 10),
+			value: kv.NewStringValue(strings.Repeat(fmt.Sprintf("raft-%d", count), 250)),
+		})
+	}
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(store, NewSortedSegmentFormatOptions(block.DefaultBlockSize, 0.01, true))
+	for _, keyValue := range keyValues {
+		segmentBuilder.add(keyValue.key, keyValue.value)
+	}
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+	testRewriteAsFormatVersion1SortedSegment(t, segmentId)
+
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, block.FormatVersion1, segment.formatVersion)
+	assert.True(t, blockMetaList.Length() > 1)
+
+	for _, keyValue := range keyValues {
+		iterator, err := segment.seekToKey(keyValue.key, blockMetaList)
+		assert.NoError(t, err)
+		assert.True(t, iterator.IsValid())
+		assert.Equal(t, keyValue.key.RawString(), iterator.Key().RawString())
+		assert.Equal(t, keyValue.value, iterator.Value())
+		iterator.Close()
+	}
+}
+
+func TestLoadASortedSegmentWithBlocksLargerThan64KiBAndKeysLargerThan64KiB(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	largeRawKey := strings.Repeat("k", 70*1024)
+	segmentBuilder := newSortedSegmentBuilderWithFormatOptions(store, NewSortedSegmentFormatOptions(256*1024, 0.01, true))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue(strings.Repeat("raft", 50*1024)))
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp(largeRawKey, 10), kv.NewStringValue("etcd"))
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segment, blockMetaList, _, err := load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, block.CurrentFormatVersion, segment.formatVersion)
+
+	iterator, err := segment.seekToKey(kv.NewStringKeyWithTimestamp(largeRawKey, 10), blockMetaList)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, largeRawKey, iterator.Key().RawString())
+	assert.Equal(t, kv.NewStringValue("etcd"), iterator.Value())
+}
+
+func TestAttemptToLoadASortedSegmentOfUnsupportedFormatVersion(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(store, false)
+	segmentBuilder.add(kv.NewStringKeyWithTimestamp("consensus", 10), kv.NewStringValue("raft"))
+
+	_, _, _, err = segmentBuilder.build(segmentId)
+	assert.NoError(t, err)
+
+	segmentBytes, err := os.ReadFile(PathSuffixForSegment(segmentId))
+	assert.NoError(t, err)
+
+	trailer, ok := block.DecodeToTrailer(segmentBytes)
+	assert.True(t, ok)
+	trailer.Version = block.CurrentFormatVersion + 1
+	copy(segmentBytes[len(segmentBytes)-block.TrailerSize:], trailer.Encode())
+	assert.NoError(t, os.WriteFile(PathSuffixForSegment(segmentId), segmentBytes, 0644))
+
+	_, _, _, err = load(segmentId, DefaultSortedSegmentFormatOptions(), store)
+	assert.ErrorIs(t, err, block.ErrUnsupportedFormatVersion)
+}
+
+// testRewriteAsLegacySortedSegment rewrites the persistent sorted segment in block.FormatVersionLegacy, the data blocks are
+// decompressed and rewritten with uint16 offsets, the block meta list is rewritten without the compression codecs, and the
+// compact footer block and the trailer are replaced with a footer block of block size.
+func testRewriteAsLegacySortedSegment(t *testing.T, segmentId uint64, withFormatParameters bool) {
+	segmentBytes, err := os.ReadFile(PathSuffixForSegment(segmentId))
+	assert.NoError(t, err)
+
+	footerBlock, enableCompression := testDecodeFooterBlock(t, segmentBytes)
+	legacySegmentBytes, legacyBlockMetaListBytes := testRewriteBlocksWithFixedWidths(t, segmentBytes, footerBlock, enableCompression, false)
+
+	offsetAt := func(index uint) uint64 {
+		offset, _ := footerBlock.GetOffsetAt(index)
+		return offset
+	}
+	legacyFooterBlock := block.NewFooterBlock(footerBlock.BlockSize())
+	legacyFooterBlock.AddOffset(uint64(len(legacySegmentBytes)))
+	legacySegmentBytes = append(legacySegmentBytes, legacyBlockMetaListBytes...)
+	legacyFooterBlock.AddOffset(uint64(len(legacySegmentBytes)))
+	legacyFooterBlock.AddOffset(uint64(len(legacySegmentBytes)))
+	legacySegmentBytes = append(legacySegmentBytes, segmentBytes[offsetAt(2):offsetAt(3)]...)
+	legacyFooterBlock.AddOffset(uint64(len(legacySegmentBytes)))
+
+	if withFormatParameters {
+		legacyFooterBlock.SetFormatParameters(footerBlock.BloomFilterFalsePositiveRate(), footerBlock.IsCompressionEnabled())
+	}
+	legacySegmentBytes = append(legacySegmentBytes, legacyFooterBlock.Encode()...)
+	assert.NoError(t, os.WriteFile(PathSuffixForSegment(segmentId), legacySegmentBytes, 0644))
+}
+
+// testRewriteAsFormatVersion1SortedSegment rewrites the persistent sorted segment in block.FormatVersion1 (without
+// checksums), the data blocks are decompressed and rewritten with uint16 offsets, the block meta list is rewritten with
+// fixed widths, and the compact footer block is rewritten with uint32 offsets.
+func testRewriteAsFormatVersion1SortedSegment(t *testing.T, segmentId uint64) {
+	segmentBytes, err := os.ReadFile(PathSuffixForSegment(segmentId))
+	assert.NoError(t, err)
+
+	footerBlock, enableCompression := testDecodeFooterBlock(t, segmentBytes)
+	rewrittenSegmentBytes, blockMetaListBytes := testRewriteBlocksWithFixedWidths(t, segmentBytes, footerBlock, enableCompression, true)
+
+	offsetAt := func(index uint) uint64 {
+		offset, _ := footerBlock.GetOffsetAt(index)
+		return offset
+	}
+	var footerBlockBytes []byte
+	footerBlockBytes = binary.LittleEndian.AppendUint16(footerBlockBytes, 4)
+	footerBlockBytes = binary.LittleEndian.AppendUint32(footerBlockBytes, uint32(len(rewrittenSegmentBytes)))
+	rewrittenSegmentBytes = append(rewrittenSegmentBytes, blockMetaListBytes...)
+	footerBlockBytes = binary.LittleEndian.AppendUint32(footerBlockBytes, uint32(len(rewrittenSegmentBytes)))
+	footerBlockBytes = binary.LittleEndian.AppendUint32(footerBlockBytes, uint32(len(rewrittenSegmentBytes)))
+	rewrittenSegmentBytes = append(rewrittenSegmentBytes, segmentBytes[offsetAt(2):offsetAt(3)]...)
+	footerBlockBytes = binary.LittleEndian.AppendUint32(footerBlockBytes, uint32(len(rewrittenSegmentBytes)))
+	footerBlockBytes = binary.LittleEndian.AppendUint32(footerBlockBytes, uint32(footerBlock.BlockSize()))
+	footerBlockBytes = binary.LittleEndian.AppendUint64(footerBlockBytes, math.Float64bits(footerBlock.BloomFilterFalsePositiveRate()))
+
+	flags := block.TrailerFlagBlockCompressionCodecs
+	if enableCompression {
+		flags |= block.TrailerFlagBlockMetaListCompressed
+	}
+	trailer := block.NewTrailer(uint32(len(footerBlockBytes)), flags)
+	trailer.Version = block.FormatVersion1
+
+	rewrittenSegmentBytes = append(rewrittenSegmentBytes, footerBlockBytes...)
+	rewrittenSegmentBytes = append(rewrittenSegmentBytes, trailer.Encode()...)
+	assert.NoError(t, os.WriteFile(PathSuffixForSegment(segmentId), rewrittenSegmentBytes, 0644))
+}
+
+// testDecodeFooterBlock decodes the compact footer block of the persistent sorted segment of block.CurrentFormatVersion,
+// and returns it along with the compression flag of the block meta list.
+func testDecodeFooterBlock(t *testing.T, segmentBytes []byte) (*block.FooterBlock, bool) {
+	trailer, ok := block.DecodeToTrailer(segmentBytes)
+	assert.True(t, ok)
+
+	footerBlockBeginOffset := len(segmentBytes) - block.TrailerSize - int(trailer.FooterLength)
+	footerBlockBytes, ok := block.VerifyAndStripChecksum(segmentBytes[footerBlockBeginOffset : len(segmentBytes)-block.TrailerSize])
+	assert.True(t, ok)
+
+	enableCompression := trailer.HasFlag(block.TrailerFlagBlockMetaListCompressed)
+	footerBlock, err := block.DecodeToCompactFooterBlock(footerBlockBytes, enableCompression)
+	assert.NoError(t, err)
+	return footerBlock, enableCompression
+}
+
+// testRewriteBlocksWithFixedWidths decompresses the data blocks of the persistent sorted segment of block.CurrentFormatVersion,
+// and rewrites them with uint16 offsets (please check testEncodeAsLegacyBlock).
+// It returns the rewritten data blocks along with the block meta list encoded with fixed widths, the block meta list records
+// the compression codecs (as block.CompressionCodecNone) only if withCompressionCodecs is true.
+func testRewriteBlocksWithFixedWidths(
+	t *testing.T,
+	segmentBytes []byte,
+	footerBlock *block.FooterBlock,
+	enableCompression bool,
+	withCompressionCodecs bool,
+) ([]byte, []byte) {
+	offsetAt := func(index uint) uint64 {
+		offset, _ := footerBlock.GetOffsetAt(index)
+		return offset
+	}
+	blockMetaList, err := block.DecodeToBlockMetaList(segmentBytes[offsetAt(0):offsetAt(1)], enableCompression)
+	assert.NoError(t, err)
+
+	var blocksBytes, blockMetaListBytes []byte
+	blockMetaListBytes = binary.LittleEndian.AppendUint32(blockMetaListBytes, uint32(blockMetaList.Length()))
+	for blockIndex := 0; blockIndex < blockMetaList.Length(); blockIndex++ {
+		blockMeta, _ := blockMetaList.GetAt(blockIndex)
+		endOffset := offsetAt(0)
+		if nextBlockMeta, ok := blockMetaList.GetAt(blockIndex + 1); ok {
+			endOffset = nextBlockMeta.BlockBeginOffset
+		}
+		blockBytes, err := block.Decompress(blockMeta.CompressionCodec, segmentBytes[blockMeta.BlockBeginOffset:endOffset], int(blockMeta.UncompressedSize))
+		assert.NoError(t, err)
+		blockBytes = testEncodeAsLegacyBlock(t, blockBytes, footerBlock.BlockSize())
+
+		blockMetaListBytes = binary.LittleEndian.AppendUint32(blockMetaListBytes, uint32(len(blocksBytes)))
+		if withCompressionCodecs {
+			blockMetaListBytes = append(blockMetaListBytes, byte(block.CompressionCodecNone))
+			blockMetaListBytes = binary.LittleEndian.AppendUint32(blockMetaListBytes, uint32(len(blockBytes)))
+		}
+		for _, key := range []kv.Key{blockMeta.StartingKey, blockMeta.EndingKey} {
+			blockMetaListBytes = binary.LittleEndian.AppendUint16(blockMetaListBytes, uint16(key.EncodedSizeInBytes()))
+			blockMetaListBytes = append(blockMetaListBytes, key.EncodedBytes()...)
+		}
+		blocksBytes = append(blocksBytes, blockBytes...)
+	}
+	if enableCompression {
+		blockMetaListBytes = s2.Encode(nil, blockMetaListBytes)
+	}
+	return blocksBytes, blockMetaListBytes
+}
+
+// testEncodeAsLegacyBlock re-encodes the given block (of block.CurrentFormatVersion) in block.FormatVersionLegacy, where each
+// key/value pair is a restart point, and the offsets are uint16. The block must not contain overflow bytes.
+func testEncodeAsLegacyBlock(t *testing.T, blockBytes []byte, blockSize uint) []byte {
+	decodedBlock, err := block.DecodeToBlockWithOverflow(blockBytes, blockSize)
+	assert.NoError(t, err)
+
+	var data, restartPoints []byte
+	iterator := decodedBlock.SeekToFirst()
+	for ; iterator.IsValid(); _ = iterator.Next() {
+		restartPoints = binary.LittleEndian.AppendUint16(restartPoints, uint16(len(data)))
+		data = binary.LittleEndian.AppendUint16(data, uint16(iterator.Key().EncodedSizeInBytes()))
+		data = append(data, iterator.Key().EncodedBytes()...)
+		data = binary.LittleEndian.AppendUint32(data, iterator.Value().SizeAsUint32())
+		data = append(data, iterator.Value().EncodedBytes()...)
+	}
+	numberOfRestartPoints := len(restartPoints) / block.Uint16Size
+	startOfRestartPoints := len(data)
+	data = append(data, restartPoints...)
+	assert.True(t, len(data)+2*block.Uint16Size <= int(blockSize))
+
+	legacyBlock := make([]byte, blockSize)
+	copy(legacyBlock, data)
+	binary.LittleEndian.PutUint16(legacyBlock[blockSize-uint(block.Uint16Size):], uint16(numberOfRestartPoints))
+	binary.LittleEndian.PutUint16(legacyBlock[blockSize-uint(2*block.Uint16Size):], uint16(startOfRestartPoints))
+	return legacyBlock
+}
+
+type testKeyValue struct {
+	key   kv.Key
+	value kv.Value
+}
+
+// testJsonKeyValues returns the given number of key/value pairs (sorted by key) with small JSON documents as values.
+func testJsonKeyValues(count int) []testKeyValue {
+	keyValues := make([]testKeyValue, 0, count)
+	for id := 0; id < count; id++ {
+		keyValues = append(keyValues, testKeyValue{
+			key: kv.NewStringKeyWithTimestamp(fmt.Sprintf("user-%04d", id), 10),
+			value: kv.NewStringValue(fmt.Sprintf(
+				`{"id":%d,"name":"user-%d","email":"user%d@zero-store.io","active":true,"roles":["reader","writer"]}`, id, id*7, id),
+			),
+		})
+	}
+	return keyValues
+}
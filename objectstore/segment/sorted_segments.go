@@ -1,30 +1,62 @@
 package segment
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/SarthakMakhija/zero-store/cache"
 	"github.com/SarthakMakhija/zero-store/iterator"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/block"
 	"github.com/SarthakMakhija/zero-store/objectstore/filter"
+	"github.com/SarthakMakhija/zero-store/objectstore/valuelog"
+	"golang.org/x/sync/singleflight"
 	"sort"
+	"sync/atomic"
 )
 
 var (
-	ErrNoSegmentForTheSegmentId = errors.New("no segment for the given id")
-	ErrEmptySegment             = errors.New("empty segment")
+	ErrNoSegmentForTheSegmentId  = errors.New("no segment for the given id")
+	ErrEmptySegment              = errors.New("empty segment")
+	ErrNoStatisticsForTheSegment = errors.New("no statistics for the segment")
 )
 
+// SortedSegments is a collection of persistent sorted segments (SortedSegment).
+// Values larger than valueSeparationThresholdInBytes are separated from the keys, they are stored in value logs
+// (valuelog.ValueLogs) and the SortedSegment only contains a valuelog.Pointer to the value.
+// A valueSeparationThresholdInBytes of 0 disables the key-value separation.
+// readaheadOptions configure the readahead of the Iterators of all the SortedSegments, please check ReadaheadOptions.
+// ioScheduler is the objectstore.IOScheduler of the Db, the reads of the readahead are scheduled on it.
+// segmentSetVersion is the version of the set of the persistent segments, it moves forward every time a SortedSegment is
+// added (or replaced), please check SegmentSetVersion.
 type SortedSegments struct {
-	persistentSegments map[uint64]SortedSegment
-	store              objectstore.Store
-	bloomFilterCache   cache.BloomFilterCache
-	blockMetaListCache cache.BlockMetaListCache
-	enableCompression  bool
+	persistentSegments              map[uint64]SortedSegment
+	segmentSetVersion               atomic.Uint64
+	store                           objectstore.Store
+	bloomFilterCache                cache.BloomFilterCache
+	blockMetaListCache              cache.BlockMetaListCache
+	compressionDictionaryCache      *cache.CompressionDictionaryCache
+	blockCache                      *cache.BlockCache
+	fetches                         *singleflight.Group
+	readaheadOptions                ReadaheadOptions
+	ioScheduler                     *objectstore.IOScheduler
+	valueLogs                       *valuelog.ValueLogs
+	formatOptions                   SortedSegmentFormatOptions
+	valueSeparationThresholdInBytes uint
 }
 
-func NewSortedSegments(store objectstore.Store, options SortedSegmentCacheOptions, enableCompression bool) (*SortedSegments, error) {
+func NewSortedSegments(
+	store objectstore.Store,
+	options SortedSegmentCacheOptions,
+	formatOptions SortedSegmentFormatOptions,
+	valueSeparationThresholdInBytes uint,
+	readaheadOptions ReadaheadOptions,
+	ioScheduler *objectstore.IOScheduler,
+) (*SortedSegments, error) {
+	if readaheadOptions.isEnabled() && ioScheduler == nil {
+		panic("readahead requires an io scheduler")
+	}
 	bloomFilterCache, err := cache.NewBloomFilterCache(options.bloomFilterCacheOptions)
 	if err != nil {
 		return nil, err
@@ -33,44 +65,97 @@ func NewSortedSegments(store objectstore.Store, options SortedSegmentCacheOption
 	if err != nil {
 		return nil, err
 	}
+	compressionDictionaryCache, err := cache.NewCompressionDictionaryCache(options.compressionDictionaryCacheOptions)
+	if err != nil {
+		return nil, err
+	}
+	blockCache, err := cache.NewBlockCache(options.blockCacheOptions)
+	if err != nil {
+		return nil, err
+	}
 	return &SortedSegments{
-		persistentSegments: make(map[uint64]SortedSegment),
-		store:              store,
-		bloomFilterCache:   bloomFilterCache,
-		blockMetaListCache: blockMetaListCache,
-		enableCompression:  enableCompression,
+		persistentSegments:              make(map[uint64]SortedSegment),
+		store:                           store,
+		bloomFilterCache:                bloomFilterCache,
+		blockMetaListCache:              blockMetaListCache,
+		compressionDictionaryCache:      &compressionDictionaryCache,
+		blockCache:                      &blockCache,
+		fetches:                         &singleflight.Group{},
+		readaheadOptions:                readaheadOptions,
+		ioScheduler:                     ioScheduler,
+		valueLogs:                       valuelog.NewValueLogs(store),
+		formatOptions:                   formatOptions,
+		valueSeparationThresholdInBytes: valueSeparationThresholdInBytes,
 	}, nil
 }
 
+// BuildAndWritePersistentSortedSegment builds the SortedSegment from the key/value pairs of the given iterator and writes it
+// to the object store.
+// Values larger than valueSeparationThresholdInBytes are written to the value log (with the same id as the segment id)
+// before the SortedSegment is written, so a SortedSegment never points to a missing value log.
 func (sortedSegments *SortedSegments) BuildAndWritePersistentSortedSegment(iterator iterator.Iterator, segmentId uint64) (SortedSegment, error) {
-	sortedSegmentBuilder := newSortedSegmentBuilderWithDefaultBlockSize(sortedSegments.store, sortedSegments.enableCompression)
+	sortedSegmentBuilder := newSortedSegmentBuilderWithFormatOptions(sortedSegments.store, sortedSegments.formatOptions)
+	valueLogBuilder := valuelog.NewBuilder(segmentId)
 	for iterator.IsValid() {
-		sortedSegmentBuilder.add(iterator.Key(), iterator.Value())
+		value := iterator.Value()
+		if sortedSegments.shouldSeparate(value) {
+			value = kv.NewValuePointer(valueLogBuilder.Add(value.Bytes()).Encode())
+		}
+		sortedSegmentBuilder.add(iterator.Key(), value)
 		if err := iterator.Next(); err != nil {
 			return EmptySortedSegment, err
 		}
 	}
+	if err := sortedSegments.valueLogs.Write(valueLogBuilder); err != nil {
+		return EmptySortedSegment, err
+	}
 	persistentSortedSegment, blockMetaList, bloomFilter, err := sortedSegmentBuilder.build(segmentId)
 	if err != nil {
 		return EmptySortedSegment, err
 	}
+	if sortedSegmentBuilder.compressionDictionary != nil {
+		sortedSegments.compressionDictionaryCache.Set(segmentId, sortedSegmentBuilder.compressionDictionary)
+	}
+	persistentSortedSegment.compressionDictionaryCache = sortedSegments.compressionDictionaryCache
+	persistentSortedSegment.blockCache = sortedSegments.blockCache
+	persistentSortedSegment.fetches = sortedSegments.fetches
+	persistentSortedSegment.readaheadOptions = sortedSegments.readaheadOptions
+	persistentSortedSegment.ioScheduler = sortedSegments.ioScheduler
+	persistentSortedSegment.valueLogs = sortedSegments.valueLogs
 	sortedSegments.updateState(segmentId, persistentSortedSegment, bloomFilter, blockMetaList)
 	return persistentSortedSegment, nil
 }
 
-func (sortedSegments *SortedSegments) Load(segmentId uint64, blockSize uint, enableCompression bool) (SortedSegment, error) {
+// Load loads the SortedSegment with the given segment id from the object store.
+// The format parameters are read from the SortedSegment, the format options of SortedSegments are used only for the
+// SortedSegment which does not record the format parameters.
+// The value log of the SortedSegment (if any) is registered before the SortedSegment, so the separated values of a loaded
+// SortedSegment are always resolvable.
+func (sortedSegments *SortedSegments) Load(segmentId uint64) (SortedSegment, error) {
 	sortedSegment, ok := sortedSegments.persistentSegments[segmentId]
 	if ok {
 		return sortedSegment, nil
 	}
-	sortedSegment, blockMetaList, bloomFilter, err := load(segmentId, blockSize, enableCompression, sortedSegments.store)
+	sortedSegment, blockMetaList, bloomFilter, err := load(segmentId, sortedSegments.formatOptions, sortedSegments.store)
 	if err != nil {
 		return EmptySortedSegment, err
 	}
+	if err := sortedSegments.valueLogs.Load(segmentId); err != nil {
+		return EmptySortedSegment, err
+	}
+	sortedSegment.compressionDictionaryCache = sortedSegments.compressionDictionaryCache
+	sortedSegment.blockCache = sortedSegments.blockCache
+	sortedSegment.fetches = sortedSegments.fetches
+	sortedSegment.readaheadOptions = sortedSegments.readaheadOptions
+	sortedSegment.ioScheduler = sortedSegments.ioScheduler
+	sortedSegment.valueLogs = sortedSegments.valueLogs
 	sortedSegments.updateState(segmentId, sortedSegment, bloomFilter, blockMetaList)
 	return sortedSegment, nil
 }
 
+// SeekToFirst returns the Iterator positioned at the first key of the SortedSegment with the given segment id.
+// The Iterator returns the separated values as encoded valuelog.Pointers from Iterator.Value, please use
+// Iterator.ResolvedValue to read the values of a scan.
 func (sortedSegments *SortedSegments) SeekToFirst(segmentId uint64) (*Iterator, error) {
 	sortedSegment, blockMetaList, err := sortedSegments.getBlockMetaListFor(segmentId)
 	if err != nil {
@@ -80,6 +165,13 @@ func (sortedSegments *SortedSegments) SeekToFirst(segmentId uint64) (*Iterator,
 }
 
 func (sortedSegments *SortedSegments) SeekToKey(key kv.Key, sortedSegment SortedSegment) (*Iterator, error) {
+	return sortedSegments.SeekToKeyWithContext(context.Background(), key, sortedSegment)
+}
+
+// SeekToKeyWithContext seeks to the given key in the given SortedSegment, the block reads are abandoned if the given
+// context is cancelled. It allows the concurrent reads (please check objectstore.IOScheduler) to be cancelled once their
+// results are no longer needed.
+func (sortedSegments *SortedSegments) SeekToKeyWithContext(ctx context.Context, key kv.Key, sortedSegment SortedSegment) (*Iterator, error) {
 	if sortedSegment.isEmpty() {
 		return nil, ErrEmptySegment
 	}
@@ -87,7 +179,7 @@ func (sortedSegments *SortedSegments) SeekToKey(key kv.Key, sortedSegment Sorted
 	if err != nil {
 		return nil, err
 	}
-	return sortedSegment.seekToKey(key, blockMetaList)
+	return sortedSegment.seekToKeyWithContext(ctx, key, blockMetaList)
 }
 
 func (sortedSegments *SortedSegments) MayContain(key kv.Key, sortedSegment SortedSegment) (bool, error) {
@@ -104,6 +196,114 @@ func (sortedSegments *SortedSegments) MayContain(key kv.Key, sortedSegment Sorte
 	return bloomFilter.MayContain(key), nil
 }
 
+// ResolveValue returns the actual value, if the given value is a pointer to a value stored in the value log.
+// It returns the given value otherwise.
+func (sortedSegments *SortedSegments) ResolveValue(value kv.Value) (kv.Value, error) {
+	return resolveValue(sortedSegments.valueLogs, value)
+}
+
+// resolveValue returns the given value if it is not a valuelog.Pointer, else it reads the value from the given value logs.
+// A SortedSegment which is not built (or loaded) by SortedSegments does not have value logs, its separated values can not
+// be resolved.
+func resolveValue(valueLogs *valuelog.ValueLogs, value kv.Value) (kv.Value, error) {
+	if !value.IsValuePointer() {
+		return value, nil
+	}
+	pointer, err := valuelog.DecodeToPointer(value.Bytes())
+	if err != nil {
+		return kv.EmptyValue, err
+	}
+	if valueLogs == nil {
+		return kv.EmptyValue, fmt.Errorf("%w: %v", valuelog.ErrNoValueLogForTheLogId, pointer.LogId)
+	}
+	resolvedValue, err := valueLogs.Get(pointer)
+	if err != nil {
+		return kv.EmptyValue, err
+	}
+	return kv.NewValue(resolvedValue), nil
+}
+
+// CollectValueLogGarbage rewrites the value logs with live ratio less than liveRatioThreshold.
+// A value in a value log is live if any of the given persistent sorted segments points to it.
+// All the versions of a key are considered live, because reads at an older timestamp may still need them.
+// It returns the ids of the value logs which were rewritten or deleted.
+// Every value log is a candidate for the garbage collection, so it must not run concurrently with
+// BuildAndWritePersistentSortedSegment: the value log of a SortedSegment which is being built is not referred by any of
+// the given segments, and would be deleted.
+func (sortedSegments *SortedSegments) CollectValueLogGarbage(segments []SortedSegment, liveRatioThreshold float64) ([]uint64, error) {
+	var livePointers []valuelog.Pointer
+	for _, sortedSegment := range segments {
+		if sortedSegment.isEmpty() {
+			continue
+		}
+		segmentLivePointers, err := sortedSegments.livePointersOf(sortedSegment)
+		if err != nil {
+			return nil, err
+		}
+		livePointers = append(livePointers, segmentLivePointers...)
+	}
+	return sortedSegments.valueLogs.CollectGarbage(livePointers, liveRatioThreshold)
+}
+
+// livePointersOf returns the value log pointers of all the versions in the given SortedSegment.
+// The Iterator (and its readahead) is closed once the SortedSegment is scanned.
+func (sortedSegments *SortedSegments) livePointersOf(sortedSegment SortedSegment) ([]valuelog.Pointer, error) {
+	blockMetaList, err := sortedSegments.getOrFetchBlockMetaList(sortedSegment)
+	if err != nil {
+		return nil, err
+	}
+	segmentIterator, err := sortedSegment.seekToFirst(blockMetaList)
+	if err != nil {
+		return nil, err
+	}
+	defer segmentIterator.Close()
+
+	var livePointers []valuelog.Pointer
+	for segmentIterator.IsValid() {
+		if segmentIterator.Value().IsValuePointer() {
+			pointer, err := valuelog.DecodeToPointer(segmentIterator.Value().Bytes())
+			if err != nil {
+				return nil, err
+			}
+			livePointers = append(livePointers, pointer)
+		}
+		if err := segmentIterator.Next(); err != nil {
+			return nil, err
+		}
+	}
+	return livePointers, nil
+}
+
+// CompressionStats returns the CompressionStats of the SortedSegment with the given segment id.
+func (sortedSegments *SortedSegments) CompressionStats(segmentId uint64) (CompressionStats, error) {
+	sortedSegment, ok := sortedSegments.persistentSegments[segmentId]
+	if !ok {
+		return CompressionStats{}, ErrNoSegmentForTheSegmentId
+	}
+	return sortedSegment.CompressionStats(), nil
+}
+
+// Statistics returns the block.Statistics of the SortedSegment with the given segment id.
+// It returns ErrNoStatisticsForTheSegment if the SortedSegment was written without the statistics section.
+func (sortedSegments *SortedSegments) Statistics(segmentId uint64) (block.Statistics, error) {
+	sortedSegment, ok := sortedSegments.persistentSegments[segmentId]
+	if !ok {
+		return block.Statistics{}, ErrNoSegmentForTheSegmentId
+	}
+	statistics, ok := sortedSegment.Statistics()
+	if !ok {
+		return block.Statistics{}, fmt.Errorf("%w: segment id %v", ErrNoStatisticsForTheSegment, segmentId)
+	}
+	return statistics, nil
+}
+
+// SegmentSetVersion returns the version of the set of the persistent segments.
+// The version moves forward after a SortedSegment is added (or replaced), so the persistent segments collected after
+// reading a version contain all the SortedSegments of that version.
+func (sortedSegments *SortedSegments) SegmentSetVersion() uint64 {
+	return sortedSegments.segmentSetVersion.Load()
+}
+
 func (sortedSegments *SortedSegments) OrderedSegmentsByDescendingSegmentId() []SortedSegment {
 	allSegments := make([]SortedSegment, 0, len(sortedSegments.persistentSegments))
 	for _, segment := range sortedSegments.persistentSegments {
@@ -127,34 +327,70 @@ func (sortedSegments *SortedSegments) getBlockMetaListFor(segmentId uint64) (Sor
 	return sortedSegment, blockMetaList, nil
 }
 
+// getOrFetchBlockMetaList returns the block.MetaList of the given SortedSegment from the blockMetaListCache, and loads
+// it from the object store (and caches it) if it is not in the cache. Concurrent loads of the same block.MetaList are
+// coalesced into a single load.
 func (sortedSegments *SortedSegments) getOrFetchBlockMetaList(sortedSegment SortedSegment) (*block.MetaList, error) {
 	blockMetaList, ok := sortedSegments.blockMetaListCache.Get(sortedSegment.id)
-	if !ok {
-		blockMetaList, err := loadBlockMetaList(sortedSegment.id, sortedSegment.footerBlock, sortedSegments.enableCompression, sortedSegments.store)
-		if err != nil {
-			return nil, err
-		}
-		sortedSegments.blockMetaListCache.Set(sortedSegment.id, blockMetaList)
+	if ok {
 		return blockMetaList, nil
 	}
-	return blockMetaList, nil
+	return coalescedFetch(
+		context.Background(),
+		sortedSegments.fetches,
+		fmt.Sprintf("block-meta-list/%v", sortedSegment.id),
+		func(ctx context.Context) (*block.MetaList, error) {
+			blockMetaList, err := loadBlockMetaList(
+				sortedSegment.id,
+				sortedSegment.footerBlock,
+				sortedSegment.formatOptions.enableCompression,
+				sortedSegment.withCompressionCodecs,
+				sortedSegment.formatVersion,
+				sortedSegment.checksums,
+				sortedSegments.store,
+			)
+			if err != nil {
+				return nil, err
+			}
+			sortedSegments.blockMetaListCache.Set(sortedSegment.id, blockMetaList)
+			return blockMetaList, nil
+		},
+	)
 }
 
+// getOrFetchBloomFilter returns the filter.BloomFilter of the given SortedSegment from the bloomFilterCache, and loads it
+// from the object store (and caches it) if it is not in the cache. Concurrent loads of the same filter.BloomFilter are
+// coalesced into a single load.
 func (sortedSegments *SortedSegments) getOrFetchBloomFilter(sortedSegment SortedSegment) (filter.BloomFilter, error) {
 	bloomFilter, ok := sortedSegments.bloomFilterCache.Get(sortedSegment.id)
-	if !ok {
-		bloomFilter, err := loadBloomFilter(sortedSegment.id, sortedSegment.footerBlock, sortedSegments.store)
-		if err != nil {
-			return filter.BloomFilter{}, err
-		}
-		sortedSegments.bloomFilterCache.Set(sortedSegment.id, bloomFilter)
+	if ok {
 		return bloomFilter, nil
 	}
-	return bloomFilter, nil
+	return coalescedFetch(
+		context.Background(),
+		sortedSegments.fetches,
+		fmt.Sprintf("bloom-filter/%v", sortedSegment.id),
+		func(ctx context.Context) (filter.BloomFilter, error) {
+			bloomFilter, err := loadBloomFilter(sortedSegment.id, sortedSegment.footerBlock, sortedSegment.checksums, sortedSegments.store)
+			if err != nil {
+				return filter.BloomFilter{}, err
+			}
+			sortedSegments.bloomFilterCache.Set(sortedSegment.id, bloomFilter)
+			return bloomFilter, nil
+		},
+	)
+}
+
+// shouldSeparate returns true if the value needs to be stored in the value log.
+func (sortedSegments *SortedSegments) shouldSeparate(value kv.Value) bool {
+	return sortedSegments.valueSeparationThresholdInBytes > 0 &&
+		!value.IsDeleted() &&
+		uint(len(value.Bytes())) > sortedSegments.valueSeparationThresholdInBytes
 }
 
 func (sortedSegments *SortedSegments) updateState(segmentId uint64, persistentSortedSegment SortedSegment, bloomFilter filter.BloomFilter, blockMetaList *block.MetaList) {
 	sortedSegments.persistentSegments[segmentId] = persistentSortedSegment
 	sortedSegments.bloomFilterCache.Set(segmentId, bloomFilter)
 	sortedSegments.blockMetaListCache.Set(segmentId, blockMetaList)
+	sortedSegments.segmentSetVersion.Add(1)
 }
@@ -3,7 +3,6 @@ package segment
 import (
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/objectstore"
-	"github.com/SarthakMakhija/zero-store/objectstore/block"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -67,7 +66,7 @@ func TestLoadSortedSegmentsWithSingleSegmentCheckKeysForNonExistence(t *testing.
 	)
 	assert.NoError(t, err)
 
-	_, err = segments.Load(segmentId, block.DefaultBlockSize, false)
+	_, err = segments.Load(segmentId)
 	assert.NoError(t, err)
 
 	contain, _ := segments.MayContain(kv.NewStringKeyWithTimestamp("algorithm", 10), segments.sortedSegmentFor(segmentId))
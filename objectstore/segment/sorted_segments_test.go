@@ -6,6 +6,7 @@ import (
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/block"
 	"github.com/SarthakMakhija/zero-store/objectstore/filter"
+	"github.com/SarthakMakhija/zero-store/objectstore/valuelog"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -158,6 +159,45 @@ func TestSortedSegmentsSeekToKeyWithMultipleKeyValues(t *testing.T) {
 	assert.False(t, iterator.IsValid())
 }
 
+func TestSortedSegmentsSeekToKeyReadsTheBlockFromTheBlockCache(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("algorithm", 10), kv.NewStringKeyWithTimestamp("distributed", 10)},
+			values: []kv.Value{kv.NewStringValue("graph"), kv.NewStringValue("foundation")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	iterator, err := segments.SeekToKey(kv.NewStringKeyWithTimestamp("distributed", 11), segments.sortedSegmentFor(segmentId))
+	assert.NoError(t, err)
+	assert.True(t, iterator.IsValid())
+
+	segmentBytes, err := os.ReadFile(PathSuffixForSegment(segmentId))
+	assert.NoError(t, err)
+	segmentBytes[2] ^= 0xFF
+	assert.NoError(t, os.WriteFile(PathSuffixForSegment(segmentId), segmentBytes, 0644))
+
+	iterator, err = segments.SeekToKey(kv.NewStringKeyWithTimestamp("distributed", 11), segments.sortedSegmentFor(segmentId))
+	assert.NoError(t, err)
+	assert.True(t, iterator.IsValid())
+	assert.Equal(t, kv.NewStringValue("foundation"), iterator.Value())
+}
+
 func TestLoadASortedSegmentWithMultipleKeyValues(t *testing.T) {
 	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
 	assert.NoError(t, err)
@@ -182,7 +222,7 @@ func TestLoadASortedSegmentWithMultipleKeyValues(t *testing.T) {
 	)
 	assert.NoError(t, err)
 
-	_, err = segments.Load(segmentId, block.DefaultBlockSize, false)
+	_, err = segments.Load(segmentId)
 	assert.NoError(t, err)
 
 	iterator, err := segments.SeekToFirst(segmentId)
@@ -247,21 +287,424 @@ func TestSortedSegmentsInDescendingOrder(t *testing.T) {
 	assert.Equal(t, uint64(1), orderedSegments[1].id)
 }
 
+func TestSortedSegmentsWithSeparatedValues(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	segments, err := testInstantiateSortedSegmentsWithValueSeparationThreshold(store, 5)
+	assert.NoError(t, err)
+
+	defer func() {
+		segments.RemoveAllPersistentSortedSegmentsIn(".")
+		store.Close()
+	}()
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys: []kv.Key{
+				kv.NewStringKeyWithTimestamp("consensus", 10),
+				kv.NewStringKeyWithTimestamp("storage", 10),
+			},
+			values: []kv.Value{kv.NewStringValue("raft"), kv.NewStringValue("log-structured merge tree")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	iterator, err := segments.SeekToFirst(segmentId)
+	assert.NoError(t, err)
+
+	assert.True(t, iterator.IsValid())
+	assert.False(t, iterator.Value().IsValuePointer())
+	value, err := segments.ResolveValue(iterator.Value())
+	assert.NoError(t, err)
+	assert.Equal(t, kv.NewStringValue("raft"), value)
+
+	_ = iterator.Next()
+
+	assert.True(t, iterator.IsValid())
+	assert.True(t, iterator.Value().IsValuePointer())
+	value, err = segments.ResolveValue(iterator.Value())
+	assert.NoError(t, err)
+	assert.Equal(t, kv.NewStringValue("log-structured merge tree"), value)
+
+	_ = iterator.Next()
+	assert.False(t, iterator.IsValid())
+}
+
+func TestSortedSegmentsScanWithResolvedSeparatedValues(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	segments, err := testInstantiateSortedSegmentsWithValueSeparationThreshold(store, 5)
+	assert.NoError(t, err)
+
+	defer func() {
+		segments.RemoveAllPersistentSortedSegmentsIn(".")
+		store.Close()
+	}()
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys: []kv.Key{
+				kv.NewStringKeyWithTimestamp("consensus", 10),
+				kv.NewStringKeyWithTimestamp("storage", 10),
+			},
+			values: []kv.Value{kv.NewStringValue("raft"), kv.NewStringValue("log-structured merge tree")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	iterator, err := segments.SeekToFirst(segmentId)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	var values []kv.Value
+	for iterator.IsValid() {
+		value, err := iterator.ResolvedValue()
+		assert.NoError(t, err)
+		values = append(values, value)
+		assert.NoError(t, iterator.Next())
+	}
+	assert.Equal(t, []kv.Value{kv.NewStringValue("raft"), kv.NewStringValue("log-structured merge tree")}, values)
+}
+
+func TestCollectValueLogGarbageWithLiveSegments(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	segments, err := testInstantiateSortedSegmentsWithValueSeparationThreshold(store, 5)
+	assert.NoError(t, err)
+
+	defer func() {
+		segments.RemoveAllPersistentSortedSegmentsIn(".")
+		store.Close()
+	}()
+
+	sortedSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("storage", 10)},
+			values: []kv.Value{kv.NewStringValue("log-structured merge tree")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	collectedLogIds, err := segments.CollectValueLogGarbage([]SortedSegment{sortedSegment}, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(collectedLogIds))
+
+	iterator, err := segments.SeekToFirst(segmentId)
+	assert.NoError(t, err)
+
+	value, err := segments.ResolveValue(iterator.Value())
+	assert.NoError(t, err)
+	assert.Equal(t, kv.NewStringValue("log-structured merge tree"), value)
+}
+
+func TestCollectValueLogGarbageWithoutLiveSegments(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	segments, err := testInstantiateSortedSegmentsWithValueSeparationThreshold(store, 5)
+	assert.NoError(t, err)
+
+	defer func() {
+		segments.RemoveAllPersistentSortedSegmentsIn(".")
+		store.Close()
+	}()
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("storage", 10)},
+			values: []kv.Value{kv.NewStringValue("log-structured merge tree")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	collectedLogIds, err := segments.CollectValueLogGarbage(nil, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{segmentId}, collectedLogIds)
+}
+
+func TestLoadASortedSegmentWithSeparatedValuesAfterCollectingValueLogGarbage(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	segments, err := testInstantiateSortedSegmentsWithValueSeparationThreshold(store, 5)
+	assert.NoError(t, err)
+
+	reloadedSegments, err := testInstantiateSortedSegmentsWithValueSeparationThreshold(store, 5)
+	assert.NoError(t, err)
+
+	defer func() {
+		reloadedSegments.RemoveAllPersistentSortedSegmentsIn(".")
+		store.Close()
+	}()
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys: []kv.Key{
+				kv.NewStringKeyWithTimestamp("consensus", 10),
+				kv.NewStringKeyWithTimestamp("storage", 10),
+			},
+			values: []kv.Value{kv.NewStringValue("replicated log"), kv.NewStringValue("log-structured merge tree")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	iterator, err := segments.SeekToFirst(segmentId)
+	assert.NoError(t, err)
+	_ = iterator.Next()
+	storagePointer, err := valuelog.DecodeToPointer(iterator.Value().Bytes())
+	assert.NoError(t, err)
+	iterator.Close()
+
+	collectedLogIds, err := segments.valueLogs.CollectGarbage([]valuelog.Pointer{storagePointer}, 0.9)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{segmentId}, collectedLogIds)
+
+	_, err = reloadedSegments.Load(segmentId)
+	assert.NoError(t, err)
+
+	iterator, err = reloadedSegments.SeekToFirst(segmentId)
+	assert.NoError(t, err)
+	defer iterator.Close()
+
+	_ = iterator.Next()
+	assert.True(t, iterator.IsValid())
+	value, err := reloadedSegments.ResolveValue(iterator.Value())
+	assert.NoError(t, err)
+	assert.Equal(t, kv.NewStringValue("log-structured merge tree"), value)
+}
+
+func TestSortedSegmentsCompressionStats(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	stats, err := segments.CompressionStats(segmentId)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.NumberOfBlocks)
+	assert.Equal(t, 0, stats.NumberOfCompressedBlocks)
+	assert.Equal(t, uint64(block.DefaultBlockSize), stats.UncompressedSizeInBytes)
+	assert.Equal(t, uint64(block.DefaultBlockSize), stats.StoredSizeInBytes)
+
+	_, err = segments.CompressionStats(segmentId + 1)
+	assert.ErrorIs(t, err, ErrNoSegmentForTheSegmentId)
+}
+
+func TestSortedSegmentsStatistics(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("paxos", 20), kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewDeletedValue(), kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	statistics, err := segments.Statistics(segmentId)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), statistics.NumberOfEntries)
+	assert.Equal(t, uint64(1), statistics.NumberOfTombstones)
+	assert.Equal(t, uint64(10), statistics.MinimumTimestamp)
+	assert.Equal(t, uint64(20), statistics.MaximumTimestamp)
+	assert.Equal(t, uint64(block.DefaultBlockSize), statistics.CompressedSizeInBytes)
+
+	_, err = segments.Statistics(segmentId + 1)
+	assert.ErrorIs(t, err, ErrNoSegmentForTheSegmentId)
+}
+
+func TestSortedSegmentsWithACompressionDictionary(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegmentsWithFormatOptions(
+		store,
+		NewSortedSegmentFormatOptionsWithCompressionDictionary(block.DefaultBlockSize, 0.01, 1024),
+		0,
+	)
+	assert.NoError(t, err)
+
+	keyValues := testJsonKeyValues(200)
+	iterator := &testKeyValueIterator{}
+	for _, keyValue := range keyValues {
+		iterator.keys = append(iterator.keys, keyValue.key)
+		iterator.values = append(iterator.values, keyValue.value)
+	}
+	_, err = segments.BuildAndWritePersistentSortedSegment(iterator, segmentId)
+	assert.NoError(t, err)
+
+	_, ok := segments.compressionDictionaryCache.Get(segmentId)
+	assert.True(t, ok)
+
+	segmentIterator, err := segments.SeekToFirst(segmentId)
+	assert.NoError(t, err)
+	defer segmentIterator.Close()
+
+	for _, keyValue := range keyValues {
+		assert.True(t, segmentIterator.IsValid())
+		assert.Equal(t, keyValue.key.RawString(), segmentIterator.Key().RawString())
+		assert.Equal(t, keyValue.value, segmentIterator.Value())
+		_ = segmentIterator.Next()
+	}
+	assert.False(t, segmentIterator.IsValid())
+}
+
+func TestSortedSegmentsSegmentSetVersionMovesForwardOnAddingASegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), segments.SegmentSetVersion())
+
+	_, err = segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("consensus", 10)},
+			values: []kv.Value{kv.NewStringValue("raft")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), segments.SegmentSetVersion())
+
+	_, err = segments.Load(segmentId)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), segments.SegmentSetVersion())
+}
+
+func TestSortedSegmentsWithReadaheadAndWithoutIOScheduler(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	defer store.Close()
+
+	assert.Panics(t, func() {
+		_, _ = NewSortedSegments(
+			store,
+			testSortedSegmentCacheOptions(),
+			DefaultSortedSegmentFormatOptions(),
+			0,
+			NewReadaheadOptions(2, 16),
+			nil,
+		)
+	})
+}
+
 func testInstantiateSortedSegments(store objectstore.Store) (*SortedSegments, error) {
-	return NewSortedSegments(store,
-		NewSortedSegmentCacheOptions(
-			cache.NewComparableKeyCacheOptions[uint64, filter.BloomFilter](
-				1000,
-				5*time.Minute,
-				func(id uint64, value filter.BloomFilter) uint32 {
-					return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
-				}),
-			cache.NewComparableKeyCacheOptions[uint64, *block.MetaList](
-				1000,
-				5*time.Minute,
-				func(id uint64, value *block.MetaList) uint32 {
-					return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
-				},
-			)), false,
+	return testInstantiateSortedSegmentsWithFormatOptions(store, DefaultSortedSegmentFormatOptions(), 0)
+}
+
+func testInstantiateSortedSegmentsWithValueSeparationThreshold(store objectstore.Store, threshold uint) (*SortedSegments, error) {
+	return testInstantiateSortedSegmentsWithFormatOptions(store, DefaultSortedSegmentFormatOptions(), threshold)
+}
+
+func testInstantiateSortedSegmentsWithFormatOptions(
+	store objectstore.Store,
+	formatOptions SortedSegmentFormatOptions,
+	valueSeparationThreshold uint,
+) (*SortedSegments, error) {
+	return NewSortedSegments(store, testSortedSegmentCacheOptions(), formatOptions, valueSeparationThreshold, NoReadahead(), nil)
+}
+
+func testSortedSegmentCacheOptions() SortedSegmentCacheOptions {
+	return NewSortedSegmentCacheOptions(
+		cache.NewComparableKeyCacheOptions[uint64, filter.BloomFilter](
+			1000,
+			5*time.Minute,
+			func(id uint64, value filter.BloomFilter) uint32 {
+				return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
+			}),
+		cache.NewComparableKeyCacheOptions[uint64, *block.MetaList](
+			1000,
+			5*time.Minute,
+			func(id uint64, value *block.MetaList) uint32 {
+				return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
+			},
+		),
+		cache.NewComparableKeyCacheOptions[uint64, *block.CompressionDictionary](
+			4<<20,
+			5*time.Minute,
+			func(id uint64, value *block.CompressionDictionary) uint32 {
+				return uint32(value.SizeInBytes())
+			},
+		),
+		cache.NewComparableKeyCacheOptions[cache.BlockCacheKey, block.Block](
+			1<<20,
+			5*time.Minute,
+			func(key cache.BlockCacheKey, value block.Block) uint32 {
+				return uint32(value.SizeInBytes())
+			},
+		),
 	)
 }
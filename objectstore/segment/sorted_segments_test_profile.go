@@ -19,6 +19,7 @@ func (sortedSegments *SortedSegments) RemoveAllPersistentSortedSegmentsIn(direct
 		delete(sortedSegments.persistentSegments, segmentId)
 		_ = os.Remove(filepath.Join(directory, PathSuffixForSegment(segmentId)))
 	}
+	sortedSegments.valueLogs.RemoveAllValueLogsIn(directory)
 }
 
 // sortedSegmentFor returns the SortedSegment for the given segment id.
@@ -13,9 +13,12 @@ var (
 	errObjectExists = errors.New("object already exists")
 )
 
+// Store reads and writes the objects using the StoreDefinition.
+// The byte ranges read by Store are cached in the diskCache (if any), please check DiskCache.
 type Store struct {
 	rootPath   string
 	definition StoreDefinition
+	diskCache  *DiskCache
 }
 
 func NewStore(rootPath string, definition StoreDefinition) Store {
@@ -25,6 +28,15 @@ func NewStore(rootPath string, definition StoreDefinition) Store {
 	}
 }
 
+// NewStoreWithDiskCache creates a Store whose byte range reads go through the given DiskCache.
+func NewStoreWithDiskCache(rootPath string, definition StoreDefinition, diskCache *DiskCache) Store {
+	return Store{
+		rootPath:   rootPath,
+		definition: definition,
+		diskCache:  diskCache,
+	}
+}
+
 func (store Store) Set(pathSuffix string, buffer []byte) error {
 	objectPath := store.objectPath(pathSuffix)
 	exists, err := store.definition.Exists(context.Background(), store.objectPath(pathSuffix))
@@ -46,11 +58,34 @@ func (store Store) Get(pathSuffix string) ([]byte, error) {
 }
 
 func (store Store) GetRange(pathSuffix string, startOffset int64, length int64) ([]byte, error) {
-	reader, err := store.definition.GetRange(context.Background(), store.objectPath(pathSuffix), startOffset, length)
+	return store.GetRangeWithContext(context.Background(), pathSuffix, startOffset, length)
+}
+
+// GetRangeWithContext reads the given byte range of the object, the read is abandoned if the given context is cancelled.
+// The byte range is read from the diskCache (if any), and it is read from the StoreDefinition (and cached) if it is not
+// in the diskCache. A failure to cache the byte range does not fail the read.
+// The byte range is not cached if an object is invalidated (deleted) while it is being read, please check
+// DiskCache.setUnlessInvalidatedSince.
+func (store Store) GetRangeWithContext(ctx context.Context, pathSuffix string, startOffset int64, length int64) ([]byte, error) {
+	var invalidationVersion uint64
+	if store.diskCache != nil {
+		if buffer, ok := store.diskCache.Get(pathSuffix, startOffset, length); ok {
+			return buffer, nil
+		}
+		invalidationVersion = store.diskCache.invalidationVersion()
+	}
+	reader, err := store.definition.GetRange(ctx, store.objectPath(pathSuffix), startOffset, length)
 	if err != nil {
 		return nil, err
 	}
-	return store.readAll(reader)
+	buffer, err := store.readAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if store.diskCache != nil && int64(len(buffer)) == length {
+		_ = store.diskCache.setUnlessInvalidatedSince(invalidationVersion, pathSuffix, startOffset, length, buffer)
+	}
+	return buffer, nil
 }
 
 func (store Store) SizeInBytes(pathSuffix string) (int64, error) {
@@ -61,7 +96,26 @@ func (store Store) SizeInBytes(pathSuffix string) (int64, error) {
 	return attributes.Size, nil
 }
 
+// Exists returns true if the object with the given path suffix exists.
+func (store Store) Exists(pathSuffix string) (bool, error) {
+	return store.definition.Exists(context.Background(), store.objectPath(pathSuffix))
+}
+
+// Delete deletes the object with the given path suffix, and invalidates its cached byte ranges (if any).
+// The byte ranges are invalidated after the object is deleted (even if the delete fails), so that a concurrent read can
+// not cache a byte range of the object after its invalidation.
+func (store Store) Delete(pathSuffix string) error {
+	err := store.definition.Delete(context.Background(), store.objectPath(pathSuffix))
+	if store.diskCache != nil {
+		store.diskCache.Invalidate(pathSuffix)
+	}
+	return err
+}
+
 func (store Store) Close() {
+	if store.diskCache != nil {
+		_ = store.diskCache.Close()
+	}
 	_ = store.definition.Close()
 }
 
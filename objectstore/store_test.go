@@ -57,6 +57,28 @@ func TestAttemptToStoreAnObjectAtAnExistingPath(t *testing.T) {
 	assert.True(t, errors.Is(err, errObjectExists))
 }
 
+func TestExistenceOfAnObject(t *testing.T) {
+	pathSuffix := t.Name()
+	storeDefinition, err := NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := NewStore(".", storeDefinition)
+	defer func() {
+		store.Close()
+		_ = os.Remove(pathSuffix)
+	}()
+
+	exists, err := store.Exists(pathSuffix)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, store.Set(pathSuffix, []byte("raft is a consensus protocol")))
+
+	exists, err = store.Exists(pathSuffix)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
 func TestGetRangeOfAnObjectFromFirstOffset(t *testing.T) {
 	pathSuffix := t.Name()
 	storeDefinition, err := NewFileSystemStoreDefinition(".")
@@ -113,3 +135,55 @@ func TestGetSizeOfObject(t *testing.T) {
 
 	assert.Equal(t, int64(34), size)
 }
+
+func TestGetRangeOfAnObjectThroughTheDiskCache(t *testing.T) {
+	pathSuffix := t.Name()
+	storeDefinition, err := NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	diskCache, err := NewDiskCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+
+	store := NewStoreWithDiskCache(".", storeDefinition, diskCache)
+	defer func() {
+		store.Close()
+		_ = os.Remove(pathSuffix)
+	}()
+
+	assert.NoError(t, store.Set(pathSuffix, []byte("raft is a consensus protocol")))
+
+	buffer, err := store.GetRange(pathSuffix, 0, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", string(buffer))
+
+	assert.NoError(t, os.Remove(pathSuffix))
+
+	buffer, err = store.GetRange(pathSuffix, 0, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", string(buffer))
+}
+
+func TestDeleteAnObjectInvalidatesTheDiskCache(t *testing.T) {
+	pathSuffix := t.Name()
+	storeDefinition, err := NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	diskCache, err := NewDiskCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+
+	store := NewStoreWithDiskCache(".", storeDefinition, diskCache)
+	defer func() {
+		store.Close()
+		_ = os.Remove(pathSuffix)
+	}()
+
+	assert.NoError(t, store.Set(pathSuffix, []byte("raft is a consensus protocol")))
+
+	_, err = store.GetRange(pathSuffix, 0, 4)
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Delete(pathSuffix))
+
+	_, ok := diskCache.Get(pathSuffix, 0, 4)
+	assert.False(t, ok)
+}
@@ -16,13 +16,19 @@ func (storeType StoreType) IsValid() bool {
 }
 
 func (storeType StoreType) GetStore(rootPath string) (Store, error) {
+	return storeType.GetStoreWithDiskCache(rootPath, nil)
+}
+
+// GetStoreWithDiskCache returns the Store whose byte range reads go through the given DiskCache, a nil DiskCache disables
+// the caching.
+func (storeType StoreType) GetStoreWithDiskCache(rootPath string, diskCache *DiskCache) (Store, error) {
 	switch storeType {
 	case FileSystemStore:
 		storeDefinition, err := NewFileSystemStoreDefinition(rootPath)
 		if err != nil {
 			return Store{}, err
 		}
-		return NewStore(rootPath, storeDefinition), nil
+		return NewStoreWithDiskCache(rootPath, storeDefinition, diskCache), nil
 	default:
 		panic("unknown store type")
 	}
@@ -0,0 +1,31 @@
+package valuelog
+
+// Builder allows building a value log in a step-by-step manner.
+// A value log is a sequence of raw values, each value is referred by a Pointer.
+type Builder struct {
+	logId uint64
+	data  []byte
+}
+
+// NewBuilder creates a new instance of Builder for the value log with the given id.
+func NewBuilder(logId uint64) *Builder {
+	return &Builder{
+		logId: logId,
+	}
+}
+
+// Add adds the value to the value log and returns the Pointer to it.
+func (builder *Builder) Add(value []byte) Pointer {
+	pointer := Pointer{
+		LogId:  builder.logId,
+		Offset: uint64(len(builder.data)),
+		Length: uint32(len(value)),
+	}
+	builder.data = append(builder.data, value...)
+	return pointer
+}
+
+// IsEmpty returns true if the builder has no values.
+func (builder *Builder) IsEmpty() bool {
+	return len(builder.data) == 0
+}
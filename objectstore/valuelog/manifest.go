@@ -0,0 +1,90 @@
+package valuelog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"slices"
+)
+
+var ErrInvalidManifest = errors.New("invalid value log manifest")
+
+var manifestChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// manifestSlots is the number of manifest objects of a value log.
+// The manifest of a generation is written to the slot (generation % manifestSlots), so the manifest of the previous
+// generation stays intact until the manifest of the next generation is completely written.
+const manifestSlots = 2
+
+// manifest is the durable metadata of a generation of a value log: its generation, its size and the relocated offsets
+// of its live values (please check valueLog).
+type manifest struct {
+	generation       uint32
+	sizeInBytes      int64
+	relocatedOffsets map[uint64]uint64
+}
+
+// encode encodes the manifest.
+// Encoding includes:
+/*
+  ----------------------------------------------------------------------------------------------------------------
+ | 4 bytes for generation | 8 bytes for size | 4 bytes for the number of relocated offsets (n) |
+ | n * (8 bytes for original offset | 8 bytes for relocated offset) | 4 bytes for the checksum (crc32 Castagnoli) |
+  ----------------------------------------------------------------------------------------------------------------
+*/
+// The relocated offsets are encoded in the increasing order of the original offsets.
+func (manifest manifest) encode() []byte {
+	originalOffsets := make([]uint64, 0, len(manifest.relocatedOffsets))
+	for originalOffset := range manifest.relocatedOffsets {
+		originalOffsets = append(originalOffsets, originalOffset)
+	}
+	slices.Sort(originalOffsets)
+
+	buffer := make([]byte, 0, uint32Size+uint64Size+uint32Size+len(originalOffsets)*2*uint64Size+uint32Size)
+	buffer = binary.LittleEndian.AppendUint32(buffer, manifest.generation)
+	buffer = binary.LittleEndian.AppendUint64(buffer, uint64(manifest.sizeInBytes))
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(len(originalOffsets)))
+	for _, originalOffset := range originalOffsets {
+		buffer = binary.LittleEndian.AppendUint64(buffer, originalOffset)
+		buffer = binary.LittleEndian.AppendUint64(buffer, manifest.relocatedOffsets[originalOffset])
+	}
+	return binary.LittleEndian.AppendUint32(buffer, crc32.Checksum(buffer, manifestChecksumTable))
+}
+
+// decodeToManifest decodes the given byte slice to the manifest, it returns ErrInvalidManifest if the byte slice is
+// truncated or its checksum does not match.
+func decodeToManifest(buffer []byte) (manifest, error) {
+	headerSize := uint32Size + uint64Size + uint32Size
+	if len(buffer) < headerSize+uint32Size {
+		return manifest{}, ErrInvalidManifest
+	}
+	content := buffer[:len(buffer)-uint32Size]
+	if binary.LittleEndian.Uint32(buffer[len(content):]) != crc32.Checksum(content, manifestChecksumTable) {
+		return manifest{}, fmt.Errorf("%w: checksum mismatch", ErrInvalidManifest)
+	}
+	decoded := manifest{
+		generation:  binary.LittleEndian.Uint32(content),
+		sizeInBytes: int64(binary.LittleEndian.Uint64(content[uint32Size:])),
+	}
+	numberOfRelocatedOffsets := int(binary.LittleEndian.Uint32(content[uint32Size+uint64Size:]))
+	if len(content) != headerSize+numberOfRelocatedOffsets*2*uint64Size {
+		return manifest{}, fmt.Errorf("%w: unexpected size %v", ErrInvalidManifest, len(buffer))
+	}
+	if decoded.generation == 0 && numberOfRelocatedOffsets > 0 {
+		return manifest{}, fmt.Errorf("%w: relocated offsets in the generation 0", ErrInvalidManifest)
+	}
+	if decoded.generation > 0 {
+		decoded.relocatedOffsets = make(map[uint64]uint64, numberOfRelocatedOffsets)
+	}
+	for offset := headerSize; offset < len(content); offset += 2 * uint64Size {
+		decoded.relocatedOffsets[binary.LittleEndian.Uint64(content[offset:])] = binary.LittleEndian.Uint64(content[offset+uint64Size:])
+	}
+	return decoded, nil
+}
+
+// PathSuffixForValueLogManifest returns the object path suffix of the given manifest slot of the value log, which is of
+// the form: <id>.vlog.<slot>.manifest.
+func PathSuffixForValueLogManifest(id uint64, slot uint32) string {
+	return fmt.Sprintf("%v.vlog.%v.manifest", id, slot)
+}
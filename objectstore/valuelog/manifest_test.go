@@ -0,0 +1,38 @@
+package valuelog
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeAndDecodeTheManifestOfTheGeneration0(t *testing.T) {
+	decoded, err := decodeToManifest(manifest{generation: 0, sizeInBytes: 128}.encode())
+	assert.NoError(t, err)
+	assert.Equal(t, manifest{generation: 0, sizeInBytes: 128}, decoded)
+}
+
+func TestEncodeAndDecodeTheManifestWithRelocatedOffsets(t *testing.T) {
+	original := manifest{
+		generation:       2,
+		sizeInBytes:      9,
+		relocatedOffsets: map[uint64]uint64{4: 0, 12: 5},
+	}
+	decoded, err := decodeToManifest(original.encode())
+	assert.NoError(t, err)
+	assert.Equal(t, original, decoded)
+}
+
+func TestDecodeACorruptedManifest(t *testing.T) {
+	buffer := manifest{generation: 1, sizeInBytes: 4, relocatedOffsets: map[uint64]uint64{8: 0}}.encode()
+	buffer[0] ^= 0xFF
+
+	_, err := decodeToManifest(buffer)
+	assert.ErrorIs(t, err, ErrInvalidManifest)
+}
+
+func TestDecodeATruncatedManifest(t *testing.T) {
+	buffer := manifest{generation: 1, sizeInBytes: 4, relocatedOffsets: map[uint64]uint64{8: 0}}.encode()
+
+	_, err := decodeToManifest(buffer[:len(buffer)-6])
+	assert.ErrorIs(t, err, ErrInvalidManifest)
+}
@@ -0,0 +1,52 @@
+package valuelog
+
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+)
+
+var uint32Size = int(unsafe.Sizeof(uint32(0)))
+var uint64Size = int(unsafe.Sizeof(uint64(0)))
+
+// PointerSize is the size of the encoded Pointer.
+var PointerSize = uint64Size + uint64Size + uint32Size
+
+var ErrInvalidPointer = errors.New("invalid value log pointer")
+
+// Pointer points to a value stored in a value log.
+// LogId is the id of the value log, which is the same as the id of the segment that separated the value.
+// Offset is the begin-offset of the value in the value log, and Length is the length of the value.
+// A Pointer always keeps the offset at which the value was originally written, please check ValueLogs.CollectGarbage.
+type Pointer struct {
+	LogId  uint64
+	Offset uint64
+	Length uint32
+}
+
+// DecodeToPointer decodes the given byte slice to the Pointer.
+func DecodeToPointer(buffer []byte) (Pointer, error) {
+	if len(buffer) != PointerSize {
+		return Pointer{}, ErrInvalidPointer
+	}
+	return Pointer{
+		LogId:  binary.LittleEndian.Uint64(buffer[:]),
+		Offset: binary.LittleEndian.Uint64(buffer[uint64Size:]),
+		Length: binary.LittleEndian.Uint32(buffer[uint64Size+uint64Size:]),
+	}, nil
+}
+
+// Encode encodes the Pointer.
+// Encoding includes:
+/*
+  ---------------------------------------------------------------
+ | 8 bytes for log id | 8 bytes for offset | 4 bytes for length |
+  ---------------------------------------------------------------
+*/
+func (pointer Pointer) Encode() []byte {
+	buffer := make([]byte, PointerSize)
+	binary.LittleEndian.PutUint64(buffer[:], pointer.LogId)
+	binary.LittleEndian.PutUint64(buffer[uint64Size:], pointer.Offset)
+	binary.LittleEndian.PutUint32(buffer[uint64Size+uint64Size:], pointer.Length)
+	return buffer
+}
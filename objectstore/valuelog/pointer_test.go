@@ -0,0 +1,19 @@
+package valuelog
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeAndDecodePointer(t *testing.T) {
+	pointer := Pointer{LogId: 10, Offset: 1024, Length: 512}
+
+	decodedPointer, err := DecodeToPointer(pointer.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, pointer, decodedPointer)
+}
+
+func TestAttemptToDecodePointerFromAnInvalidBuffer(t *testing.T) {
+	_, err := DecodeToPointer([]byte("raft"))
+	assert.ErrorIs(t, err, ErrInvalidPointer)
+}
@@ -0,0 +1,303 @@
+package valuelog
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SarthakMakhija/zero-store/objectstore"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrNoValueLogForTheLogId = errors.New("no value log for the given id")
+	ErrValueNotLive          = errors.New("value is not live in the value log")
+)
+
+// valueLog represents a value log in the object store.
+// generation is incremented every time the value log is rewritten by ValueLogs.CollectGarbage.
+// relocatedOffsets map the original offsets of the live values to their offsets in the current generation, it is nil for the
+// generation 0.
+type valueLog struct {
+	id               uint64
+	generation       uint32
+	sizeInBytes      int64
+	relocatedOffsets map[uint64]uint64
+}
+
+// offsetOf returns the offset of the value (referred by the Pointer) in the current generation of the value log.
+func (log valueLog) offsetOf(pointer Pointer) (uint64, bool) {
+	if log.relocatedOffsets == nil {
+		return pointer.Offset, true
+	}
+	offset, ok := log.relocatedOffsets[pointer.Offset]
+	return offset, ok
+}
+
+// pathSuffix returns the object path suffix of the current generation of the value log.
+func (log valueLog) pathSuffix() string {
+	return PathSuffixForValueLog(log.id, log.generation)
+}
+
+// ValueLogs is a collection of value logs.
+// Values larger than the value separation threshold are stored in value logs (WiscKey style key-value separation),
+// and the persistent segments only contain a Pointer to the value.
+// The generation, the size and the relocated offsets of every value log are persisted in its manifest (please check
+// manifest), and a value log is registered again from its manifest by Load.
+type ValueLogs struct {
+	logs  map[uint64]valueLog
+	store objectstore.Store
+	lock  sync.RWMutex
+}
+
+// NewValueLogs creates a new instance of ValueLogs.
+func NewValueLogs(store objectstore.Store) *ValueLogs {
+	return &ValueLogs{
+		logs:  make(map[uint64]valueLog),
+		store: store,
+	}
+}
+
+// Write writes the value log built by the Builder, followed by its manifest, to the object store.
+// It does nothing if the Builder is empty.
+func (valueLogs *ValueLogs) Write(builder *Builder) error {
+	if builder.IsEmpty() {
+		return nil
+	}
+	if err := valueLogs.store.Set(PathSuffixForValueLog(builder.logId, 0), builder.data); err != nil {
+		return err
+	}
+	log := valueLog{
+		id:          builder.logId,
+		generation:  0,
+		sizeInBytes: int64(len(builder.data)),
+	}
+	if err := valueLogs.writeManifest(log); err != nil {
+		return err
+	}
+
+	valueLogs.lock.Lock()
+	defer valueLogs.lock.Unlock()
+
+	valueLogs.logs[builder.logId] = log
+	return nil
+}
+
+// Load registers the value log with the given id from its manifest in the object store.
+// The manifest with the highest valid generation is used, a manifest which was not completely written is ignored.
+// It does nothing if the value log is already registered, or if there is no manifest for the value log (the segment
+// with the same id did not separate any value).
+func (valueLogs *ValueLogs) Load(logId uint64) error {
+	valueLogs.lock.RLock()
+	_, ok := valueLogs.logs[logId]
+	valueLogs.lock.RUnlock()
+	if ok {
+		return nil
+	}
+
+	var current *manifest
+	for slot := uint32(0); slot < manifestSlots; slot++ {
+		pathSuffix := PathSuffixForValueLogManifest(logId, slot)
+		exists, err := valueLogs.store.Exists(pathSuffix)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		buffer, err := valueLogs.store.Get(pathSuffix)
+		if err != nil {
+			return err
+		}
+		decoded, err := decodeToManifest(buffer)
+		if err != nil {
+			continue
+		}
+		if current == nil || decoded.generation > current.generation {
+			current = &decoded
+		}
+	}
+	if current == nil {
+		return nil
+	}
+	log := valueLog{
+		id:               logId,
+		generation:       current.generation,
+		sizeInBytes:      current.sizeInBytes,
+		relocatedOffsets: current.relocatedOffsets,
+	}
+	if log.generation > 0 {
+		if err := valueLogs.deleteIfExists(PathSuffixForValueLog(logId, log.generation-1)); err != nil {
+			return err
+		}
+	}
+
+	valueLogs.lock.Lock()
+	defer valueLogs.lock.Unlock()
+
+	valueLogs.logs[logId] = log
+	return nil
+}
+
+// Get returns the value referred by the Pointer, it reads the value from the object store using GetRange.
+// The read lock is held while reading the value, so that CollectGarbage does not delete the value log which is being read.
+func (valueLogs *ValueLogs) Get(pointer Pointer) ([]byte, error) {
+	valueLogs.lock.RLock()
+	defer valueLogs.lock.RUnlock()
+
+	log, ok := valueLogs.logs[pointer.LogId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNoValueLogForTheLogId, pointer.LogId)
+	}
+	offset, ok := log.offsetOf(pointer)
+	if !ok {
+		return nil, fmt.Errorf("%w: log id %v, offset %v", ErrValueNotLive, pointer.LogId, pointer.Offset)
+	}
+	return valueLogs.store.GetRange(log.pathSuffix(), int64(offset), int64(pointer.Length))
+}
+
+// CollectGarbage rewrites the value logs with live ratio less than the liveRatioThreshold.
+// livePointers are all the pointers referred by the persistent segments, and the live ratio of a value log is the size
+// of its live values divided by its size.
+// Rewriting a value log involves:
+// 1) Reading the current generation of the value log.
+// 2) Writing the live values to the next generation of the value log.
+// 3) Writing the manifest of the next generation, which relocates the original offsets of the live values to their
+// offsets in the next generation.
+// 4) Deleting the current generation of the value log.
+// The current generation is deleted only after the manifest of the next generation is durable, so a crash at any step
+// leaves a readable value log (please check Load).
+// A value log without any live value is deleted, along with its manifests.
+// It returns the ids of the value logs which were rewritten or deleted.
+func (valueLogs *ValueLogs) CollectGarbage(livePointers []Pointer, liveRatioThreshold float64) ([]uint64, error) {
+	livePointersByLogId := make(map[uint64]map[uint64]Pointer)
+	for _, pointer := range livePointers {
+		if _, ok := livePointersByLogId[pointer.LogId]; !ok {
+			livePointersByLogId[pointer.LogId] = make(map[uint64]Pointer)
+		}
+		livePointersByLogId[pointer.LogId][pointer.Offset] = pointer
+	}
+
+	var collectedLogIds []uint64
+	for _, log := range valueLogs.copyLogs() {
+		livePointersInLog := livePointersByLogId[log.id]
+		liveSizeInBytes := int64(0)
+		for _, pointer := range livePointersInLog {
+			liveSizeInBytes += int64(pointer.Length)
+		}
+		if float64(liveSizeInBytes)/float64(log.sizeInBytes) >= liveRatioThreshold {
+			continue
+		}
+		if err := valueLogs.rewrite(log, livePointersInLog); err != nil {
+			return collectedLogIds, err
+		}
+		collectedLogIds = append(collectedLogIds, log.id)
+	}
+	slices.Sort(collectedLogIds)
+	return collectedLogIds, nil
+}
+
+// rewrite writes the live values of the value log to its next generation, and deletes the current generation.
+// The value log is deleted if it has no live values.
+func (valueLogs *ValueLogs) rewrite(log valueLog, livePointers map[uint64]Pointer) error {
+	if len(livePointers) == 0 {
+		valueLogs.lock.Lock()
+		delete(valueLogs.logs, log.id)
+		valueLogs.lock.Unlock()
+
+		for slot := uint32(0); slot < manifestSlots; slot++ {
+			if err := valueLogs.deleteIfExists(PathSuffixForValueLogManifest(log.id, slot)); err != nil {
+				return err
+			}
+		}
+		return valueLogs.store.Delete(log.pathSuffix())
+	}
+
+	data, err := valueLogs.store.Get(log.pathSuffix())
+	if err != nil {
+		return err
+	}
+	originalOffsets := make([]uint64, 0, len(livePointers))
+	for offset := range livePointers {
+		originalOffsets = append(originalOffsets, offset)
+	}
+	slices.Sort(originalOffsets)
+
+	var rewrittenData []byte
+	relocatedOffsets := make(map[uint64]uint64, len(originalOffsets))
+	for _, originalOffset := range originalOffsets {
+		pointer := livePointers[originalOffset]
+		offset, ok := log.offsetOf(pointer)
+		if !ok || offset+uint64(pointer.Length) > uint64(len(data)) {
+			return fmt.Errorf("%w: log id %v, offset %v", ErrValueNotLive, pointer.LogId, pointer.Offset)
+		}
+		relocatedOffsets[originalOffset] = uint64(len(rewrittenData))
+		rewrittenData = append(rewrittenData, data[offset:offset+uint64(pointer.Length)]...)
+	}
+
+	rewrittenLog := valueLog{
+		id:               log.id,
+		generation:       log.generation + 1,
+		sizeInBytes:      int64(len(rewrittenData)),
+		relocatedOffsets: relocatedOffsets,
+	}
+	if err := valueLogs.deleteIfExists(rewrittenLog.pathSuffix()); err != nil {
+		return err
+	}
+	if err := valueLogs.store.Set(rewrittenLog.pathSuffix(), rewrittenData); err != nil {
+		return err
+	}
+	if err := valueLogs.writeManifest(rewrittenLog); err != nil {
+		return err
+	}
+
+	valueLogs.lock.Lock()
+	valueLogs.logs[log.id] = rewrittenLog
+	valueLogs.lock.Unlock()
+
+	return valueLogs.store.Delete(log.pathSuffix())
+}
+
+// writeManifest writes the manifest of the given value log to the manifest slot of its generation, replacing the
+// manifest of an older generation in that slot.
+func (valueLogs *ValueLogs) writeManifest(log valueLog) error {
+	pathSuffix := PathSuffixForValueLogManifest(log.id, log.generation%manifestSlots)
+	if err := valueLogs.deleteIfExists(pathSuffix); err != nil {
+		return err
+	}
+	return valueLogs.store.Set(pathSuffix, manifest{
+		generation:       log.generation,
+		sizeInBytes:      log.sizeInBytes,
+		relocatedOffsets: log.relocatedOffsets,
+	}.encode())
+}
+
+// deleteIfExists deletes the object with the given path suffix, if it exists.
+// A generation (or a manifest) may be left behind by a rewrite which did not complete.
+func (valueLogs *ValueLogs) deleteIfExists(pathSuffix string) error {
+	exists, err := valueLogs.store.Exists(pathSuffix)
+	if err != nil || !exists {
+		return err
+	}
+	return valueLogs.store.Delete(pathSuffix)
+}
+
+// copyLogs returns a copy of all the value logs.
+func (valueLogs *ValueLogs) copyLogs() []valueLog {
+	valueLogs.lock.RLock()
+	defer valueLogs.lock.RUnlock()
+
+	logs := make([]valueLog, 0, len(valueLogs.logs))
+	for _, log := range valueLogs.logs {
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+// PathSuffixForValueLog returns the value log object path suffix which is of the form: <id>.vlog for the generation 0,
+// and <id>.<generation>.vlog for the later generations.
+func PathSuffixForValueLog(id uint64, generation uint32) string {
+	if generation == 0 {
+		return fmt.Sprintf("%v.vlog", id)
+	}
+	return fmt.Sprintf("%v.%v.vlog", id, generation)
+}
@@ -0,0 +1,284 @@
+package valuelog
+
+import (
+	"github.com/SarthakMakhija/zero-store/objectstore"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestWriteAValueLogAndGetValues(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	raftPointer := builder.Add([]byte("raft"))
+	paxosPointer := builder.Add([]byte("paxos"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	value, err := valueLogs.Get(raftPointer)
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", string(value))
+
+	value, err = valueLogs.Get(paxosPointer)
+	assert.NoError(t, err)
+	assert.Equal(t, "paxos", string(value))
+}
+
+func TestWriteAnEmptyValueLog(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	defer store.Close()
+
+	valueLogs := NewValueLogs(store)
+	assert.NoError(t, valueLogs.Write(NewBuilder(1)))
+
+	_, err = os.Stat(PathSuffixForValueLog(1, 0))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAttemptToGetAValueFromANonExistingValueLog(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	defer store.Close()
+
+	valueLogs := NewValueLogs(store)
+	_, err = valueLogs.Get(Pointer{LogId: 1, Offset: 0, Length: 4})
+	assert.ErrorIs(t, err, ErrNoValueLogForTheLogId)
+}
+
+func TestCollectGarbageOfAValueLogWithLowLiveRatio(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	raftPointer := builder.Add([]byte("raft"))
+	paxosPointer := builder.Add([]byte("paxos"))
+	zabPointer := builder.Add([]byte("zab"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	collectedLogIds, err := valueLogs.CollectGarbage([]Pointer{zabPointer}, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1}, collectedLogIds)
+
+	value, err := valueLogs.Get(zabPointer)
+	assert.NoError(t, err)
+	assert.Equal(t, "zab", string(value))
+
+	_, err = valueLogs.Get(raftPointer)
+	assert.ErrorIs(t, err, ErrValueNotLive)
+
+	_, err = valueLogs.Get(paxosPointer)
+	assert.ErrorIs(t, err, ErrValueNotLive)
+
+	_, err = os.Stat(PathSuffixForValueLog(1, 0))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(PathSuffixForValueLog(1, 1))
+	assert.NoError(t, err)
+}
+
+func TestCollectGarbageOfAValueLogTwice(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	_ = builder.Add([]byte("raft"))
+	paxosPointer := builder.Add([]byte("paxos"))
+	zabPointer := builder.Add([]byte("zab"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	_, err = valueLogs.CollectGarbage([]Pointer{paxosPointer, zabPointer}, 0.9)
+	assert.NoError(t, err)
+	_, err = valueLogs.CollectGarbage([]Pointer{zabPointer}, 0.9)
+	assert.NoError(t, err)
+
+	value, err := valueLogs.Get(zabPointer)
+	assert.NoError(t, err)
+	assert.Equal(t, "zab", string(value))
+}
+
+func TestCollectGarbageOfAValueLogWithoutLiveValues(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	raftPointer := builder.Add([]byte("raft"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	collectedLogIds, err := valueLogs.CollectGarbage(nil, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1}, collectedLogIds)
+
+	_, err = valueLogs.Get(raftPointer)
+	assert.ErrorIs(t, err, ErrNoValueLogForTheLogId)
+
+	_, err = os.Stat(PathSuffixForValueLog(1, 0))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCollectGarbageDoesNotRewriteAValueLogWithHighLiveRatio(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	raftPointer := builder.Add([]byte("raft"))
+	paxosPointer := builder.Add([]byte("paxos"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	collectedLogIds, err := valueLogs.CollectGarbage([]Pointer{raftPointer, paxosPointer}, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(collectedLogIds))
+
+	_, err = os.Stat(PathSuffixForValueLog(1, 0))
+	assert.NoError(t, err)
+}
+
+func TestLoadAValueLogWrittenByAnotherValueLogs(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	raftPointer := builder.Add([]byte("raft"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	reloadedValueLogs := NewValueLogs(store)
+	assert.NoError(t, reloadedValueLogs.Load(1))
+
+	value, err := reloadedValueLogs.Get(raftPointer)
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", string(value))
+}
+
+func TestLoadAValueLogAfterCollectingGarbage(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	raftPointer := builder.Add([]byte("raft"))
+	paxosPointer := builder.Add([]byte("paxos"))
+	zabPointer := builder.Add([]byte("zab"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	_, err = valueLogs.CollectGarbage([]Pointer{paxosPointer, zabPointer}, 0.9)
+	assert.NoError(t, err)
+	_, err = valueLogs.CollectGarbage([]Pointer{zabPointer}, 0.9)
+	assert.NoError(t, err)
+
+	reloadedValueLogs := NewValueLogs(store)
+	assert.NoError(t, reloadedValueLogs.Load(1))
+
+	value, err := reloadedValueLogs.Get(zabPointer)
+	assert.NoError(t, err)
+	assert.Equal(t, "zab", string(value))
+
+	_, err = reloadedValueLogs.Get(raftPointer)
+	assert.ErrorIs(t, err, ErrValueNotLive)
+	_, err = reloadedValueLogs.Get(paxosPointer)
+	assert.ErrorIs(t, err, ErrValueNotLive)
+}
+
+func TestLoadAValueLogWithAnIncompleteManifestOfTheNextGeneration(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	valueLogs := NewValueLogs(store)
+
+	defer func() {
+		valueLogs.RemoveAllValueLogsIn(".")
+		_ = os.Remove(PathSuffixForValueLog(1, 1))
+		store.Close()
+	}()
+
+	builder := NewBuilder(1)
+	raftPointer := builder.Add([]byte("raft"))
+	assert.NoError(t, valueLogs.Write(builder))
+
+	assert.NoError(t, store.Set(PathSuffixForValueLog(1, 1), []byte("raft")))
+	incompleteManifest := manifest{generation: 1, sizeInBytes: 4, relocatedOffsets: map[uint64]uint64{0: 0}}.encode()
+	assert.NoError(t, store.Set(PathSuffixForValueLogManifest(1, 1), incompleteManifest[:len(incompleteManifest)-3]))
+
+	reloadedValueLogs := NewValueLogs(store)
+	assert.NoError(t, reloadedValueLogs.Load(1))
+
+	value, err := reloadedValueLogs.Get(raftPointer)
+	assert.NoError(t, err)
+	assert.Equal(t, "raft", string(value))
+
+	_, err = reloadedValueLogs.CollectGarbage(nil, 0.5)
+	assert.NoError(t, err)
+}
+
+func TestLoadAValueLogWithoutManifest(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	defer store.Close()
+
+	valueLogs := NewValueLogs(store)
+	assert.NoError(t, valueLogs.Load(1))
+
+	_, err = valueLogs.Get(Pointer{LogId: 1, Offset: 0, Length: 4})
+	assert.ErrorIs(t, err, ErrNoValueLogForTheLogId)
+}
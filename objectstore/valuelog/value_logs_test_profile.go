@@ -0,0 +1,22 @@
+//go:build test
+
+package valuelog
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// RemoveAllValueLogsIn removes the value log files, along with their manifests.
+func (valueLogs *ValueLogs) RemoveAllValueLogsIn(directory string) {
+	valueLogs.lock.Lock()
+	defer valueLogs.lock.Unlock()
+
+	for logId, log := range valueLogs.logs {
+		delete(valueLogs.logs, logId)
+		_ = os.Remove(filepath.Join(directory, log.pathSuffix()))
+		for slot := uint32(0); slot < manifestSlots; slot++ {
+			_ = os.Remove(filepath.Join(directory, PathSuffixForValueLogManifest(logId, slot)))
+		}
+	}
+}
@@ -1,52 +1,240 @@
 package get_strategies
 
 import (
-	"github.com/SarthakMakhija/zero-store/iterator"
+	"context"
 	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/segment"
 	"iter"
+	"math"
+	"slices"
 )
 
+// DurableOnlyGet gets the value of a key from the persistent segments.
+// The block reads of the candidate segments are issued concurrently on the ioScheduler, which bounds the number of
+// concurrent reads of the Db.
+// rowCache is the snapshot of the (optional) RowCache in front of the persistent segments, please check RowCache.
+// negativeLookups is the snapshot of the (optional) cache of the keys absent in the persistent segments, please check
+// NegativeLookupSnapshot.
 type DurableOnlyGet struct {
 	segments                   *segment.SortedSegments
+	ioScheduler                *objectstore.IOScheduler
 	persistentSegmentsSequence iter.Seq2[int, segment.SortedSegment]
+	rowCache                   RowCacheSnapshot
+	negativeLookups            NegativeLookupSnapshot
 }
 
-func NewDurableOnlyGet(segments *segment.SortedSegments, persistentSegmentsSequence iter.Seq2[int, segment.SortedSegment]) DurableOnlyGet {
+// visibleVersion is the result of looking up a key in a persistent segment, found is false if the segment does not
+// contain a version of the key visible at the read timestamp. timestamp is the timestamp of the version.
+type visibleVersion struct {
+	value     kv.Value
+	timestamp uint64
+	found     bool
+}
+
+func NewDurableOnlyGet(
+	segments *segment.SortedSegments,
+	ioScheduler *objectstore.IOScheduler,
+	persistentSegmentsSequence iter.Seq2[int, segment.SortedSegment],
+) DurableOnlyGet {
+	return NewDurableOnlyGetWithCaches(
+		segments,
+		ioScheduler,
+		persistentSegmentsSequence,
+		RowCacheSnapshot{},
+		NegativeLookupSnapshot{},
+	)
+}
+
+// NewDurableOnlyGetWithCaches creates DurableOnlyGet which looks up (and populates) the given RowCacheSnapshot and
+// NegativeLookupSnapshot in front of the persistent segments. Both the snapshots must be taken before the
+// persistentSegmentsSequence is collected.
+func NewDurableOnlyGetWithCaches(
+	segments *segment.SortedSegments,
+	ioScheduler *objectstore.IOScheduler,
+	persistentSegmentsSequence iter.Seq2[int, segment.SortedSegment],
+	rowCache RowCacheSnapshot,
+	negativeLookups NegativeLookupSnapshot,
+) DurableOnlyGet {
 	return DurableOnlyGet{
 		segments:                   segments,
+		ioScheduler:                ioScheduler,
 		persistentSegmentsSequence: persistentSegmentsSequence,
+		rowCache:                   rowCache,
+		negativeLookups:            negativeLookups,
 	}
 }
 
+// Get looks up the key in the persistent segments (persistentSegmentsSequence is ordered by descending segment id), and
+// returns the value from the newest segment containing a version of the key at or below the timestamp of the key.
+// A newer segment always holds the newer versions of a key.
+// A segment is skipped (without checking its bloom filter) if its minimum timestamp is above the read timestamp.
+// The block reads of all the candidate segments (whose bloom filter may contain the key) are scheduled concurrently, and
+// their results are consumed newest-first. Once a segment answers, the reads of the older segments are cancelled.
+// The RowCache (if any) is looked up first, and the version found in the persistent segments is cached if it is the newest
+// durable version of the key, that is, no persistent segment contains a version above the read timestamp.
+// A key known to be absent in the persistent segments (please check NegativeLookupSnapshot) is answered without looking
+// up the persistent segments, and a key found absent is recorded.
 func (getOperation DurableOnlyGet) Get(key kv.Key) GetResponse {
-	mergeIterator, err := getOperation.mergeAllIteratorsFor(key)
-	if err != nil {
-		return errorResponse(err)
+	if getOperation.negativeLookups.isAbsent(key) {
+		return negativeResponse()
+	}
+	if value, ok := getOperation.rowCache.get(key); ok {
+		return positiveResponse(value)
 	}
-	boundedIterator := iterator.NewInclusiveBoundedIterator(mergeIterator, key)
-	defer boundedIterator.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	if boundedIterator.IsValid() && boundedIterator.Key().IsRawKeyEqualTo(key) {
-		return positiveResponse(boundedIterator.Value())
+	newestDurableVersion := key.Timestamp() < math.MaxUint64
+	var scheduledReads []*objectstore.ScheduledRead[visibleVersion]
+	for _, sortedSegment := range getOperation.persistentSegmentsSequence {
+		if newestDurableVersion && !sortedSegment.ContainsOnlyVersionsOlderThan(key.Timestamp()+1) {
+			newestDurableVersion = false
+		}
+		if !sortedSegment.MayContainVersionsVisibleAt(key.Timestamp()) {
+			continue
+		}
+		mayContain, err := getOperation.segments.MayContain(key, sortedSegment)
+		if err != nil {
+			return errorResponse(err)
+		}
+		if !mayContain {
+			continue
+		}
+		scheduledReads = append(scheduledReads, objectstore.Schedule(ctx, getOperation.ioScheduler, func(ctx context.Context) (visibleVersion, error) {
+			return getOperation.getFrom(ctx, sortedSegment, key)
+		}))
+	}
+	for _, scheduledRead := range scheduledReads {
+		version, err := scheduledRead.Wait()
+		if err != nil {
+			return errorResponse(err)
+		}
+		if version.found {
+			value, err := getOperation.segments.ResolveValue(version.value)
+			if err != nil {
+				return errorResponse(err)
+			}
+			if newestDurableVersion {
+				getOperation.rowCache.set(kv.NewKey(key.RawBytes(), version.timestamp), value)
+			}
+			return positiveResponse(value)
+		}
 	}
+	getOperation.negativeLookups.recordAbsent(key)
 	return negativeResponse()
 }
 
-func (getOperation DurableOnlyGet) mergeAllIteratorsFor(key kv.Key) (*iterator.MergeIterator, error) {
-	var iterators []iterator.Iterator
+// getFrom returns the newest version of the key (at or below the timestamp of the key) in the given segment.
+func (getOperation DurableOnlyGet) getFrom(ctx context.Context, sortedSegment segment.SortedSegment, key kv.Key) (visibleVersion, error) {
+	segmentIterator, err := getOperation.segments.SeekToKeyWithContext(ctx, key, sortedSegment)
+	if err != nil {
+		return visibleVersion{}, err
+	}
+	defer segmentIterator.Close()
+
+	if !segmentIterator.IsValid() || !segmentIterator.Key().IsRawKeyEqualTo(key) {
+		return visibleVersion{}, nil
+	}
+	return visibleVersion{value: segmentIterator.Value(), timestamp: segmentIterator.Key().Timestamp(), found: true}, nil
+}
+
+// MultiGet gets the values of the given keys from the persistent segments, the responses are in the order of the keys.
+// The keys are sorted, and the persistent segments are probed newest-first. Each segment is probed only for the keys which
+// are not found in a newer segment, and which the segment may contain (as per its timestamp range, its key range and its
+// bloom filter). Please check segment.SortedSegments.MultiGet for the reads of the blocks within a segment.
+// The keys whose versions are in the RowCache (if any) are answered from the RowCache, and (like Get) the version found for
+// a key is cached if it is the newest durable version of the key. The keys known to be absent are answered without looking
+// up the persistent segments, and the keys found absent are recorded, please check NegativeLookupSnapshot.
+func (getOperation DurableOnlyGet) MultiGet(keys []kv.Key) []GetResponse {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responses := make([]GetResponse, len(keys))
+	pendingKeyIndexes := make([]int, 0, len(keys))
+	for index, key := range keys {
+		if getOperation.negativeLookups.isAbsent(key) {
+			responses[index] = negativeResponse()
+			continue
+		}
+		if value, ok := getOperation.rowCache.get(key); ok {
+			responses[index] = positiveResponse(value)
+			continue
+		}
+		pendingKeyIndexes = append(pendingKeyIndexes, index)
+	}
+	slices.SortFunc(pendingKeyIndexes, func(index, otherIndex int) int {
+		return keys[index].CompareKeysWithDescendingTimestamp(keys[otherIndex])
+	})
+	newestDurableVersions := make(map[int]bool, len(pendingKeyIndexes))
+	for _, keyIndex := range pendingKeyIndexes {
+		newestDurableVersions[keyIndex] = keys[keyIndex].Timestamp() < math.MaxUint64
+	}
+	foundVersionTimestamps := make(map[int]uint64, len(pendingKeyIndexes))
+
+	errorResponses := func(err error) []GetResponse {
+		for _, keyIndex := range pendingKeyIndexes {
+			responses[keyIndex] = errorResponse(err)
+		}
+		return responses
+	}
 	for _, sortedSegment := range getOperation.persistentSegmentsSequence {
-		mayContain, err := getOperation.segments.MayContain(key, sortedSegment)
+		for keyIndex, newestDurableVersion := range newestDurableVersions {
+			if newestDurableVersion && !sortedSegment.ContainsOnlyVersionsOlderThan(keys[keyIndex].Timestamp()+1) {
+				newestDurableVersions[keyIndex] = false
+			}
+		}
+		if len(pendingKeyIndexes) == 0 {
+			continue
+		}
+		var candidateKeyIndexes []int
+		var candidateKeys []kv.Key
+		for _, keyIndex := range pendingKeyIndexes {
+			key := keys[keyIndex]
+			if !sortedSegment.MayContainVersionsVisibleAt(key.Timestamp()) {
+				continue
+			}
+			mayContain, err := getOperation.segments.MayContain(key, sortedSegment)
+			if err != nil {
+				return errorResponses(err)
+			}
+			if mayContain {
+				candidateKeyIndexes = append(candidateKeyIndexes, keyIndex)
+				candidateKeys = append(candidateKeys, key)
+			}
+		}
+		if len(candidateKeys) == 0 {
+			continue
+		}
+		versions, err := getOperation.segments.MultiGet(ctx, getOperation.ioScheduler, candidateKeys, sortedSegment)
 		if err != nil {
-			return nil, err
+			return errorResponses(err)
 		}
-		if mayContain {
-			segmentIterator, err := getOperation.segments.SeekToKey(key, sortedSegment)
+		answeredKeyIndexes := make(map[int]struct{}, len(versions))
+		for candidateIndex, version := range versions {
+			keyIndex := candidateKeyIndexes[candidateIndex]
+			answeredKeyIndexes[keyIndex] = struct{}{}
+			resolvedValue, err := getOperation.segments.ResolveValue(version.Value)
 			if err != nil {
-				return nil, err
+				responses[keyIndex] = errorResponse(err)
+				continue
 			}
-			iterators = append(iterators, segmentIterator)
+			responses[keyIndex] = positiveResponse(resolvedValue)
+			foundVersionTimestamps[keyIndex] = version.Timestamp
 		}
+		pendingKeyIndexes = slices.DeleteFunc(pendingKeyIndexes, func(keyIndex int) bool {
+			_, answered := answeredKeyIndexes[keyIndex]
+			return answered
+		})
+	}
+	for keyIndex, timestamp := range foundVersionTimestamps {
+		if newestDurableVersions[keyIndex] {
+			getOperation.rowCache.set(kv.NewKey(keys[keyIndex].RawBytes(), timestamp), responses[keyIndex].Value())
+		}
+	}
+	for _, keyIndex := range pendingKeyIndexes {
+		getOperation.negativeLookups.recordAbsent(keys[keyIndex])
+		responses[keyIndex] = negativeResponse()
 	}
-	return iterator.NewMergeIterator(iterators), nil
+	return responses
 }
@@ -1,7 +1,9 @@
 package get_strategies
 
 import (
+	"errors"
 	"github.com/SarthakMakhija/zero-store/cache"
+	"github.com/SarthakMakhija/zero-store/cache/key_cache"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/block"
@@ -65,7 +67,7 @@ func TestDurableOnlyGetWithASingleSegmentContainingTheKey(t *testing.T) {
 	)
 	assert.NoError(t, err)
 
-	getOperation := NewDurableOnlyGet(segments, slices.Backward([]segment.SortedSegment{aSegment}))
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.Backward([]segment.SortedSegment{aSegment}))
 	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 11))
 
 	assert.True(t, getResponse.IsValueAvailable())
@@ -96,7 +98,7 @@ func TestDurableOnlyGetWithASingleSegmentNotContainingTheKey(t *testing.T) {
 	)
 	assert.NoError(t, err)
 
-	getOperation := NewDurableOnlyGet(segments, slices.Backward([]segment.SortedSegment{aSegment}))
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.Backward([]segment.SortedSegment{aSegment}))
 	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("paxos", 11))
 
 	assert.False(t, getResponse.IsValueAvailable())
@@ -138,7 +140,7 @@ func TestDurableOnlyGetWithMultipleSegmentsOneOfWhichContainsTheKey(t *testing.T
 	)
 	assert.NoError(t, err)
 
-	getOperation := NewDurableOnlyGet(segments, slices.Backward([]segment.SortedSegment{aSegment, anotherSegment}))
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.Backward([]segment.SortedSegment{aSegment, anotherSegment}))
 	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("paxos", 18))
 
 	assert.True(t, getResponse.IsValueAvailable())
@@ -180,7 +182,7 @@ func TestDurableOnlyGetWithMultipleSegmentsManyOfWhichContainTheKey(t *testing.T
 	)
 	assert.NoError(t, err)
 
-	getOperation := NewDurableOnlyGet(segments, slices.Backward([]segment.SortedSegment{anotherSegment, aSegment}))
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.Backward([]segment.SortedSegment{aSegment, anotherSegment}))
 
 	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 16))
 	assert.True(t, getResponse.IsValueAvailable())
@@ -206,6 +208,674 @@ func testInstantiateSortedSegments(store objectstore.Store) (*segment.SortedSegm
 				func(id uint64, value *block.MetaList) uint32 {
 					return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
 				},
-			)), false,
+			),
+			cache.NewComparableKeyCacheOptions[uint64, *block.CompressionDictionary](
+				1000,
+				5*time.Minute,
+				func(id uint64, value *block.CompressionDictionary) uint32 {
+					return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
+				},
+			),
+			cache.NewComparableKeyCacheOptions[cache.BlockCacheKey, block.Block](
+				1<<20,
+				5*time.Minute,
+				func(key cache.BlockCacheKey, value block.Block) uint32 {
+					return uint32(unsafe.Sizeof(key)) + uint32(value.SizeInBytes())
+				},
+			)), segment.DefaultSortedSegmentFormatOptions(), 0, segment.NoReadahead(), nil,
+	)
+}
+
+func TestDurableOnlyGetWithACorruptedSegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	segmentBytes, err := os.ReadFile(segment.PathSuffixForSegment(segmentId))
+	assert.NoError(t, err)
+	segmentBytes[2] ^= 0xFF
+	assert.NoError(t, os.WriteFile(segment.PathSuffixForSegment(segmentId), segmentBytes, 0644))
+
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.Backward([]segment.SortedSegment{aSegment}))
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 11))
+
+	assert.True(t, getResponse.IsError())
+	var corruption *segment.ErrCorruption
+	assert.True(t, errors.As(getResponse.Error(), &corruption))
+	assert.Equal(t, segmentId, corruption.SegmentId)
+	assert.Equal(t, 0, corruption.BlockIndex)
+}
+
+func TestDurableOnlyGetSkipsASegmentWithMinimumTimestampAboveTheReadTimestamp(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	aSegmentId := uint64(1)
+	anotherSegmentId := uint64(2)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(aSegmentId))
+		_ = os.Remove(segment.PathSuffixForSegment(anotherSegmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		aSegmentId,
+	)
+	assert.NoError(t, err)
+
+	anotherSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 50)},
+			values: []kv.Value{kv.NewStringValue("another consensus")},
+		},
+		anotherSegmentId,
+	)
+	assert.NoError(t, err)
+	testCorruptFirstBlockOf(t, anotherSegmentId)
+
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.All([]segment.SortedSegment{anotherSegment, aSegment}))
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 20))
+
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponse.Value())
+}
+
+func TestDurableOnlyGetSkipsASegmentWithMaximumTimestampBelowTheVisibleVersion(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	aSegmentId := uint64(1)
+	anotherSegmentId := uint64(2)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(aSegmentId))
+		_ = os.Remove(segment.PathSuffixForSegment(anotherSegmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		aSegmentId,
+	)
+	assert.NoError(t, err)
+	testCorruptFirstBlockOf(t, aSegmentId)
+
+	anotherSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 50)},
+			values: []kv.Value{kv.NewStringValue("another consensus")},
+		},
+		anotherSegmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.All([]segment.SortedSegment{anotherSegment, aSegment}))
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 60))
+
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("another consensus"), getResponse.Value())
+}
+
+func TestDurableOnlyGetStopsAtTheNewestSegmentContainingTheKey(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	aSegmentId := uint64(1)
+	anotherSegmentId := uint64(2)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(aSegmentId))
+		_ = os.Remove(segment.PathSuffixForSegment(anotherSegmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("paxos", 100), kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus"), kv.NewStringValue("consensus")},
+		},
+		aSegmentId,
+	)
+	assert.NoError(t, err)
+	testCorruptFirstBlockOf(t, aSegmentId)
+
+	anotherSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 50)},
+			values: []kv.Value{kv.NewStringValue("another consensus")},
+		},
+		anotherSegmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.All([]segment.SortedSegment{anotherSegment, aSegment}))
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 200))
+
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("another consensus"), getResponse.Value())
+}
+
+func testCorruptFirstBlockOf(t *testing.T, segmentId uint64) {
+	segmentBytes, err := os.ReadFile(segment.PathSuffixForSegment(segmentId))
+	assert.NoError(t, err)
+	segmentBytes[2] ^= 0xFF
+	assert.NoError(t, os.WriteFile(segment.PathSuffixForSegment(segmentId), segmentBytes, 0644))
+}
+
+func TestDurableOnlyMultiGetWithMultipleSegments(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	aSegmentId := uint64(1)
+	anotherSegmentId := uint64(2)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(aSegmentId))
+		_ = os.Remove(segment.PathSuffixForSegment(anotherSegmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys: []kv.Key{
+				kv.NewStringKeyWithTimestamp("consensus", 10),
+				kv.NewStringKeyWithTimestamp("distributed", 10),
+				kv.NewStringKeyWithTimestamp("raft", 10),
+			},
+			values: []kv.Value{kv.NewStringValue("paxos"), kv.NewStringValue("TiKV"), kv.NewStringValue("etcd")},
+		},
+		aSegmentId,
+	)
+	assert.NoError(t, err)
+
+	anotherSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("distributed", 20), kv.NewStringKeyWithTimestamp("raft", 20)},
+			values: []kv.Value{kv.NewDeletedValue(), kv.NewStringValue("consensus")},
+		},
+		anotherSegmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.All([]segment.SortedSegment{anotherSegment, aSegment}))
+	getResponses := getOperation.MultiGet([]kv.Key{
+		kv.NewStringKeyWithTimestamp("raft", 25),
+		kv.NewStringKeyWithTimestamp("quorum", 25),
+		kv.NewStringKeyWithTimestamp("consensus", 25),
+		kv.NewStringKeyWithTimestamp("distributed", 25),
+		kv.NewStringKeyWithTimestamp("raft", 15),
+	})
+
+	assert.Equal(t, 5, len(getResponses))
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponses[0].Value())
+	assert.False(t, getResponses[1].IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("paxos"), getResponses[2].Value())
+	assert.False(t, getResponses[3].IsValueAvailable())
+	assert.False(t, getResponses[3].IsError())
+	assert.Equal(t, kv.NewStringValue("etcd"), getResponses[4].Value())
+}
+
+func TestDurableOnlyMultiGetWithACorruptedSegment(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+	testCorruptFirstBlockOf(t, segmentId)
+
+	getOperation := NewDurableOnlyGet(segments, objectstore.NewIOScheduler(4), slices.All([]segment.SortedSegment{aSegment}))
+	getResponses := getOperation.MultiGet([]kv.Key{kv.NewStringKeyWithTimestamp("raft", 11)})
+
+	assert.True(t, getResponses[0].IsError())
+	var corruption *segment.ErrCorruption
+	assert.True(t, errors.As(getResponses[0].Error(), &corruption))
+}
+
+func TestDurableOnlyGetCachesTheNewestDurableVersionInTheRowCache(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer func() {
+		store.Close()
+		rowCache.Stop()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.Backward([]segment.SortedSegment{aSegment}),
+		rowCache.Snapshot(),
+		NegativeLookupSnapshot{},
 	)
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 11))
+
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponse.Value())
+
+	value, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 10))
+	assert.True(t, ok)
+	assert.Equal(t, kv.NewStringValue("consensus"), value)
+}
+
+func TestDurableOnlyGetDoesNotCacheAVersionWhichIsNotTheNewestDurableVersion(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	aSegmentId := uint64(1)
+	anotherSegmentId := uint64(2)
+
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer func() {
+		store.Close()
+		rowCache.Stop()
+		_ = os.Remove(segment.PathSuffixForSegment(aSegmentId))
+		_ = os.Remove(segment.PathSuffixForSegment(anotherSegmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		aSegmentId,
+	)
+	assert.NoError(t, err)
+
+	anotherSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 50)},
+			values: []kv.Value{kv.NewStringValue("another consensus")},
+		},
+		anotherSegmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.All([]segment.SortedSegment{anotherSegment, aSegment}),
+		rowCache.Snapshot(),
+		NegativeLookupSnapshot{},
+	)
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 20))
+
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponse.Value())
+
+	_, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 20))
+	assert.False(t, ok)
+}
+
+func TestDurableOnlyMultiGetCachesTheNewestDurableVersionsInTheRowCache(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer func() {
+		store.Close()
+		rowCache.Stop()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("paxos", 8), kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus algorithm"), kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.Backward([]segment.SortedSegment{aSegment}),
+		rowCache.Snapshot(),
+		NegativeLookupSnapshot{},
+	)
+	getResponses := getOperation.MultiGet([]kv.Key{
+		kv.NewStringKeyWithTimestamp("raft", 11),
+		kv.NewStringKeyWithTimestamp("paxos", 11),
+	})
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponses[0].Value())
+	assert.Equal(t, kv.NewStringValue("consensus algorithm"), getResponses[1].Value())
+
+	value, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 10))
+	assert.True(t, ok)
+	assert.Equal(t, kv.NewStringValue("consensus"), value)
+
+	value, ok = rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("paxos", 8))
+	assert.True(t, ok)
+	assert.Equal(t, kv.NewStringValue("consensus algorithm"), value)
+}
+
+func TestDurableOnlyMultiGetDoesNotCacheAVersionWhichIsNotTheNewestDurableVersion(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	aSegmentId := uint64(1)
+	anotherSegmentId := uint64(2)
+
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer func() {
+		store.Close()
+		rowCache.Stop()
+		_ = os.Remove(segment.PathSuffixForSegment(aSegmentId))
+		_ = os.Remove(segment.PathSuffixForSegment(anotherSegmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		aSegmentId,
+	)
+	assert.NoError(t, err)
+
+	anotherSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 50)},
+			values: []kv.Value{kv.NewStringValue("another consensus")},
+		},
+		anotherSegmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.All([]segment.SortedSegment{anotherSegment, aSegment}),
+		rowCache.Snapshot(),
+		NegativeLookupSnapshot{},
+	)
+	getResponses := getOperation.MultiGet([]kv.Key{kv.NewStringKeyWithTimestamp("raft", 20)})
+
+	assert.True(t, getResponses[0].IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponses[0].Value())
+
+	_, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 20))
+	assert.False(t, ok)
+}
+
+func TestDurableOnlyGetAndMultiGetAreAnsweredFromTheRowCache(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer func() {
+		store.Close()
+		rowCache.Stop()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("paxos", 10), kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus algorithm"), kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+	testCorruptFirstBlockOf(t, segmentId)
+
+	rowCache.Snapshot().set(kv.NewStringKeyWithTimestamp("raft", 10), kv.NewStringValue("consensus"))
+
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.Backward([]segment.SortedSegment{aSegment}),
+		rowCache.Snapshot(),
+		NegativeLookupSnapshot{},
+	)
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 11))
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponse.Value())
+
+	getResponses := getOperation.MultiGet([]kv.Key{
+		kv.NewStringKeyWithTimestamp("raft", 11),
+		kv.NewStringKeyWithTimestamp("paxos", 11),
+	})
+	assert.True(t, getResponses[0].IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponses[0].Value())
+	assert.True(t, getResponses[1].IsError())
+}
+
+func testNegativeLookupCache(t *testing.T) *cache.NegativeLookupCache {
+	negativeLookupCache, err := cache.NewNegativeLookupCache(cache.NewComparableKeyCacheOptions[string, cache.NegativeLookup](
+		4096,
+		5*time.Minute,
+		func(key string, value cache.NegativeLookup) uint32 {
+			return uint32(len(key)) + uint32(unsafe.Sizeof(value))
+		},
+	))
+	assert.NoError(t, err)
+	return &negativeLookupCache
+}
+
+func TestDurableOnlyGetRecordsAnAbsentKeyInTheNegativeLookupCache(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	negativeLookupCache := testNegativeLookupCache(t)
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.Backward([]segment.SortedSegment{aSegment}),
+		RowCacheSnapshot{},
+		NewNegativeLookupSnapshot(negativeLookupCache, segments.SegmentSetVersion()),
+	)
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("paxos", 11))
+	assert.False(t, getResponse.IsValueAvailable())
+
+	getResponse = getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 5))
+	assert.False(t, getResponse.IsValueAvailable())
+
+	getResponses := getOperation.MultiGet([]kv.Key{kv.NewStringKeyWithTimestamp("etcd", 11)})
+	assert.False(t, getResponses[0].IsValueAvailable())
+
+	assert.True(t, negativeLookupCache.IsAbsent([]byte("paxos"), 11, segments.SegmentSetVersion()))
+	assert.True(t, negativeLookupCache.IsAbsent([]byte("raft"), 5, segments.SegmentSetVersion()))
+	assert.True(t, negativeLookupCache.IsAbsent([]byte("etcd"), 11, segments.SegmentSetVersion()))
+	assert.False(t, negativeLookupCache.IsAbsent([]byte("raft"), 11, segments.SegmentSetVersion()))
+}
+
+func TestDurableOnlyGetAndMultiGetConsultTheNegativeLookupCache(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+	testCorruptFirstBlockOf(t, segmentId)
+
+	negativeLookupCache := testNegativeLookupCache(t)
+	negativeLookupCache.RecordAbsent([]byte("raft"), 11, segments.SegmentSetVersion())
+
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.Backward([]segment.SortedSegment{aSegment}),
+		RowCacheSnapshot{},
+		NewNegativeLookupSnapshot(negativeLookupCache, segments.SegmentSetVersion()),
+	)
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 11))
+	assert.False(t, getResponse.IsError())
+	assert.False(t, getResponse.IsValueAvailable())
+
+	getResponses := getOperation.MultiGet([]kv.Key{kv.NewStringKeyWithTimestamp("raft", 11)})
+	assert.False(t, getResponses[0].IsError())
+	assert.False(t, getResponses[0].IsValueAvailable())
+}
+
+func TestDurableOnlyGetIgnoresANegativeLookupOfAnotherSegmentSetVersion(t *testing.T) {
+	storeDefinition, err := objectstore.NewFileSystemStoreDefinition(".")
+	assert.NoError(t, err)
+
+	store := objectstore.NewStore(".", storeDefinition)
+	segmentId := uint64(1)
+
+	defer func() {
+		store.Close()
+		_ = os.Remove(segment.PathSuffixForSegment(segmentId))
+	}()
+
+	segments, err := testInstantiateSortedSegments(store)
+	assert.NoError(t, err)
+
+	negativeLookupCache := testNegativeLookupCache(t)
+	negativeLookupCache.RecordAbsent([]byte("raft"), 11, segments.SegmentSetVersion())
+
+	aSegment, err := segments.BuildAndWritePersistentSortedSegment(
+		&testKeyValueIterator{
+			keys:   []kv.Key{kv.NewStringKeyWithTimestamp("raft", 10)},
+			values: []kv.Value{kv.NewStringValue("consensus")},
+		},
+		segmentId,
+	)
+	assert.NoError(t, err)
+
+	getOperation := NewDurableOnlyGetWithCaches(
+		segments,
+		objectstore.NewIOScheduler(4),
+		slices.Backward([]segment.SortedSegment{aSegment}),
+		RowCacheSnapshot{},
+		NewNegativeLookupSnapshot(negativeLookupCache, segments.SegmentSetVersion()),
+	)
+	getResponse := getOperation.Get(kv.NewStringKeyWithTimestamp("raft", 11))
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, kv.NewStringValue("consensus"), getResponse.Value())
 }
@@ -0,0 +1,39 @@
+package get_strategies
+
+import (
+	"github.com/SarthakMakhija/zero-store/cache"
+	"github.com/SarthakMakhija/zero-store/kv"
+)
+
+// NegativeLookupSnapshot is the (optional) cache.NegativeLookupCache along with the segment set version of the persistent
+// segments at the beginning of a read, please check segment.SortedSegments.SegmentSetVersion.
+// It must be taken before the persistent segments of the read are collected, so that a key recorded absent at the
+// segmentSetVersion was looked up in (at least) all the persistent segments of that version.
+// A NegativeLookupSnapshot with a nil cache is disabled.
+type NegativeLookupSnapshot struct {
+	cache             *cache.NegativeLookupCache
+	segmentSetVersion uint64
+}
+
+func NewNegativeLookupSnapshot(negativeLookupCache *cache.NegativeLookupCache, segmentSetVersion uint64) NegativeLookupSnapshot {
+	return NegativeLookupSnapshot{
+		cache:             negativeLookupCache,
+		segmentSetVersion: segmentSetVersion,
+	}
+}
+
+// isAbsent returns true if the key is known to have no version visible at its timestamp in the persistent segments.
+func (snapshot NegativeLookupSnapshot) isAbsent(key kv.Key) bool {
+	if snapshot.cache == nil {
+		return false
+	}
+	return snapshot.cache.IsAbsent(key.RawBytes(), key.Timestamp(), snapshot.segmentSetVersion)
+}
+
+// recordAbsent records that the key has no version visible at its timestamp in the persistent segments.
+func (snapshot NegativeLookupSnapshot) recordAbsent(key kv.Key) {
+	if snapshot.cache == nil {
+		return
+	}
+	snapshot.cache.RecordAbsent(key.RawBytes(), key.Timestamp(), snapshot.segmentSetVersion)
+}
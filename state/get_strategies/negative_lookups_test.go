@@ -0,0 +1,29 @@
+package get_strategies
+
+import (
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNegativeLookupSnapshotRecordAbsentAndCheck(t *testing.T) {
+	snapshot := NewNegativeLookupSnapshot(testNegativeLookupCache(t), 3)
+	snapshot.recordAbsent(kv.NewStringKeyWithTimestamp("raft", 10))
+
+	assert.True(t, snapshot.isAbsent(kv.NewStringKeyWithTimestamp("raft", 10)))
+	assert.False(t, snapshot.isAbsent(kv.NewStringKeyWithTimestamp("raft", 11)))
+}
+
+func TestNegativeLookupSnapshotIsInvalidatedByANewSegmentSetVersion(t *testing.T) {
+	negativeLookupCache := testNegativeLookupCache(t)
+	NewNegativeLookupSnapshot(negativeLookupCache, 3).recordAbsent(kv.NewStringKeyWithTimestamp("raft", 10))
+
+	assert.False(t, NewNegativeLookupSnapshot(negativeLookupCache, 4).isAbsent(kv.NewStringKeyWithTimestamp("raft", 10)))
+}
+
+func TestDisabledNegativeLookupSnapshot(t *testing.T) {
+	snapshot := NegativeLookupSnapshot{}
+	snapshot.recordAbsent(kv.NewStringKeyWithTimestamp("raft", 10))
+
+	assert.False(t, snapshot.isAbsent(kv.NewStringKeyWithTimestamp("raft", 10)))
+}
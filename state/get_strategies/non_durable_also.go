@@ -21,3 +21,28 @@ func (getOperation NonDurableAlsoGet) Get(key kv.Key) GetResponse {
 	}
 	return getOperation.durableOnlyGetOperation.Get(key)
 }
+
+// MultiGet gets the values of the given keys, the responses are in the order of the keys.
+// Each key is looked up in the memory segments, and the keys whose values are not available in the memory segments are
+// looked up together in the persistent segments (please check DurableOnlyGet.MultiGet).
+func (getOperation NonDurableAlsoGet) MultiGet(keys []kv.Key) []GetResponse {
+	responses := make([]GetResponse, len(keys))
+	var durableKeyIndexes []int
+	var durableKeys []kv.Key
+	for index, key := range keys {
+		getResponse := getOperation.nonDurableOnlyGetOperation.Get(key)
+		if getResponse.IsValueAvailable() {
+			responses[index] = getResponse
+			continue
+		}
+		durableKeyIndexes = append(durableKeyIndexes, index)
+		durableKeys = append(durableKeys, key)
+	}
+	if len(durableKeys) == 0 {
+		return responses
+	}
+	for index, getResponse := range getOperation.durableOnlyGetOperation.MultiGet(durableKeys) {
+		responses[durableKeyIndexes[index]] = getResponse
+	}
+	return responses
+}
@@ -38,6 +38,11 @@ func (response GetResponse) IsError() bool {
 	return response.err != nil
 }
 
+// Error returns the error (if any) which occurred while getting the value, for example: *segment.ErrCorruption.
+func (response GetResponse) Error() error {
+	return response.err
+}
+
 func (response GetResponse) Value() kv.Value {
 	return response.value
 }
@@ -23,4 +23,5 @@ func TestGetResponseWithAnError(t *testing.T) {
 	response := errorResponse(errors.New("test error"))
 	assert.False(t, response.IsValueAvailable())
 	assert.True(t, response.IsError())
+	assert.Equal(t, "test error", response.Error().Error())
 }
@@ -0,0 +1,118 @@
+package get_strategies
+
+import (
+	"github.com/SarthakMakhija/zero-store/cache/key_cache"
+	"github.com/SarthakMakhija/zero-store/kv"
+	"iter"
+	"sync"
+)
+
+// RowCache caches the versions of the keys read by DurableOnlyGet, it is a key_cache.KeyCache keyed by the raw key and the
+// timestamp of the version.
+// A version (at timestamp t) is cached only if it is the newest durable version of its key, that is, the read timestamp
+// is not below the maximum timestamp of any persistent segment. Hence, a cached version answers every read at or above
+// t, as long as no newer version of the key is flushed. key_cache.KeyCache returns the newest cached version at or below
+// the read timestamp, and misses if there is no such version.
+// A flush invalidates the cached versions of all the keys of the flushed segment:
+// 1) BeginFlush bypasses the RowCache (for both the lookups and the population) until the flush ends, because the
+// persistent segment becomes visible to the reads before its keys are invalidated.
+// 2) EndFlush deletes all the cached versions of the given keys.
+// Each flush moves the generation forward, and a read populates the RowCache only if the generation has not moved since
+// the read started (please check RowCacheSnapshot), so a read which did not see a flushed segment never caches a stale
+// version.
+// A nil RowCache is disabled.
+type RowCache struct {
+	keyCache          *key_cache.KeyCache
+	generation        uint64
+	flushesInProgress int
+	lock              sync.RWMutex
+}
+
+// RowCacheSnapshot is the RowCache along with its generation at the beginning of a read.
+// It must be taken before the persistent segments of the read are collected.
+type RowCacheSnapshot struct {
+	rowCache   *RowCache
+	generation uint64
+}
+
+func NewRowCache(options key_cache.KeyCacheOptions) *RowCache {
+	return &RowCache{
+		keyCache: key_cache.NewKeyCache(options),
+	}
+}
+
+// Snapshot returns the RowCacheSnapshot at the current generation.
+func (rowCache *RowCache) Snapshot() RowCacheSnapshot {
+	if rowCache == nil {
+		return RowCacheSnapshot{}
+	}
+	rowCache.lock.RLock()
+	defer rowCache.lock.RUnlock()
+
+	return RowCacheSnapshot{rowCache: rowCache, generation: rowCache.generation}
+}
+
+// BeginFlush bypasses the RowCache until the matching EndFlush.
+func (rowCache *RowCache) BeginFlush() {
+	if rowCache == nil {
+		return
+	}
+	rowCache.lock.Lock()
+	defer rowCache.lock.Unlock()
+
+	rowCache.generation++
+	rowCache.flushesInProgress++
+}
+
+// EndFlush invalidates all the cached versions of the keys returned by the given sequence (the keys of the flushed
+// segment), and ends the bypass of BeginFlush.
+func (rowCache *RowCache) EndFlush(keys iter.Seq[kv.Key]) {
+	if rowCache == nil {
+		return
+	}
+	rowCache.lock.Lock()
+	defer rowCache.lock.Unlock()
+
+	for key := range keys {
+		rowCache.keyCache.Delete(key)
+	}
+	rowCache.generation++
+	rowCache.flushesInProgress--
+}
+
+// Stop stops the RowCache.
+func (rowCache *RowCache) Stop() {
+	if rowCache == nil {
+		return
+	}
+	rowCache.keyCache.Stop()
+}
+
+// get returns the cached version of the key visible at the timestamp of the key.
+func (snapshot RowCacheSnapshot) get(key kv.Key) (kv.Value, bool) {
+	if snapshot.rowCache == nil {
+		return kv.EmptyValue, false
+	}
+	snapshot.rowCache.lock.RLock()
+	defer snapshot.rowCache.lock.RUnlock()
+
+	if snapshot.rowCache.flushesInProgress > 0 {
+		return kv.EmptyValue, false
+	}
+	return snapshot.rowCache.keyCache.Get(key)
+}
+
+// set caches the given version (versionKey is the key with the timestamp of the version), if no flush has begun since
+// the snapshot was taken.
+func (snapshot RowCacheSnapshot) set(versionKey kv.Key, value kv.Value) {
+	if snapshot.rowCache == nil {
+		return
+	}
+	snapshot.rowCache.lock.RLock()
+	defer snapshot.rowCache.lock.RUnlock()
+
+	if snapshot.rowCache.flushesInProgress > 0 || snapshot.rowCache.generation != snapshot.generation {
+		return
+	}
+	snapshot.rowCache.keyCache.Set(versionKey, value)
+}
@@ -0,0 +1,92 @@
+package get_strategies
+
+import (
+	"github.com/SarthakMakhija/zero-store/cache/key_cache"
+	"github.com/SarthakMakhija/zero-store/kv"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRowCacheSetAndGetAVersion(t *testing.T) {
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer rowCache.Stop()
+
+	snapshot := rowCache.Snapshot()
+	snapshot.set(kv.NewStringKeyWithTimestamp("raft", 10), kv.NewStringValue("consensus"))
+
+	value, ok := snapshot.get(kv.NewStringKeyWithTimestamp("raft", 12))
+	assert.True(t, ok)
+	assert.Equal(t, kv.NewStringValue("consensus"), value)
+}
+
+func TestRowCacheGetAVersionAboveTheReadTimestamp(t *testing.T) {
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer rowCache.Stop()
+
+	snapshot := rowCache.Snapshot()
+	snapshot.set(kv.NewStringKeyWithTimestamp("raft", 10), kv.NewStringValue("consensus"))
+
+	_, ok := snapshot.get(kv.NewStringKeyWithTimestamp("raft", 9))
+	assert.False(t, ok)
+}
+
+func TestRowCacheDoesNotSetAVersionIfAFlushBeganAfterTheSnapshot(t *testing.T) {
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer rowCache.Stop()
+
+	snapshot := rowCache.Snapshot()
+	rowCache.BeginFlush()
+	rowCache.EndFlush(slices.Values([]kv.Key{kv.NewStringKeyWithTimestamp("paxos", 5)}))
+
+	snapshot.set(kv.NewStringKeyWithTimestamp("raft", 10), kv.NewStringValue("consensus"))
+
+	_, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 12))
+	assert.False(t, ok)
+}
+
+func TestRowCacheIsBypassedDuringAFlush(t *testing.T) {
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer rowCache.Stop()
+
+	rowCache.Snapshot().set(kv.NewStringKeyWithTimestamp("raft", 10), kv.NewStringValue("consensus"))
+
+	rowCache.BeginFlush()
+	_, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 12))
+	assert.False(t, ok)
+
+	rowCache.EndFlush(slices.Values([]kv.Key{kv.NewStringKeyWithTimestamp("paxos", 5)}))
+	value, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 12))
+	assert.True(t, ok)
+	assert.Equal(t, kv.NewStringValue("consensus"), value)
+}
+
+func TestRowCacheEndFlushInvalidatesTheFlushedKeys(t *testing.T) {
+	rowCache := NewRowCache(key_cache.NewKeyCacheOptions(512*1024, time.Second*10))
+	defer rowCache.Stop()
+
+	snapshot := rowCache.Snapshot()
+	snapshot.set(kv.NewStringKeyWithTimestamp("raft", 10), kv.NewStringValue("consensus"))
+	snapshot.set(kv.NewStringKeyWithTimestamp("paxos", 8), kv.NewStringValue("consensus algorithm"))
+
+	rowCache.BeginFlush()
+	rowCache.EndFlush(slices.Values([]kv.Key{kv.NewStringKeyWithTimestamp("raft", 15)}))
+
+	_, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("raft", 20))
+	assert.False(t, ok)
+
+	value, ok := rowCache.Snapshot().get(kv.NewStringKeyWithTimestamp("paxos", 20))
+	assert.True(t, ok)
+	assert.Equal(t, kv.NewStringValue("consensus algorithm"), value)
+}
+
+func TestNilRowCacheIsDisabled(t *testing.T) {
+	var rowCache *RowCache
+
+	snapshot := rowCache.Snapshot()
+	snapshot.set(kv.NewStringKeyWithTimestamp("raft", 10), kv.NewStringValue("consensus"))
+
+	_, ok := snapshot.get(kv.NewStringKeyWithTimestamp("raft", 12))
+	assert.False(t, ok)
+}
@@ -2,9 +2,13 @@ package state
 
 import (
 	"github.com/SarthakMakhija/zero-store/cache"
+	"github.com/SarthakMakhija/zero-store/cache/key_cache"
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/block"
 	"github.com/SarthakMakhija/zero-store/objectstore/filter"
+	objectStore "github.com/SarthakMakhija/zero-store/objectstore/segment"
+	"github.com/SarthakMakhija/zero-store/state/get_strategies"
+	"math"
 	"time"
 	"unsafe"
 )
@@ -15,45 +19,110 @@ const (
 
 	blockMetaListCacheSizeInBytes = 16 * 1024 * 1024
 	blockMetaListCacheEntryTTL    = 5 * time.Minute
+
+	compressionDictionaryCacheSizeInBytes = 8 * 1024 * 1024
+	compressionDictionaryCacheEntryTTL    = 5 * time.Minute
+
+	blockCacheSizeInBytes = 64 * 1024 * 1024
+	blockCacheEntryTTL    = 5 * time.Minute
+
+	maxBatchSizeInBytes = 64 * 1024 * 1024
+	maxConcurrentReads  = 16
+
+	readaheadInitialWindow = 2
+	readaheadMaximumWindow = 16
+	// arena of memory.SortedSegment uses uint32 offsets, the max batch size bounds the size of the dedicated
+	// memory.SortedSegment of a batch, please check StorageState.Set.
+	maxAllowedBatchSizeInBytes = math.MaxUint32 / 2
 )
 
 type StorageOptions struct {
-	sortedSegmentSizeInBytes      int64
-	storeType                     objectstore.StoreType
-	rootDirectory                 string
-	sortedSegmentBlockCompression bool
-	flushInactiveSegmentDuration  time.Duration
-	bloomFilterCacheOptions       cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter]
-	blockMetaListCacheOptions     cache.ComparableKeyCacheOptions[uint64, *block.MetaList]
+	sortedSegmentSizeInBytes          int64
+	maxBatchSizeInBytes               int64
+	storeType                         objectstore.StoreType
+	rootDirectory                     string
+	sortedSegmentBlockSize            uint
+	sortedSegmentBlockCompression     bool
+	sortedSegmentCompressionCodec     block.CompressionCodec
+	compressionDictionarySizeInBytes  uint
+	sortedSegmentBlockHashIndex       bool
+	bloomFilterFalsePositiveRate      float64
+	valueSeparationThresholdInBytes   uint
+	flushInactiveSegmentDuration      time.Duration
+	maxConcurrentReads                uint
+	readaheadOptions                  objectStore.ReadaheadOptions
+	rowCacheOptions                   *key_cache.KeyCacheOptions
+	negativeLookupCacheOptions        *cache.ComparableKeyCacheOptions[string, cache.NegativeLookup]
+	diskCacheDirectory                string
+	diskCacheSizeInBytes              int64
+	bloomFilterCacheOptions           cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter]
+	blockMetaListCacheOptions         cache.ComparableKeyCacheOptions[uint64, *block.MetaList]
+	compressionDictionaryCacheOptions cache.ComparableKeyCacheOptions[uint64, *block.CompressionDictionary]
+	blockCacheOptions                 cache.ComparableKeyCacheOptions[cache.BlockCacheKey, block.Block]
 }
 
 type StorageOptionsBuilder struct {
-	sortedSegmentSizeInBytes      int64
-	storeType                     objectstore.StoreType
-	rootDirectory                 string
-	sortedSegmentBlockCompression bool
-	flushInactiveSegmentDuration  time.Duration
-	bloomFilterCacheOptions       cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter]
-	blockMetaListCacheOptions     cache.ComparableKeyCacheOptions[uint64, *block.MetaList]
+	sortedSegmentSizeInBytes          int64
+	maxBatchSizeInBytes               int64
+	storeType                         objectstore.StoreType
+	rootDirectory                     string
+	sortedSegmentBlockSize            uint
+	sortedSegmentBlockCompression     bool
+	sortedSegmentCompressionCodec     block.CompressionCodec
+	compressionDictionarySizeInBytes  uint
+	sortedSegmentBlockHashIndex       bool
+	bloomFilterFalsePositiveRate      float64
+	valueSeparationThresholdInBytes   uint
+	flushInactiveSegmentDuration      time.Duration
+	maxConcurrentReads                uint
+	readaheadOptions                  objectStore.ReadaheadOptions
+	rowCacheOptions                   *key_cache.KeyCacheOptions
+	negativeLookupCacheOptions        *cache.ComparableKeyCacheOptions[string, cache.NegativeLookup]
+	diskCacheDirectory                string
+	diskCacheSizeInBytes              int64
+	bloomFilterCacheOptions           cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter]
+	blockMetaListCacheOptions         cache.ComparableKeyCacheOptions[uint64, *block.MetaList]
+	compressionDictionaryCacheOptions cache.ComparableKeyCacheOptions[uint64, *block.CompressionDictionary]
+	blockCacheOptions                 cache.ComparableKeyCacheOptions[cache.BlockCacheKey, block.Block]
 }
 
 func NewStorageOptionsBuilder() *StorageOptionsBuilder {
 	return &StorageOptionsBuilder{
 		sortedSegmentSizeInBytes:      1 << 15, //32 Kib
+		maxBatchSizeInBytes:           maxBatchSizeInBytes,
+		sortedSegmentBlockSize:        block.DefaultBlockSize,
 		sortedSegmentBlockCompression: false,
+		sortedSegmentCompressionCodec: block.CompressionCodecS2,
+		bloomFilterFalsePositiveRate:  filter.DefaultFalsePositiveRate,
 		flushInactiveSegmentDuration:  60 * time.Second,
+		maxConcurrentReads:            maxConcurrentReads,
+		readaheadOptions:              objectStore.NewReadaheadOptions(readaheadInitialWindow, readaheadMaximumWindow),
 		bloomFilterCacheOptions: cache.NewComparableKeyCacheOptions[uint64, filter.BloomFilter](
 			bloomFilterCacheSizeInBytes,
 			bloomFilterCacheEntryTTL,
 			func(id uint64, value filter.BloomFilter) uint32 {
-				return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
+				return uint32(unsafe.Sizeof(id)) + uint32(value.SizeInBytes())
 			},
 		),
 		blockMetaListCacheOptions: cache.NewComparableKeyCacheOptions[uint64, *block.MetaList](
 			blockMetaListCacheSizeInBytes,
 			blockMetaListCacheEntryTTL,
 			func(id uint64, value *block.MetaList) uint32 {
-				return uint32(unsafe.Sizeof(id) + unsafe.Sizeof(value))
+				return uint32(unsafe.Sizeof(id)) + uint32(value.SizeInBytes())
+			},
+		),
+		compressionDictionaryCacheOptions: cache.NewComparableKeyCacheOptions[uint64, *block.CompressionDictionary](
+			compressionDictionaryCacheSizeInBytes,
+			compressionDictionaryCacheEntryTTL,
+			func(id uint64, value *block.CompressionDictionary) uint32 {
+				return uint32(unsafe.Sizeof(id)) + uint32(value.SizeInBytes())
+			},
+		),
+		blockCacheOptions: cache.NewComparableKeyCacheOptions[cache.BlockCacheKey, block.Block](
+			blockCacheSizeInBytes,
+			blockCacheEntryTTL,
+			func(key cache.BlockCacheKey, value block.Block) uint32 {
+				return uint32(unsafe.Sizeof(key)) + uint32(value.SizeInBytes())
 			},
 		),
 	}
@@ -68,22 +137,142 @@ func (builder *StorageOptionsBuilder) WithSortedSegmentSizeInBytes(size int64) *
 	return builder
 }
 
+func (builder *StorageOptionsBuilder) WithMaxBatchSizeInBytes(size int64) *StorageOptionsBuilder {
+	if size <= 0 {
+		panic("max batch size must be greater than 0")
+	}
+	if size > maxAllowedBatchSizeInBytes {
+		panic("max batch size must not be greater than 2 GiB")
+	}
+	builder.maxBatchSizeInBytes = size
+	return builder
+}
+
 func (builder *StorageOptionsBuilder) WithFileSystemStoreType(rootDirectory string) *StorageOptionsBuilder {
 	builder.storeType = objectstore.FileSystemStore
 	builder.rootDirectory = rootDirectory
 	return builder
 }
 
+// WithSortedSegmentBlockSize sets the block size of the persistent sorted segments.
+func (builder *StorageOptionsBuilder) WithSortedSegmentBlockSize(size uint) *StorageOptionsBuilder {
+	if size < block.MinimumBlockSize || size > block.MaximumBlockSize {
+		panic("sorted segment block size must be between 64 bytes and 4 MiB")
+	}
+	builder.sortedSegmentBlockSize = size
+	return builder
+}
+
+// WithBloomFilterFalsePositiveRate sets the false positive rate of the bloom filters of the persistent sorted segments.
+func (builder *StorageOptionsBuilder) WithBloomFilterFalsePositiveRate(rate float64) *StorageOptionsBuilder {
+	if rate <= 0 || rate >= 1 {
+		panic("bloom filter false positive rate must be between 0 and 1 (exclusive)")
+	}
+	builder.bloomFilterFalsePositiveRate = rate
+	return builder
+}
+
+// EnableSortedSegmentBlockCompression enables the compression of the data blocks and the block meta list of the persistent
+// sorted segments. The data blocks are compressed using block.CompressionCodecS2, unless a different codec is set using
+// WithSortedSegmentBlockCompressionCodec.
 func (builder *StorageOptionsBuilder) EnableSortedSegmentBlockCompression() *StorageOptionsBuilder {
 	builder.sortedSegmentBlockCompression = true
 	return builder
 }
 
+// WithSortedSegmentBlockCompressionCodec enables the compression of the persistent sorted segments, and sets the codec used
+// to compress their data blocks. block.CompressionCodecNone disables the compression.
+func (builder *StorageOptionsBuilder) WithSortedSegmentBlockCompressionCodec(codec block.CompressionCodec) *StorageOptionsBuilder {
+	if _, err := block.CodecFor(codec); err != nil {
+		panic(err)
+	}
+	builder.sortedSegmentBlockCompression = codec != block.CompressionCodecNone
+	builder.sortedSegmentCompressionCodec = codec
+	return builder
+}
+
+// WithSortedSegmentCompressionDictionarySizeInBytes enables the compression of the persistent sorted segments using a zstd
+// dictionary of at most the given size, which is trained from the sampled values of each persistent sorted segment.
+// It is useful for small values (like small JSON documents) which compress poorly one block at a time.
+func (builder *StorageOptionsBuilder) WithSortedSegmentCompressionDictionarySizeInBytes(size uint) *StorageOptionsBuilder {
+	if size == 0 {
+		panic("compression dictionary size must be greater than 0")
+	}
+	builder.sortedSegmentBlockCompression = true
+	builder.sortedSegmentCompressionCodec = block.CompressionCodecZstd
+	builder.compressionDictionarySizeInBytes = size
+	return builder
+}
+
+// EnableSortedSegmentBlockHashIndex appends a hash index to each data block of the persistent sorted segments, which allows
+// the point lookups to skip the binary search within a block.
+func (builder *StorageOptionsBuilder) EnableSortedSegmentBlockHashIndex() *StorageOptionsBuilder {
+	builder.sortedSegmentBlockHashIndex = true
+	return builder
+}
+
+// WithValueSeparationThresholdInBytes enables the key-value separation, values larger than the threshold are stored in the value logs.
+func (builder *StorageOptionsBuilder) WithValueSeparationThresholdInBytes(threshold uint) *StorageOptionsBuilder {
+	builder.valueSeparationThresholdInBytes = threshold
+	return builder
+}
+
 func (builder *StorageOptionsBuilder) WithFlushInactiveSegmentDuration(duration time.Duration) *StorageOptionsBuilder {
 	builder.flushInactiveSegmentDuration = duration
 	return builder
 }
 
+// WithMaxConcurrentReads sets the maximum number of object store reads which are issued concurrently by the Db, please
+// check objectstore.IOScheduler.
+func (builder *StorageOptionsBuilder) WithMaxConcurrentReads(reads uint) *StorageOptionsBuilder {
+	if reads == 0 {
+		panic("max concurrent reads must be greater than 0")
+	}
+	builder.maxConcurrentReads = reads
+	return builder
+}
+
+// WithSortedSegmentReadahead sets the initial and the maximum readahead windows (in blocks) of the scans over the
+// persistent sorted segments, please check objectStore.ReadaheadOptions.
+func (builder *StorageOptionsBuilder) WithSortedSegmentReadahead(initialWindow uint, maximumWindow uint) *StorageOptionsBuilder {
+	builder.readaheadOptions = objectStore.NewReadaheadOptions(initialWindow, maximumWindow)
+	return builder
+}
+
+// DisableSortedSegmentReadahead disables the readahead of the scans over the persistent sorted segments.
+func (builder *StorageOptionsBuilder) DisableSortedSegmentReadahead() *StorageOptionsBuilder {
+	builder.readaheadOptions = objectStore.NoReadahead()
+	return builder
+}
+
+// WithRowCache enables the row cache in front of the persistent sorted segments, which caches the newest durable versions
+// of the keys read by the durable gets, please check get_strategies.RowCache.
+func (builder *StorageOptionsBuilder) WithRowCache(options key_cache.KeyCacheOptions) *StorageOptionsBuilder {
+	builder.rowCacheOptions = &options
+	return builder
+}
+
+// WithNegativeLookupCacheOptions enables the cache of the keys absent in the persistent sorted segments, which is consulted
+// before the persistent sorted segments are looked up, please check cache.NegativeLookupCache.
+func (builder *StorageOptionsBuilder) WithNegativeLookupCacheOptions(options cache.ComparableKeyCacheOptions[string, cache.NegativeLookup]) *StorageOptionsBuilder {
+	builder.negativeLookupCacheOptions = &options
+	return builder
+}
+
+// WithDiskCache enables the local-disk cache of the byte ranges read from the object store, on the given directory with
+// the given size, please check objectstore.DiskCache.
+func (builder *StorageOptionsBuilder) WithDiskCache(directory string, sizeInBytes int64) *StorageOptionsBuilder {
+	if len(directory) == 0 {
+		panic("disk cache directory must be specified")
+	}
+	if sizeInBytes <= 0 {
+		panic("disk cache size must be greater than 0")
+	}
+	builder.diskCacheDirectory = directory
+	builder.diskCacheSizeInBytes = sizeInBytes
+	return builder
+}
+
 func (builder *StorageOptionsBuilder) WithBloomFilterCacheOptions(options cache.ComparableKeyCacheOptions[uint64, filter.BloomFilter]) *StorageOptionsBuilder {
 	builder.bloomFilterCacheOptions = options
 	return builder
@@ -94,6 +283,16 @@ func (builder *StorageOptionsBuilder) WithBlockMetaListCacheOptions(options cach
 	return builder
 }
 
+func (builder *StorageOptionsBuilder) WithCompressionDictionaryCacheOptions(options cache.ComparableKeyCacheOptions[uint64, *block.CompressionDictionary]) *StorageOptionsBuilder {
+	builder.compressionDictionaryCacheOptions = options
+	return builder
+}
+
+func (builder *StorageOptionsBuilder) WithBlockCacheOptions(options cache.ComparableKeyCacheOptions[cache.BlockCacheKey, block.Block]) *StorageOptionsBuilder {
+	builder.blockCacheOptions = options
+	return builder
+}
+
 func (builder *StorageOptionsBuilder) Build() StorageOptions {
 	if !builder.storeType.IsValid() {
 		panic("invalid store type")
@@ -102,12 +301,86 @@ func (builder *StorageOptionsBuilder) Build() StorageOptions {
 		panic("root directory must be specified")
 	}
 	return StorageOptions{
-		sortedSegmentSizeInBytes:      builder.sortedSegmentSizeInBytes,
-		storeType:                     builder.storeType,
-		rootDirectory:                 builder.rootDirectory,
-		sortedSegmentBlockCompression: builder.sortedSegmentBlockCompression,
-		flushInactiveSegmentDuration:  builder.flushInactiveSegmentDuration,
-		bloomFilterCacheOptions:       builder.bloomFilterCacheOptions,
-		blockMetaListCacheOptions:     builder.blockMetaListCacheOptions,
+		sortedSegmentSizeInBytes:          builder.sortedSegmentSizeInBytes,
+		maxBatchSizeInBytes:               builder.maxBatchSizeInBytes,
+		storeType:                         builder.storeType,
+		rootDirectory:                     builder.rootDirectory,
+		sortedSegmentBlockSize:            builder.sortedSegmentBlockSize,
+		sortedSegmentBlockCompression:     builder.sortedSegmentBlockCompression,
+		sortedSegmentCompressionCodec:     builder.sortedSegmentCompressionCodec,
+		compressionDictionarySizeInBytes:  builder.compressionDictionarySizeInBytes,
+		sortedSegmentBlockHashIndex:       builder.sortedSegmentBlockHashIndex,
+		bloomFilterFalsePositiveRate:      builder.bloomFilterFalsePositiveRate,
+		valueSeparationThresholdInBytes:   builder.valueSeparationThresholdInBytes,
+		flushInactiveSegmentDuration:      builder.flushInactiveSegmentDuration,
+		maxConcurrentReads:                builder.maxConcurrentReads,
+		readaheadOptions:                  builder.readaheadOptions,
+		rowCacheOptions:                   builder.rowCacheOptions,
+		negativeLookupCacheOptions:        builder.negativeLookupCacheOptions,
+		diskCacheDirectory:                builder.diskCacheDirectory,
+		diskCacheSizeInBytes:              builder.diskCacheSizeInBytes,
+		bloomFilterCacheOptions:           builder.bloomFilterCacheOptions,
+		blockMetaListCacheOptions:         builder.blockMetaListCacheOptions,
+		compressionDictionaryCacheOptions: builder.compressionDictionaryCacheOptions,
+		blockCacheOptions:                 builder.blockCacheOptions,
+	}
+}
+
+// rowCache returns the get_strategies.RowCache of the Db, it returns nil if the row cache is not enabled.
+func (options StorageOptions) rowCache() *get_strategies.RowCache {
+	if options.rowCacheOptions == nil {
+		return nil
+	}
+	return get_strategies.NewRowCache(*options.rowCacheOptions)
+}
+
+// negativeLookupCache returns the cache.NegativeLookupCache of the Db, it returns nil if the negative lookup cache is not
+// enabled.
+func (options StorageOptions) negativeLookupCache() (*cache.NegativeLookupCache, error) {
+	if options.negativeLookupCacheOptions == nil {
+		return nil, nil
+	}
+	negativeLookupCache, err := cache.NewNegativeLookupCache(*options.negativeLookupCacheOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &negativeLookupCache, nil
+}
+
+// diskCache returns the objectstore.DiskCache of the Db, it returns nil if the disk cache is not enabled.
+func (options StorageOptions) diskCache() (*objectstore.DiskCache, error) {
+	if len(options.diskCacheDirectory) == 0 {
+		return nil, nil
+	}
+	return objectstore.NewDiskCache(options.diskCacheDirectory, options.diskCacheSizeInBytes)
+}
+
+// sortedSegmentFormatOptions returns the format options of the persistent sorted segments.
+func (options StorageOptions) sortedSegmentFormatOptions() objectStore.SortedSegmentFormatOptions {
+	compressionCodec := options.sortedSegmentCompressionCodecOrNone()
+	formatOptions := objectStore.NewSortedSegmentFormatOptionsWithCompressionCodec(
+		options.sortedSegmentBlockSize,
+		options.bloomFilterFalsePositiveRate,
+		compressionCodec,
+	)
+	if compressionCodec == block.CompressionCodecZstd && options.compressionDictionarySizeInBytes > 0 {
+		formatOptions = objectStore.NewSortedSegmentFormatOptionsWithCompressionDictionary(
+			options.sortedSegmentBlockSize,
+			options.bloomFilterFalsePositiveRate,
+			options.compressionDictionarySizeInBytes,
+		)
+	}
+	if options.sortedSegmentBlockHashIndex {
+		formatOptions = formatOptions.WithBlockHashIndex()
+	}
+	return formatOptions
+}
+
+// sortedSegmentCompressionCodecOrNone returns the codec used to compress the data blocks of the persistent sorted segments,
+// it returns block.CompressionCodecNone if the compression is not enabled.
+func (options StorageOptions) sortedSegmentCompressionCodecOrNone() block.CompressionCodec {
+	if !options.sortedSegmentBlockCompression {
+		return block.CompressionCodecNone
 	}
+	return options.sortedSegmentCompressionCodec
 }
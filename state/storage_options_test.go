@@ -2,12 +2,15 @@ package state
 
 import (
 	"github.com/SarthakMakhija/zero-store/cache"
+	"github.com/SarthakMakhija/zero-store/cache/key_cache"
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	"github.com/SarthakMakhija/zero-store/objectstore/block"
 	"github.com/SarthakMakhija/zero-store/objectstore/filter"
+	objectStore "github.com/SarthakMakhija/zero-store/objectstore/segment"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
+	"unsafe"
 )
 
 func TestStorageOptionsWithSortedSegmentSize(t *testing.T) {
@@ -15,6 +18,17 @@ func TestStorageOptionsWithSortedSegmentSize(t *testing.T) {
 	assert.Equal(t, int64(2<<10), storageOptions.sortedSegmentSizeInBytes)
 }
 
+func TestStorageOptionsWithMaxBatchSize(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithMaxBatchSizeInBytes(1 << 20).WithFileSystemStoreType(".").Build()
+	assert.Equal(t, int64(1<<20), storageOptions.maxBatchSizeInBytes)
+}
+
+func TestStorageOptionsWithMaxBatchSizeBeyondTheAllowedSize(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithMaxBatchSizeInBytes(1 << 32)
+	})
+}
+
 func TestStorageOptionsWithoutStoreType(t *testing.T) {
 	assert.Panics(t, func() {
 		NewStorageOptionsBuilder().WithSortedSegmentSizeInBytes(2 << 10).Build()
@@ -47,6 +61,42 @@ func TestStorageOptionsWithSortedSegmentBlockCompressionNotEnabled(t *testing.T)
 	assert.False(t, storageOptions.sortedSegmentBlockCompression)
 }
 
+func TestStorageOptionsWithSortedSegmentBlockCompressionCodec(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithSortedSegmentSizeInBytes(2 << 10).
+		WithFileSystemStoreType(".").
+		WithSortedSegmentBlockCompressionCodec(block.CompressionCodecZstd).
+		Build()
+	assert.True(t, storageOptions.sortedSegmentBlockCompression)
+	assert.Equal(t, block.CompressionCodecZstd, storageOptions.sortedSegmentCompressionCodecOrNone())
+}
+
+func TestStorageOptionsWithSortedSegmentBlockCompressionCodecNone(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithSortedSegmentSizeInBytes(2 << 10).
+		WithFileSystemStoreType(".").
+		EnableSortedSegmentBlockCompression().
+		WithSortedSegmentBlockCompressionCodec(block.CompressionCodecNone).
+		Build()
+	assert.False(t, storageOptions.sortedSegmentBlockCompression)
+	assert.Equal(t, block.CompressionCodecNone, storageOptions.sortedSegmentCompressionCodecOrNone())
+}
+
+func TestStorageOptionsWithSortedSegmentBlockCompressionEnabledUsesS2ByDefault(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithSortedSegmentSizeInBytes(2 << 10).
+		WithFileSystemStoreType(".").
+		EnableSortedSegmentBlockCompression().
+		Build()
+	assert.Equal(t, block.CompressionCodecS2, storageOptions.sortedSegmentCompressionCodecOrNone())
+}
+
+func TestStorageOptionsWithAnUnknownSortedSegmentBlockCompressionCodec(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithSortedSegmentBlockCompressionCodec(block.CompressionCodec(250))
+	})
+}
+
 func TestStorageOptionsFlushInactiveSegmentDuration(t *testing.T) {
 	storageOptions := NewStorageOptionsBuilder().
 		WithSortedSegmentSizeInBytes(2 << 10).
@@ -75,3 +125,163 @@ func TestStorageOptionsBlockMetaListCacheOptions(t *testing.T) {
 	assert.Equal(t, uint(150), storageOptions.blockMetaListCacheOptions.SizeInBytes())
 	assert.Equal(t, 3*time.Minute, storageOptions.blockMetaListCacheOptions.EntryTTL())
 }
+
+func TestStorageOptionsWithValueSeparationThreshold(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithValueSeparationThresholdInBytes(1 << 10).WithFileSystemStoreType(".").Build()
+	assert.Equal(t, uint(1<<10), storageOptions.valueSeparationThresholdInBytes)
+}
+
+func TestStorageOptionsWithSortedSegmentBlockSize(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithSortedSegmentBlockSize(8 << 10).WithFileSystemStoreType(".").Build()
+	assert.Equal(t, uint(8<<10), storageOptions.sortedSegmentBlockSize)
+}
+
+func TestStorageOptionsWithSortedSegmentBlockSizeOutsideTheAllowedRange(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithSortedSegmentBlockSize(16)
+	})
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithSortedSegmentBlockSize(block.MaximumBlockSize << 1)
+	})
+}
+
+func TestStorageOptionsWithBloomFilterFalsePositiveRate(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithBloomFilterFalsePositiveRate(0.001).WithFileSystemStoreType(".").Build()
+	assert.Equal(t, 0.001, storageOptions.bloomFilterFalsePositiveRate)
+}
+
+func TestStorageOptionsWithInvalidBloomFilterFalsePositiveRate(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithBloomFilterFalsePositiveRate(1)
+	})
+}
+
+func TestStorageOptionsWithSortedSegmentCompressionDictionarySize(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithSortedSegmentCompressionDictionarySizeInBytes(4 << 10).
+		WithFileSystemStoreType(".").
+		Build()
+
+	assert.Equal(t, uint(4<<10), storageOptions.compressionDictionarySizeInBytes)
+	assert.Equal(t, block.CompressionCodecZstd, storageOptions.sortedSegmentCompressionCodecOrNone())
+	assert.Equal(
+		t,
+		objectStore.NewSortedSegmentFormatOptionsWithCompressionDictionary(block.DefaultBlockSize, filter.DefaultFalsePositiveRate, 4<<10),
+		storageOptions.sortedSegmentFormatOptions(),
+	)
+}
+
+func TestStorageOptionsWithZeroSortedSegmentCompressionDictionarySize(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithSortedSegmentCompressionDictionarySizeInBytes(0)
+	})
+}
+
+func TestStorageOptionsCompressionDictionaryCacheOptions(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithCompressionDictionaryCacheOptions(cache.NewComparableKeyCacheOptions[uint64, *block.CompressionDictionary](1<<10, 2*time.Minute, nil)).
+		Build()
+	assert.Equal(t, uint(1<<10), storageOptions.compressionDictionaryCacheOptions.SizeInBytes())
+	assert.Equal(t, 2*time.Minute, storageOptions.compressionDictionaryCacheOptions.EntryTTL())
+}
+
+func TestStorageOptionsBlockCacheOptions(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithBlockCacheOptions(cache.NewComparableKeyCacheOptions[cache.BlockCacheKey, block.Block](4<<10, 3*time.Minute, nil)).
+		Build()
+	assert.Equal(t, uint(4<<10), storageOptions.blockCacheOptions.SizeInBytes())
+	assert.Equal(t, 3*time.Minute, storageOptions.blockCacheOptions.EntryTTL())
+}
+
+func TestStorageOptionsWithSortedSegmentReadahead(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithFileSystemStoreType(".").WithSortedSegmentReadahead(1, 8).Build()
+	assert.Equal(t, objectStore.NewReadaheadOptions(1, 8), storageOptions.readaheadOptions)
+}
+
+func TestStorageOptionsWithDisabledSortedSegmentReadahead(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithFileSystemStoreType(".").DisableSortedSegmentReadahead().Build()
+	assert.Equal(t, objectStore.NoReadahead(), storageOptions.readaheadOptions)
+}
+
+func TestStorageOptionsWithRowCache(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithRowCache(key_cache.NewKeyCacheOptions(512*1024, 10*time.Second)).
+		Build()
+
+	rowCache := storageOptions.rowCache()
+	defer rowCache.Stop()
+
+	assert.NotNil(t, rowCache)
+}
+
+func TestStorageOptionsWithoutRowCache(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithFileSystemStoreType(".").Build()
+	assert.Nil(t, storageOptions.rowCache())
+}
+
+func TestStorageOptionsWithNegativeLookupCache(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithNegativeLookupCacheOptions(cache.NewComparableKeyCacheOptions[string, cache.NegativeLookup](
+			4096,
+			5*time.Minute,
+			func(key string, value cache.NegativeLookup) uint32 {
+				return uint32(len(key)) + uint32(unsafe.Sizeof(value))
+			},
+		)).
+		Build()
+
+	negativeLookupCache, err := storageOptions.negativeLookupCache()
+	assert.NoError(t, err)
+	assert.NotNil(t, negativeLookupCache)
+}
+
+func TestStorageOptionsWithoutNegativeLookupCache(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithFileSystemStoreType(".").Build()
+	negativeLookupCache, err := storageOptions.negativeLookupCache()
+	assert.NoError(t, err)
+	assert.Nil(t, negativeLookupCache)
+}
+
+func TestStorageOptionsWithDiskCache(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithFileSystemStoreType(".").WithDiskCache("disk_cache", 1<<20).Build()
+	assert.Equal(t, "disk_cache", storageOptions.diskCacheDirectory)
+	assert.Equal(t, int64(1<<20), storageOptions.diskCacheSizeInBytes)
+}
+
+func TestStorageOptionsWithoutDiskCache(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithFileSystemStoreType(".").Build()
+	diskCache, err := storageOptions.diskCache()
+	assert.NoError(t, err)
+	assert.Nil(t, diskCache)
+}
+
+func TestStorageOptionsWithZeroDiskCacheSize(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithDiskCache("disk_cache", 0)
+	})
+}
+
+func TestStorageOptionsWithSortedSegmentBlockHashIndex(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().EnableSortedSegmentBlockHashIndex().WithFileSystemStoreType(".").Build()
+	assert.True(t, storageOptions.sortedSegmentBlockHashIndex)
+	assert.Equal(
+		t,
+		objectStore.NewSortedSegmentFormatOptions(block.DefaultBlockSize, filter.DefaultFalsePositiveRate, false).WithBlockHashIndex(),
+		storageOptions.sortedSegmentFormatOptions(),
+	)
+}
+
+func TestStorageOptionsWithMaxConcurrentReads(t *testing.T) {
+	storageOptions := NewStorageOptionsBuilder().WithMaxConcurrentReads(4).WithFileSystemStoreType(".").Build()
+	assert.Equal(t, uint(4), storageOptions.maxConcurrentReads)
+}
+
+func TestStorageOptionsWithZeroMaxConcurrentReads(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStorageOptionsBuilder().WithMaxConcurrentReads(0)
+	})
+}
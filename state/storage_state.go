@@ -1,13 +1,17 @@
 package state
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/SarthakMakhija/zero-store/cache"
 	"github.com/SarthakMakhija/zero-store/future"
 	"github.com/SarthakMakhija/zero-store/kv"
 	"github.com/SarthakMakhija/zero-store/memory"
 	"github.com/SarthakMakhija/zero-store/objectstore"
 	objectStore "github.com/SarthakMakhija/zero-store/objectstore/segment"
 	"github.com/SarthakMakhija/zero-store/state/get_strategies"
+	"iter"
 	"log"
 	"slices"
 	"sync"
@@ -15,38 +19,71 @@ import (
 )
 
 var (
-	ErrDbStopped = errors.New("db is stopped, can not perform the operation")
+	ErrDbStopped     = errors.New("db is stopped, can not perform the operation")
+	ErrBatchTooLarge = errors.New("batch is too large, can not perform Set")
 )
 
 type StorageState struct {
 	activeSegment            memory.SortedSegment
 	inactiveSegments         *inactiveSegments
 	persistentSortedSegments *objectStore.SortedSegments
+	ioScheduler              *objectstore.IOScheduler
+	rowCache                 *get_strategies.RowCache
+	negativeLookupCache      *cache.NegativeLookupCache
 	segmentIdGenerator       *SegmentIdGenerator
 	closeChannel             chan struct{}
 	options                  StorageOptions
 	store                    objectstore.Store
 	stateLock                sync.RWMutex
+	writeLock                sync.Mutex
+	flushLock                sync.Mutex
 }
 
+// NewStorageState creates StorageState with the given StorageOptions.
+// The store (along with its objectstore.DiskCache, if any) is closed if any of the later steps fails.
 func NewStorageState(options StorageOptions) (*StorageState, error) {
 	segmentIdGenerator := NewSegmentIdGenerator()
-	store, err := options.storeType.GetStore(options.rootDirectory)
+	diskCache, err := options.diskCache()
 	if err != nil {
 		return nil, err
 	}
+	store, err := options.storeType.GetStoreWithDiskCache(options.rootDirectory, diskCache)
+	if err != nil {
+		if diskCache != nil {
+			_ = diskCache.Close()
+		}
+		return nil, err
+	}
+	negativeLookupCache, err := options.negativeLookupCache()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	ioScheduler := objectstore.NewIOScheduler(options.maxConcurrentReads)
 	persistentSortedSegments, err := objectStore.NewSortedSegments(
 		store,
-		objectStore.NewSortedSegmentCacheOptions(options.bloomFilterCacheOptions, options.blockMetaListCacheOptions),
-		options.sortedSegmentBlockCompression,
+		objectStore.NewSortedSegmentCacheOptions(
+			options.bloomFilterCacheOptions,
+			options.blockMetaListCacheOptions,
+			options.compressionDictionaryCacheOptions,
+			options.blockCacheOptions,
+		),
+		options.sortedSegmentFormatOptions(),
+		options.valueSeparationThresholdInBytes,
+		options.readaheadOptions,
+		ioScheduler,
 	)
 	if err != nil {
+		store.Close()
 		return nil, err
 	}
 	storageState := &StorageState{
 		activeSegment:            memory.NewSortedSegment(segmentIdGenerator.NextId(), options.sortedSegmentSizeInBytes),
 		inactiveSegments:         newInactiveSegments(),
 		persistentSortedSegments: persistentSortedSegments,
+		ioScheduler:              ioScheduler,
+		rowCache:                 options.rowCache(),
+		negativeLookupCache:      negativeLookupCache,
 		segmentIdGenerator:       segmentIdGenerator,
 		closeChannel:             make(chan struct{}),
 		options:                  options,
@@ -62,7 +99,15 @@ func (state *StorageState) Get(key kv.Key, strategy get_strategies.GetStrategyTy
 		return get_strategies.NewNonDurableOnlyGet(state.activeSegment, slices.Backward(state.inactiveSegments.copySegments()))
 	}
 	newDurableOnlyGet := func() get_strategies.DurableOnlyGet {
-		return get_strategies.NewDurableOnlyGet(state.persistentSortedSegments, slices.All(state.persistentSortedSegments.OrderedSegmentsByDescendingSegmentId()))
+		rowCacheSnapshot := state.rowCache.Snapshot()
+		negativeLookupSnapshot := state.negativeLookupSnapshot()
+		return get_strategies.NewDurableOnlyGetWithCaches(
+			state.persistentSortedSegments,
+			state.ioScheduler,
+			slices.All(state.persistentSortedSegments.OrderedSegmentsByDescendingSegmentId()),
+			rowCacheSnapshot,
+			negativeLookupSnapshot,
+		)
 	}
 	newNonDurableAlsoGet := func() get_strategies.NonDurableAlsoGet {
 		return get_strategies.NewNonDurableAlsoGet(newNonDurableOnlyGet(), newDurableOnlyGet())
@@ -85,23 +130,166 @@ func (state *StorageState) Get(key kv.Key, strategy get_strategies.GetStrategyTy
 	return resolveGetStrategy().Get(key)
 }
 
+// MultiGet gets the values of the given keys at the given read timestamp from the memory segments and the persistent
+// segments, the responses are in the order of the keys.
+// The keys which are not available in the memory segments are looked up together in the persistent segments, such that each
+// needed block of a persistent segment is read once, please check get_strategies.DurableOnlyGet.MultiGet.
+func (state *StorageState) MultiGet(keys [][]byte, readTimestamp uint64) []get_strategies.GetResponse {
+	state.stateLock.RLock()
+	rowCacheSnapshot := state.rowCache.Snapshot()
+	negativeLookupSnapshot := state.negativeLookupSnapshot()
+	getOperation := get_strategies.NewNonDurableAlsoGet(
+		get_strategies.NewNonDurableOnlyGet(state.activeSegment, slices.Backward(state.inactiveSegments.copySegments())),
+		get_strategies.NewDurableOnlyGetWithCaches(
+			state.persistentSortedSegments,
+			state.ioScheduler,
+			slices.All(state.persistentSortedSegments.OrderedSegmentsByDescendingSegmentId()),
+			rowCacheSnapshot,
+			negativeLookupSnapshot,
+		),
+	)
+	state.stateLock.RUnlock()
+
+	timestampedKeys := make([]kv.Key, 0, len(keys))
+	for _, key := range keys {
+		timestampedKeys = append(timestampedKeys, kv.NewKey(key, readTimestamp))
+	}
+	return getOperation.MultiGet(timestampedKeys)
+}
+
+// Set writes the kv.TimestampedBatch to the active memory.SortedSegment.
+// A batch which can not fit even in an empty memory.SortedSegment of the configured size (sortedSegmentSizeInBytes),
+// is written to a dedicated memory.SortedSegment sized for it. This keeps all the key/value pairs of a batch in one segment,
+// and hence the entire batch becomes visible at its timestamp together.
+// It returns ErrBatchTooLarge if the size of the memory.SortedSegment needed to fit the batch (which includes the skiplist
+// nodes of the key/value pairs and the size of an empty memory.SortedSegment) is greater than maxBatchSizeInBytes. This
+// keeps the dedicated memory.SortedSegment of a batch within the uint32 offsets of its arena.
+// It returns objectStore.ErrKeyTooLargeForBlock if a key of the batch (along with at-least one byte of its value) does not
+// fit in a data block of the persistent sorted segment, such a key would fail every flush of its memory.SortedSegment.
 func (state *StorageState) Set(batch kv.TimestampedBatch) (*future.Future[struct{}], error) {
-	state.mayBeFreezeActiveSegment(batch.SizeInBytes())
+	formatOptions := state.options.sortedSegmentFormatOptions()
+	for iterator := batch.Iterator(); iterator.IsValid(); _ = iterator.Next() {
+		if err := formatOptions.ValidateKeySize(iterator.Key(), iterator.Value()); err != nil {
+			return nil, err
+		}
+	}
+	requiredSizeInBytes := memory.SizeInBytesToFit(batch)
+	if sortedSegmentSizeInBytes := memory.SortedSegmentSizeInBytesToFit(requiredSizeInBytes); sortedSegmentSizeInBytes > state.options.maxBatchSizeInBytes {
+		return nil, fmt.Errorf(
+			"%w: batch size %v bytes (%v bytes to fit), max batch size %v bytes",
+			ErrBatchTooLarge,
+			batch.SizeInBytes(),
+			sortedSegmentSizeInBytes,
+			state.options.maxBatchSizeInBytes,
+		)
+	}
+
+	state.writeLock.Lock()
+	defer state.writeLock.Unlock()
+
+	if !memory.CanFitInAnEmptySortedSegment(requiredSizeInBytes, state.options.sortedSegmentSizeInBytes) {
+		return state.writeToDedicatedSegment(batch, requiredSizeInBytes)
+	}
+	state.mayBeFreezeActiveSegment(int(requiredSizeInBytes))
 	if err := state.writeToActiveSegment(batch); err != nil {
 		return nil, err
 	}
 	return state.activeSegment.FlushToObjectStoreFuture(), nil
 }
 
+// Flush freezes the active memory.SortedSegment, even if it is not full, and moves it along with all the older inactive
+// segments to the object store.
+// It returns the ids of the persistent sorted segments (objectStore.SortedSegment) which were created for the flushed segments.
+// Flush checks the context before moving each inactive segment, and returns the context error if the context is done.
+func (state *StorageState) Flush(ctx context.Context) ([]uint64, error) {
+	select {
+	case <-state.closeChannel:
+		return nil, ErrDbStopped
+	default:
+	}
+
+	freezeActiveSegmentIfNotEmpty := func() {
+		state.writeLock.Lock()
+		defer state.writeLock.Unlock()
+
+		if !state.activeSegment.IsEmpty() {
+			state.freezeActiveSegment()
+		}
+	}
+	inactiveSegmentIds := func() []uint64 {
+		state.stateLock.RLock()
+		defer state.stateLock.RUnlock()
+
+		segmentIds := make([]uint64, 0, len(state.inactiveSegments.segments))
+		for _, segment := range state.inactiveSegments.segments {
+			segmentIds = append(segmentIds, segment.Id())
+		}
+		return segmentIds
+	}
+	hasInactiveSegmentUpto := func(segmentId uint64) bool {
+		state.stateLock.RLock()
+		defer state.stateLock.RUnlock()
+
+		oldest, ok := state.inactiveSegments.oldest()
+		return ok && oldest.Id() <= segmentId
+	}
+
+	freezeActiveSegmentIfNotEmpty()
+	segmentIds := inactiveSegmentIds()
+	if len(segmentIds) == 0 {
+		return segmentIds, nil
+	}
+	for hasInactiveSegmentUpto(segmentIds[len(segmentIds)-1]) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := state.mayBeFlushOldestInactiveSegment(); err != nil {
+			return nil, err
+		}
+	}
+	return segmentIds, nil
+}
+
 // mayBeFreezeActiveSegment may freeze the active memory.SortedSegment if it does not have required size.
-// It creates a new memory.SortedSegment, and sends the previously active memory.SortedSegment to be moved to object store.
 func (state *StorageState) mayBeFreezeActiveSegment(sizeInBytes int) {
 	if !state.activeSegment.CanFit(int64(sizeInBytes)) {
+		state.freezeActiveSegment()
+	}
+}
+
+// writeToDedicatedSegment writes the batch which is larger than the configured size of memory.SortedSegment.
+// It involves the following:
+// 1) Freezing the active memory.SortedSegment, if it is not empty.
+// 2) Replacing the (empty) active memory.SortedSegment with a memory.SortedSegment sized for the batch.
+// 3) Writing the batch to the (new) active memory.SortedSegment.
+// 4) Freezing the (new) active memory.SortedSegment, so that the next batch goes to a memory.SortedSegment of the configured size.
+func (state *StorageState) writeToDedicatedSegment(batch kv.TimestampedBatch, requiredSizeInBytes int64) (*future.Future[struct{}], error) {
+	replaceActiveSegmentWithSegmentToFit := func() {
 		state.stateLock.Lock()
-		state.inactiveSegments.append(state.activeSegment)
-		state.activeSegment = memory.NewSortedSegment(state.segmentIdGenerator.NextId(), state.options.sortedSegmentSizeInBytes)
-		state.stateLock.Unlock()
+		defer state.stateLock.Unlock()
+
+		state.activeSegment = memory.NewSortedSegmentToFit(state.activeSegment.Id(), requiredSizeInBytes)
 	}
+
+	if !state.activeSegment.IsEmpty() {
+		state.freezeActiveSegment()
+	}
+	replaceActiveSegmentWithSegmentToFit()
+	if err := state.writeToActiveSegment(batch); err != nil {
+		return nil, err
+	}
+	flushToObjectStoreFuture := state.activeSegment.FlushToObjectStoreFuture()
+	state.freezeActiveSegment()
+	return flushToObjectStoreFuture, nil
+}
+
+// freezeActiveSegment creates a new memory.SortedSegment, and sends the previously active memory.SortedSegment to be moved to object store.
+func (state *StorageState) freezeActiveSegment() {
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+
+	state.inactiveSegments.append(state.activeSegment)
+	state.activeSegment = memory.NewSortedSegment(state.segmentIdGenerator.NextId(), state.options.sortedSegmentSizeInBytes)
 }
 
 // writeToActiveSegment writes the batch to the active segment.
@@ -123,10 +311,29 @@ func (state *StorageState) writeToActiveSegment(batch kv.TimestampedBatch) error
 	return nil
 }
 
+// CollectValueLogGarbage rewrites the value logs with live ratio less than liveRatioThreshold.
+// It returns the ids of the value logs which were rewritten or deleted.
+// flushLock serializes the garbage collection with the flush of the inactive segments. A flush writes the value log of a
+// persistent sorted segment before the segment is published, and such a value log has no live pointer in the persistent
+// sorted segments collected by a concurrent garbage collection.
+func (state *StorageState) CollectValueLogGarbage(liveRatioThreshold float64) ([]uint64, error) {
+	state.flushLock.Lock()
+	defer state.flushLock.Unlock()
+
+	persistentSegments := func() []objectStore.SortedSegment {
+		state.stateLock.RLock()
+		defer state.stateLock.RUnlock()
+
+		return state.persistentSortedSegments.OrderedSegmentsByDescendingSegmentId()
+	}
+	return state.persistentSortedSegments.CollectValueLogGarbage(persistentSegments(), liveRatioThreshold)
+}
+
 // Close closes the StorageState.
 func (state *StorageState) Close() {
 	close(state.closeChannel)
 	state.store.Close()
+	state.rowCache.Stop()
 	state.inactiveSegments.flushAllToObjectStoreMarkAsError()
 }
 
@@ -156,7 +363,12 @@ func (state *StorageState) spawnObjectStoreMovement() {
 // It returns (false, error), if there is an error.
 // It returns (true, nil), if an inactive segment was flushed without any error.
 // It returns (false, nil), if there was no inactive segment to be flushed.
+// flushLock ensures that the oldest inactive segment is not moved to the object store concurrently by
+// spawnObjectStoreMovement and Flush.
 func (state *StorageState) mayBeFlushOldestInactiveSegment() (bool, error) {
+	state.flushLock.Lock()
+	defer state.flushLock.Unlock()
+
 	updateState := func(segmentId uint64) {
 		state.stateLock.Lock()
 		defer state.stateLock.Unlock()
@@ -176,6 +388,9 @@ func (state *StorageState) mayBeFlushOldestInactiveSegment() (bool, error) {
 	}
 
 	if oldestInMemorySegmentToFlush, ok := oldestInactiveSegmentIfAvailable(); ok {
+		state.rowCache.BeginFlush()
+		defer state.rowCache.EndFlush(keysOf(oldestInMemorySegmentToFlush))
+
 		_, err := buildAndWritePersistentSortedSegment(oldestInMemorySegmentToFlush)
 		if err != nil {
 			//TODO: what if flush succeeds later on, how will AsyncAwait handle it?
@@ -189,6 +404,33 @@ func (state *StorageState) mayBeFlushOldestInactiveSegment() (bool, error) {
 	return false, nil
 }
 
+// negativeLookupSnapshot returns the get_strategies.NegativeLookupSnapshot at the current segment set version of the
+// persistent sorted segments, it must be taken before the persistent sorted segments of a read are collected.
+func (state *StorageState) negativeLookupSnapshot() get_strategies.NegativeLookupSnapshot {
+	if state.negativeLookupCache == nil {
+		return get_strategies.NegativeLookupSnapshot{}
+	}
+	return get_strategies.NewNegativeLookupSnapshot(
+		state.negativeLookupCache,
+		state.persistentSortedSegments.SegmentSetVersion(),
+	)
+}
+
+// keysOf returns the sequence of all the keys of the given memory.SortedSegment.
+func keysOf(segment memory.SortedSegment) iter.Seq[kv.Key] {
+	return func(yield func(kv.Key) bool) {
+		iterator := memory.NewAllEntriesSortedSegmentIterator(segment)
+		defer iterator.Close()
+
+		for iterator.IsValid() {
+			if !yield(iterator.Key()) {
+				return
+			}
+			_ = iterator.Next()
+		}
+	}
+}
+
 type inactiveSegments struct {
 	segments []memory.SortedSegment //oldest to latest
 }
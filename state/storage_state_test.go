@@ -1,11 +1,18 @@
 package state
 
 import (
+	"context"
+	"fmt"
+	"github.com/SarthakMakhija/zero-store/cache"
+	"github.com/SarthakMakhija/zero-store/cache/key_cache"
 	"github.com/SarthakMakhija/zero-store/kv"
+	objectStore "github.com/SarthakMakhija/zero-store/objectstore/segment"
 	"github.com/SarthakMakhija/zero-store/state/get_strategies"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 	"time"
+	"unsafe"
 )
 
 func TestStorageStateSetWithAnEmptyBatch(t *testing.T) {
@@ -270,6 +277,265 @@ func TestStorageStateWithAMultiplePutsAndNonDurableAlsoGet(t *testing.T) {
 	assert.Equal(t, "paxos", getResponse.Value().String())
 }
 
+func TestStorageStateFlushWithAnActiveSegmentThatIsNotFull(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	flushToObjectStoreFuture, err := storageState.Set(timestampedBatch)
+	assert.NoError(t, err)
+
+	segmentIds, err := storageState.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1}, segmentIds)
+
+	flushToObjectStoreFuture.Wait()
+	assert.True(t, flushToObjectStoreFuture.Status().IsOk())
+	assert.Equal(t, uint64(2), storageState.activeSegment.Id())
+	assert.True(t, storageState.hasPersistentSortedSegmentFor(1))
+
+	getResponse := storageState.Get(kv.NewStringKeyWithTimestamp("consensus", 10), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "raft", getResponse.Value().String())
+}
+
+func TestStorageStateFlushWithInactiveSegmentsAndAnActiveSegment(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithSortedSegmentSizeInBytes(260).
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	_, _ = storageState.Set(timestampedBatch)
+
+	batch = kv.NewBatch()
+	_ = batch.Set([]byte("storage"), []byte("NVMe"))
+	timestampedBatch, err = kv.NewTimestampedBatch(batch, 20)
+	assert.NoError(t, err)
+	_, _ = storageState.Set(timestampedBatch)
+
+	batch = kv.NewBatch()
+	_ = batch.Set([]byte("data-structure"), []byte("LSM"))
+	timestampedBatch, err = kv.NewTimestampedBatch(batch, 30)
+	assert.NoError(t, err)
+	_, _ = storageState.Set(timestampedBatch)
+
+	segmentIds, err := storageState.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, segmentIds)
+
+	assert.Equal(t, uint64(4), storageState.activeSegment.Id())
+	assert.Equal(t, 0, len(storageState.inactiveSegments.segments))
+	assert.True(t, storageState.hasPersistentSortedSegmentFor(1))
+	assert.True(t, storageState.hasPersistentSortedSegmentFor(2))
+	assert.True(t, storageState.hasPersistentSortedSegmentFor(3))
+}
+
+func TestStorageStateFlushWithAnEmptyActiveSegment(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().WithFileSystemStoreType(".").Build())
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+	}()
+
+	segmentIds, err := storageState.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(segmentIds))
+	assert.Equal(t, uint64(1), storageState.activeSegment.Id())
+}
+
+func TestStorageStateFlushWithACancelledContext(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	_, _ = storageState.Set(timestampedBatch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = storageState.Flush(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, storageState.hasPersistentSortedSegmentFor(1))
+}
+
+func TestStorageStateFlushAfterClose(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().WithFileSystemStoreType(".").Build())
+	assert.NoError(t, err)
+
+	storageState.Close()
+
+	_, err = storageState.Flush(context.Background())
+	assert.ErrorIs(t, err, ErrDbStopped)
+}
+
+func TestStorageStateWithABatchLargerThanTheSortedSegment(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithSortedSegmentSizeInBytes(260).
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	_, _ = storageState.Set(timestampedBatch)
+
+	batch = kv.NewBatch()
+	_ = batch.Set([]byte("storage"), []byte("NVMe"))
+	_ = batch.Set([]byte("data-structure"), []byte("LSM"))
+	_ = batch.Set([]byte("consensus"), []byte("paxos"))
+	timestampedBatch, err = kv.NewTimestampedBatch(batch, 20)
+	assert.NoError(t, err)
+	flushToObjectStoreFuture, err := storageState.Set(timestampedBatch)
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint64(3), storageState.activeSegment.Id())
+	assert.True(t, storageState.activeSegment.IsEmpty())
+	assert.Equal(t, 2, len(storageState.inactiveSegments.segments))
+
+	getResponse := storageState.Get(kv.NewStringKeyWithTimestamp("data-structure", 20), get_strategies.NonDurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "LSM", getResponse.Value().String())
+
+	keepFlushingInactiveSegmentsUntilNoMoreInactiveSegmentToFlush(t, storageState)
+	flushToObjectStoreFuture.Wait()
+	assert.True(t, flushToObjectStoreFuture.Status().IsOk())
+
+	getResponse = storageState.Get(kv.NewStringKeyWithTimestamp("consensus", 20), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "paxos", getResponse.Value().String())
+}
+
+func TestStorageStateWithABatchLargerThanTheMaxBatchSize(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithMaxBatchSizeInBytes(16).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+
+	_, err = storageState.Set(timestampedBatch)
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+	assert.True(t, storageState.activeSegment.IsEmpty())
+}
+
+func TestStorageStateWithABatchWhichNeedsASortedSegmentLargerThanTheMaxBatchSize(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithMaxBatchSizeInBytes(4096).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+	}()
+
+	batch := kv.NewBatch()
+	for count := 1; count <= 100; count++ {
+		_ = batch.Set([]byte(fmt.Sprintf("k%d", count)), []byte("v"))
+	}
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	assert.Less(t, int64(timestampedBatch.SizeInBytes()), int64(4096))
+
+	_, err = storageState.Set(timestampedBatch)
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+	assert.True(t, storageState.activeSegment.IsEmpty())
+}
+
+func TestStorageStateWithAKeyWhichDoesNotFitInABlock(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	assert.NoError(t, batch.Set([]byte(strings.Repeat("k", 5000)), []byte("raft")))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+
+	_, err = storageState.Set(timestampedBatch)
+	assert.ErrorIs(t, err, objectStore.ErrKeyTooLargeForBlock)
+	assert.True(t, storageState.activeSegment.IsEmpty())
+
+	batch = kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	timestampedBatch, err = kv.NewTimestampedBatch(batch, 20)
+	assert.NoError(t, err)
+	_, err = storageState.Set(timestampedBatch)
+	assert.NoError(t, err)
+
+	segmentIds, err := storageState.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1}, segmentIds)
+
+	getResponse := storageState.Get(kv.NewStringKeyWithTimestamp("consensus", 20), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "raft", getResponse.Value().String())
+}
+
 func keepFlushingInactiveSegmentsUntilNoMoreInactiveSegmentToFlush(t *testing.T, storageState *StorageState) {
 	for {
 		flushed, err := storageState.mayBeFlushOldestInactiveSegment()
@@ -281,3 +547,231 @@ func keepFlushingInactiveSegmentsUntilNoMoreInactiveSegmentToFlush(t *testing.T,
 }
 
 //TODO: add tests for checking versioned get, after the get implementation is done
+
+func TestStorageStateWithSeparatedValuesAndDurableOnlyGet(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		WithValueSeparationThresholdInBytes(8).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	_ = batch.Set([]byte("storage"), []byte("log-structured merge tree"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	_, err = storageState.Set(timestampedBatch)
+	assert.NoError(t, err)
+
+	_, err = storageState.Flush(context.Background())
+	assert.NoError(t, err)
+
+	getResponse := storageState.Get(kv.NewStringKeyWithTimestamp("consensus", 10), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "raft", getResponse.Value().String())
+
+	getResponse = storageState.Get(kv.NewStringKeyWithTimestamp("storage", 10), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "log-structured merge tree", getResponse.Value().String())
+
+	collectedLogIds, err := storageState.CollectValueLogGarbage(0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(collectedLogIds))
+}
+
+func TestStorageStateCollectValueLogGarbageWaitsForAFlushInProgress(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		WithValueSeparationThresholdInBytes(8).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	storageState.flushLock.Lock()
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		_, err := storageState.CollectValueLogGarbage(0.5)
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-collected:
+		assert.Fail(t, "value log garbage collection did not wait for the flush in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	storageState.flushLock.Unlock()
+	<-collected
+}
+
+func TestStorageStateWithSortedSegmentFormatOptionsAndDurableOnlyGet(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		WithSortedSegmentBlockSize(128).
+		WithBloomFilterFalsePositiveRate(0.001).
+		EnableSortedSegmentBlockCompression().
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	_ = batch.Set([]byte("storage"), []byte("log-structured merge tree"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	_, err = storageState.Set(timestampedBatch)
+	assert.NoError(t, err)
+
+	_, err = storageState.Flush(context.Background())
+	assert.NoError(t, err)
+
+	getResponse := storageState.Get(kv.NewStringKeyWithTimestamp("storage", 10), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "log-structured merge tree", getResponse.Value().String())
+}
+
+func TestStorageStateMultiGetFromMemoryAndPersistentSegments(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	batch := kv.NewBatch()
+	_ = batch.Set([]byte("consensus"), []byte("raft"))
+	_ = batch.Set([]byte("storage"), []byte("NVMe"))
+	timestampedBatch, err := kv.NewTimestampedBatch(batch, 10)
+	assert.NoError(t, err)
+	flushToObjectStoreFuture, err := storageState.Set(timestampedBatch)
+	assert.NoError(t, err)
+
+	_, err = storageState.Flush(context.Background())
+	assert.NoError(t, err)
+	flushToObjectStoreFuture.Wait()
+
+	batch = kv.NewBatch()
+	_ = batch.Set([]byte("distributed"), []byte("TiKV"))
+	timestampedBatch, err = kv.NewTimestampedBatch(batch, 20)
+	assert.NoError(t, err)
+	_, err = storageState.Set(timestampedBatch)
+	assert.NoError(t, err)
+
+	getResponses := storageState.MultiGet([][]byte{[]byte("storage"), []byte("quorum"), []byte("distributed"), []byte("consensus")}, 25)
+	assert.Equal(t, 4, len(getResponses))
+	assert.Equal(t, "NVMe", getResponses[0].Value().String())
+	assert.False(t, getResponses[1].IsValueAvailable())
+	assert.Equal(t, "TiKV", getResponses[2].Value().String())
+	assert.Equal(t, "raft", getResponses[3].Value().String())
+}
+
+func TestStorageStateWithRowCacheAndDurableOnlyGetAfterANewerVersionIsFlushed(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		WithRowCache(key_cache.NewKeyCacheOptions(512*1024, 10*time.Second)).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	setAndFlush := func(value string, timestamp uint64) {
+		batch := kv.NewBatch()
+		_ = batch.Set([]byte("consensus"), []byte(value))
+		timestampedBatch, err := kv.NewTimestampedBatch(batch, timestamp)
+		assert.NoError(t, err)
+		flushToObjectStoreFuture, err := storageState.Set(timestampedBatch)
+		assert.NoError(t, err)
+
+		_, err = storageState.Flush(context.Background())
+		assert.NoError(t, err)
+		flushToObjectStoreFuture.Wait()
+		assert.True(t, flushToObjectStoreFuture.Status().IsOk())
+	}
+
+	setAndFlush("raft", 10)
+	getResponse := storageState.Get(kv.NewStringKeyWithTimestamp("consensus", 20), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "raft", getResponse.Value().String())
+
+	setAndFlush("paxos", 15)
+	getResponse = storageState.Get(kv.NewStringKeyWithTimestamp("consensus", 20), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "paxos", getResponse.Value().String())
+}
+
+func TestStorageStateWithNegativeLookupCacheAndDurableOnlyGetAfterTheKeyIsFlushed(t *testing.T) {
+	storageState, err := NewStorageState(NewStorageOptionsBuilder().
+		WithFileSystemStoreType(".").
+		WithFlushInactiveSegmentDuration(5 * time.Minute).
+		WithNegativeLookupCacheOptions(cache.NewComparableKeyCacheOptions[string, cache.NegativeLookup](
+			4096,
+			5*time.Minute,
+			func(key string, value cache.NegativeLookup) uint32 {
+				return uint32(len(key)) + uint32(unsafe.Sizeof(value))
+			},
+		)).
+		Build(),
+	)
+	assert.NoError(t, err)
+
+	defer func() {
+		storageState.Close()
+		storageState.RemoveAllPersistentSortedSegmentsIn(".")
+	}()
+
+	setAndFlush := func(key string, value string, timestamp uint64) {
+		batch := kv.NewBatch()
+		_ = batch.Set([]byte(key), []byte(value))
+		timestampedBatch, err := kv.NewTimestampedBatch(batch, timestamp)
+		assert.NoError(t, err)
+		flushToObjectStoreFuture, err := storageState.Set(timestampedBatch)
+		assert.NoError(t, err)
+
+		_, err = storageState.Flush(context.Background())
+		assert.NoError(t, err)
+		flushToObjectStoreFuture.Wait()
+		assert.True(t, flushToObjectStoreFuture.Status().IsOk())
+	}
+
+	setAndFlush("raft", "consensus", 10)
+	getResponse := storageState.Get(kv.NewStringKeyWithTimestamp("paxos", 20), get_strategies.DurableOnlyType)
+	assert.False(t, getResponse.IsValueAvailable())
+
+	setAndFlush("paxos", "consensus algorithm", 15)
+	getResponse = storageState.Get(kv.NewStringKeyWithTimestamp("paxos", 20), get_strategies.DurableOnlyType)
+	assert.True(t, getResponse.IsValueAvailable())
+	assert.Equal(t, "consensus algorithm", getResponse.Value().String())
+
+	getResponses := storageState.MultiGet([][]byte{[]byte("paxos")}, 20)
+	assert.True(t, getResponses[0].IsValueAvailable())
+	assert.Equal(t, "consensus algorithm", getResponses[0].Value().String())
+}